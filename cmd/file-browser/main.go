@@ -2,14 +2,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
 	"reflect"
 	"strings"
+	"syscall"
+	"time"
 
 	"file-browser/internal/server"
 )
 
+// shutdownTimeout 收到退出信号后等待进行中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 // formatConfig 将配置格式化为可读字符串，用于日志输出
 func formatConfig(cfg server.Config) string {
 	value := reflect.ValueOf(cfg)
@@ -49,10 +58,33 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// 收到 Ctrl-C 或 SIGTERM 时优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// 输出配置信息并启动服务
 	log.Printf("file-browser: %s", formatConfig(cfg))
-	r := srv.Handler()
-	if err := r.Run(cfg.Addr()); err != nil {
+	httpServer := &http.Server{Addr: cfg.Addr(), Handler: srv.Handler()}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		srv.Close()
 		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop() // 再次收到信号时立即退出
+
+	// 停止接收新请求并等待进行中的请求完成（SSE 等长连接超时后强制关闭），
+	// 然后停止后台任务：取消重复文件扫描、关闭索引监听并保存索引
+	log.Printf("file-browser: shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("file-browser: shutdown: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		log.Printf("file-browser: close: %v", err)
 	}
 }
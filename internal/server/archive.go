@@ -0,0 +1,584 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	archiveSeparator   = "!/"                   // 压缩包路径与包内路径的分隔符，例如 /app.zip!/config/app.yaml
+	archiveMaxEntries  = 100000                 // 单个压缩包最多允许的条目数
+	archiveMaxExpanded = 8 * 1024 * 1024 * 1024 // 单个压缩包解压后的总大小上限 (8GB)
+	archiveMaxBuffered = 64 * 1024 * 1024       // 预览时最多读入内存的条目大小 (64MB)
+
+	archiveListCacheEntries = 200000 // 条目列表缓存最多保存的条目总数
+)
+
+var (
+	// errArchiveTooLarge 压缩包条目数或解压后大小超出限制（防止压缩炸弹）
+	errArchiveTooLarge = errors.New("archive exceeds entry count or expanded size limit")
+	// errEntryTooLarge 压缩包条目过大，无法读入内存预览
+	errEntryTooLarge = errors.New("archive entry too large to preview")
+)
+
+// archiveFormat 支持的压缩包格式
+type archiveFormat int
+
+const (
+	formatZip archiveFormat = iota + 1
+	formatTar
+	formatTarGz
+	formatTarZst
+)
+
+// archiveSuffixes 文件名后缀到压缩包格式的映射（按后缀长度优先匹配）
+var archiveSuffixes = []struct {
+	suffix string
+	format archiveFormat
+}{
+	{".tar.gz", formatTarGz},
+	{".tar.zst", formatTarZst},
+	{".tgz", formatTarGz},
+	{".tzst", formatTarZst},
+	{".tar", formatTar},
+	{".zip", formatZip},
+	{".jar", formatZip},
+}
+
+// archiveFormatOf 根据文件名判断压缩包格式
+func archiveFormatOf(name string) (archiveFormat, bool) {
+	lower := strings.ToLower(name)
+	for _, item := range archiveSuffixes {
+		if strings.HasSuffix(lower, item.suffix) {
+			return item.format, true
+		}
+	}
+	return 0, false
+}
+
+// archiveTarget 压缩包内路径的解析结果
+type archiveTarget struct {
+	absPath string        // 压缩包的绝对路径
+	relPath string        // 压缩包相对于根目录的路径（不含前导 /）
+	inner   string        // 包内路径（不含前导 /，空字符串表示包的根目录）
+	format  archiveFormat // 压缩包格式
+	info    os.FileInfo   // 压缩包文件信息
+}
+
+// displayPath 返回包内路径的完整展示形式，例如 /builds/app.zip!/config/app.yaml
+func (t archiveTarget) displayPath(inner string) string {
+	return path.Join("/", t.relPath) + archiveSeparator + inner
+}
+
+// splitArchivePath 将请求路径拆分为压缩包路径和包内路径
+// 仅当分隔符前的部分具有受支持的压缩包后缀时才视为包内路径
+func splitArchivePath(reqPath string) (string, string, bool) {
+	reqPath = strings.TrimSpace(reqPath)
+	if strings.HasSuffix(reqPath, "!") {
+		reqPath += "/"
+	}
+	searchFrom := 0
+	for {
+		i := strings.Index(reqPath[searchFrom:], archiveSeparator)
+		if i < 0 {
+			return "", "", false
+		}
+		outer := reqPath[:searchFrom+i]
+		if _, ok := archiveFormatOf(outer); ok {
+			return outer, reqPath[searchFrom+i+len(archiveSeparator):], true
+		}
+		searchFrom += i + len(archiveSeparator)
+	}
+}
+
+// resolveArchivePath 解析指向压缩包内部的路径
+// 压缩包本身经 resolvePath 做路径遍历和符号链接校验；
+// 第二个返回值表示请求路径是否指向压缩包内部，为 false 时应按普通路径处理
+func (s *Server) resolveArchivePath(reqPath string) (archiveTarget, bool, error) {
+	outer, inner, ok := splitArchivePath(reqPath)
+	if !ok {
+		return archiveTarget{}, false, nil
+	}
+
+	absPath, relPath, err := s.resolvePath(outer)
+	if err != nil {
+		// 分隔符前的部分不存在时，按普通路径处理（例如名为 a.zip! 的目录）
+		if errors.Is(err, os.ErrNotExist) {
+			return archiveTarget{}, false, nil
+		}
+		return archiveTarget{}, true, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil || info.IsDir() {
+		return archiveTarget{}, false, nil
+	}
+
+	format, _ := archiveFormatOf(info.Name())
+	return archiveTarget{
+		absPath: absPath,
+		relPath: relPath,
+		inner:   strings.Trim(path.Clean("/"+inner), "/"),
+		format:  format,
+		info:    info,
+	}, true, nil
+}
+
+// archiveEntry 压缩包中的一个条目，实现 os.FileInfo 以便复用预览逻辑
+type archiveEntry struct {
+	name    string // 包内路径（不含前导和末尾 /）
+	isDir   bool
+	size    int64
+	modTime time.Time
+	offset  int64 // tar 包中条目数据在（解压后的）tar 流中的偏移，-1 表示无法直接定位；zip 不使用
+}
+
+func (e archiveEntry) Name() string       { return path.Base(e.name) }
+func (e archiveEntry) Size() int64        { return e.size }
+func (e archiveEntry) ModTime() time.Time { return e.modTime }
+func (e archiveEntry) IsDir() bool        { return e.isDir }
+func (e archiveEntry) Sys() any           { return nil }
+func (e archiveEntry) Mode() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// cleanEntryName 规范化包内条目名称，拒绝逃逸出包根目录的名称
+func cleanEntryName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains("/"+name+"/", "/../") {
+		return "", false
+	}
+	clean := strings.Trim(path.Clean("/"+name), "/")
+	return clean, clean != ""
+}
+
+// archiveListCache 按压缩包缓存解析出的条目列表，避免每次浏览或打开条目都从头解压 tar 包
+type archiveListCache struct {
+	lru *lruCache[string, []archiveEntry] // 缓存键（路径、大小和修改时间）-> 条目列表
+}
+
+// newArchiveListCache 创建条目列表缓存，按条目总数限制容量
+func newArchiveListCache(max int64) *archiveListCache {
+	return &archiveListCache{lru: newLRUCache[string, []archiveEntry](max, func(entries []archiveEntry) int64 {
+		return int64(len(entries)) + 1
+	})}
+}
+
+// listArchive 返回压缩包中的全部条目，返回的切片由缓存共享，调用方不能修改
+// 压缩包大小或修改时间变化后缓存键随之变化，旧列表会被自然淘汰
+func (s *Server) listArchive(t archiveTarget) ([]archiveEntry, error) {
+	key := fmt.Sprintf("%s@%d@%d", t.absPath, t.info.Size(), t.info.ModTime().UnixNano())
+	if entries, ok := s.archives.lru.get(key); ok {
+		return entries, nil
+	}
+	entries, err := readArchiveList(t)
+	if err != nil {
+		return nil, err
+	}
+	s.archives.lru.add(key, entries)
+	return entries, nil
+}
+
+// readArchiveList 读取压缩包中的全部条目，tar 包同时记录每个条目数据的偏移
+func readArchiveList(t archiveTarget) ([]archiveEntry, error) {
+	if t.format == formatZip {
+		reader, err := openZip(t.absPath)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		entries := make([]archiveEntry, 0, len(reader.File))
+		for _, f := range reader.File {
+			name, ok := cleanEntryName(f.Name)
+			if !ok || f.Mode()&fs.ModeSymlink != 0 {
+				continue
+			}
+			entries = append(entries, archiveEntry{
+				name:    name,
+				isDir:   f.FileInfo().IsDir(),
+				size:    int64(f.UncompressedSize64),
+				modTime: f.Modified,
+			})
+		}
+		return entries, nil
+	}
+
+	stream, err := openTar(t)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var entries []archiveEntry
+	for {
+		hdr, name, err := stream.next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, archiveEntry{
+			name:    name,
+			isDir:   hdr.Typeflag == tar.TypeDir,
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+			offset:  stream.dataOffset(hdr),
+		})
+	}
+}
+
+// findArchiveEntry 在条目列表中查找包内路径对应的条目
+func findArchiveEntry(t archiveTarget, entries []archiveEntry) (archiveEntry, error) {
+	for _, entry := range entries {
+		if entry.name != t.inner {
+			continue
+		}
+		if entry.isDir {
+			return archiveEntry{}, errNotAFile
+		}
+		return entry, nil
+	}
+	return archiveEntry{}, entryNotFound(t)
+}
+
+// openArchiveEntry 打开压缩包中的文件条目，调用方负责关闭返回的 ReadCloser
+func (s *Server) openArchiveEntry(t archiveTarget) (io.ReadCloser, archiveEntry, error) {
+	if t.format == formatZip {
+		reader, err := openZip(t.absPath)
+		if err != nil {
+			return nil, archiveEntry{}, err
+		}
+		for _, f := range reader.File {
+			name, ok := cleanEntryName(f.Name)
+			if !ok || name != t.inner || f.Mode()&fs.ModeSymlink != 0 {
+				continue
+			}
+			if f.FileInfo().IsDir() {
+				reader.Close()
+				return nil, archiveEntry{}, errNotAFile
+			}
+			rc, err := f.Open()
+			if err != nil {
+				reader.Close()
+				return nil, archiveEntry{}, err
+			}
+			entry := archiveEntry{name: name, size: int64(f.UncompressedSize64), modTime: f.Modified}
+			return &multiCloser{Reader: rc, closers: []io.Closer{rc, reader}}, entry, nil
+		}
+		reader.Close()
+		return nil, archiveEntry{}, entryNotFound(t)
+	}
+
+	// tar 包先从缓存的条目列表定位，条目不存在或是目录时无需读取压缩包
+	entries, err := s.listArchive(t)
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	entry, err := findArchiveEntry(t, entries)
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	if entry.offset < 0 {
+		return scanTarEntry(t)
+	}
+
+	file, err := os.Open(t.absPath)
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	var data io.ReaderAt = file
+	switch t.format {
+	case formatTarGz, formatTarZst:
+		// 压缩的 tar 包通过解压检查点随机读取，不必每次从头解压到条目所在位置
+		compression := compressionGzip
+		if t.format == formatTarZst {
+			compression = compressionZstd
+		}
+		data = &decompressReader{
+			index:       s.decompress.get(t.absPath+archiveSeparator, t.info),
+			compression: compression,
+			raw:         file,
+			rawSize:     t.info.Size(),
+			total:       -1,
+		}
+	}
+	return &multiCloser{Reader: io.NewSectionReader(data, entry.offset, entry.size), closers: []io.Closer{file}}, entry, nil
+}
+
+// scanTarEntry 从头顺序读取 tar 包直到找到条目，以流的方式返回条目内容
+func scanTarEntry(t archiveTarget) (io.ReadCloser, archiveEntry, error) {
+	stream, err := openTar(t)
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	for {
+		hdr, name, err := stream.next()
+		if err != nil {
+			stream.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, archiveEntry{}, entryNotFound(t)
+			}
+			return nil, archiveEntry{}, err
+		}
+		if name != t.inner {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			stream.Close()
+			return nil, archiveEntry{}, errNotAFile
+		}
+		entry := archiveEntry{name: name, size: hdr.Size, modTime: hdr.ModTime, offset: -1}
+		return &multiCloser{Reader: io.LimitReader(stream.reader, hdr.Size), closers: stream.closers}, entry, nil
+	}
+}
+
+// readArchiveEntry 将压缩包中的文件条目读入内存，超过 archiveMaxBuffered 时返回 errEntryTooLarge
+func (s *Server) readArchiveEntry(t archiveTarget) (*bytes.Reader, archiveEntry, error) {
+	rc, entry, err := s.openArchiveEntry(t)
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	defer rc.Close()
+	if entry.size > archiveMaxBuffered {
+		return nil, archiveEntry{}, errEntryTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rc, archiveMaxBuffered+1))
+	if err != nil {
+		return nil, archiveEntry{}, err
+	}
+	if int64(len(data)) > archiveMaxBuffered {
+		return nil, archiveEntry{}, errEntryTooLarge
+	}
+	entry.size = int64(len(data))
+	return bytes.NewReader(data), entry, nil
+}
+
+// listArchiveDir 列出压缩包内某个目录下的直接子项
+// 很多压缩包不包含目录条目，因此根据文件路径补全虚拟目录
+func listArchiveDir(t archiveTarget, entries []archiveEntry) ([]fileEntry, error) {
+	prefix := ""
+	if t.inner != "" {
+		prefix = t.inner + "/"
+	}
+
+	children := make(map[string]fileEntry)
+	dirExists := t.inner == ""
+	for _, entry := range entries {
+		if entry.name == t.inner {
+			if !entry.isDir {
+				return nil, errNotADir
+			}
+			dirExists = true
+			continue
+		}
+		if !strings.HasPrefix(entry.name, prefix) {
+			continue
+		}
+		dirExists = true
+
+		rest := strings.TrimPrefix(entry.name, prefix)
+		name, _, nested := strings.Cut(rest, "/")
+		item := fileEntry{
+			Name:     name,
+			Path:     t.displayPath(prefix + name),
+			Modified: entry.modTime.UTC().Format(time.RFC3339),
+		}
+		if nested || entry.isDir {
+			item.Type = "dir"
+			if existing, ok := children[name]; ok && existing.Type == "dir" && !entry.isDir {
+				continue // 保留显式目录条目的修改时间
+			}
+		} else {
+			item.Type = "file"
+			item.Size = entry.size
+		}
+		children[name] = item
+	}
+	if !dirExists {
+		return nil, entryNotFound(t)
+	}
+
+	items := make([]fileEntry, 0, len(children))
+	for _, item := range children {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Type != items[j].Type {
+			return items[i].Type == "dir"
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	return items, nil
+}
+
+// openZip 打开 zip 文件并检查条目数和声明的解压大小
+func openZip(absPath string) (*zip.ReadCloser, error) {
+	reader, err := zip.OpenReader(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(reader.File) > archiveMaxEntries {
+		reader.Close()
+		return nil, errArchiveTooLarge
+	}
+	var total uint64
+	for _, f := range reader.File {
+		total += f.UncompressedSize64
+		if total > archiveMaxExpanded {
+			reader.Close()
+			return nil, errArchiveTooLarge
+		}
+	}
+	return reader, nil
+}
+
+// tarStream 顺序读取 tar 包（可能经过 gzip/zstd 压缩）的条目
+type tarStream struct {
+	reader  *tar.Reader
+	counter *countingReader // 统计 tar.Reader 已读取的（解压后的）字节数
+	closers []io.Closer
+	count   int // 已读取的条目数
+}
+
+// openTar 打开 tar 包，解压后的数据量受 archiveMaxExpanded 限制
+func openTar(t archiveTarget) (*tarStream, error) {
+	file, err := os.Open(t.absPath)
+	if err != nil {
+		return nil, err
+	}
+	stream := &tarStream{closers: []io.Closer{file}}
+
+	var decompressed io.Reader = file
+	switch t.format {
+	case formatTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			stream.Close()
+			return nil, err
+		}
+		stream.closers = append([]io.Closer{gz}, stream.closers...)
+		decompressed = gz
+	case formatTarZst:
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			stream.Close()
+			return nil, err
+		}
+		rc := zr.IOReadCloser()
+		stream.closers = append([]io.Closer{rc}, stream.closers...)
+		decompressed = rc
+	}
+
+	// tar.Reader 按块读取且不预读，Next 返回后已读取的字节数即为条目数据的偏移
+	stream.counter = &countingReader{r: &expandLimitReader{r: decompressed, remaining: archiveMaxExpanded}}
+	stream.reader = tar.NewReader(stream.counter)
+	return stream, nil
+}
+
+// next 返回下一个文件或目录条目及其规范化名称，跳过符号链接等特殊条目
+// 条目数超出限制时返回 errArchiveTooLarge，读完时返回 io.EOF
+func (ts *tarStream) next() (*tar.Header, string, error) {
+	for {
+		if ts.count >= archiveMaxEntries {
+			return nil, "", errArchiveTooLarge
+		}
+		hdr, err := ts.reader.Next()
+		if err != nil {
+			return nil, "", err
+		}
+		ts.count++
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+			continue
+		}
+		if name, ok := cleanEntryName(hdr.Name); ok {
+			return hdr, name, nil
+		}
+	}
+}
+
+// dataOffset 返回 next 刚返回的条目数据在 tar 流中的偏移
+// GNU 稀疏文件的数据不是连续存放的，返回 -1
+func (ts *tarStream) dataOffset(hdr *tar.Header) int64 {
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return -1
+		}
+	}
+	return ts.counter.n
+}
+
+// Close 关闭解压器和底层文件
+func (ts *tarStream) Close() error {
+	var errs []error
+	for _, c := range ts.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// expandLimitReader 限制解压后读取的总字节数
+type expandLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *expandLimitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// multiCloser 读取时使用 Reader，关闭时依次关闭所有 closers
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// entryNotFound 返回包内路径不存在的错误，可被 errors.Is(err, os.ErrNotExist) 识别
+func entryNotFound(t archiveTarget) error {
+	return fmt.Errorf("%s: %w", t.displayPath(t.inner), os.ErrNotExist)
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// archiveFixture 测试压缩包中的文件
+var archiveFixture = []struct {
+	name    string
+	content string
+}{
+	{"config/app.yaml", "name: app\n"},
+	{"config/db/main.yaml", "host: localhost\n"},
+	{"README.md", "# readme\n"},
+}
+
+// ArchiveTestSuite 压缩包浏览测试套件
+type ArchiveTestSuite struct {
+	suite.Suite
+	tmpDir string
+	router *gin.Engine
+}
+
+func (s *ArchiveTestSuite) SetupSuite() {
+	tmpDir, err := os.MkdirTemp("", "file-browser-archive-test-*")
+	require.NoError(s.T(), err)
+	s.tmpDir = tmpDir
+
+	require.NoError(s.T(), os.Mkdir(filepath.Join(tmpDir, "builds"), 0755))
+	s.writeZip(filepath.Join(tmpDir, "builds", "app.zip"))
+	tarData := s.tarBytes()
+	require.NoError(s.T(), os.WriteFile(filepath.Join(tmpDir, "builds", "app.tar"), tarData, 0644))
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err = gw.Write(tarData)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), gw.Close())
+	require.NoError(s.T(), os.WriteFile(filepath.Join(tmpDir, "builds", "app.tar.gz"), gz.Bytes(), 0644))
+
+	var zst bytes.Buffer
+	zw, err := zstd.NewWriter(&zst)
+	require.NoError(s.T(), err)
+	_, err = zw.Write(tarData)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), zw.Close())
+	require.NoError(s.T(), os.WriteFile(filepath.Join(tmpDir, "builds", "app.tar.zst"), zst.Bytes(), 0644))
+
+	server, err := New(Config{Root: tmpDir, PreviewMax: 1024 * 1024})
+	require.NoError(s.T(), err)
+	s.router = server.Handler()
+}
+
+func (s *ArchiveTestSuite) TearDownSuite() {
+	if s.tmpDir != "" {
+		assert.NoError(s.T(), os.RemoveAll(s.tmpDir))
+	}
+}
+
+func (s *ArchiveTestSuite) writeZip(path string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range archiveFixture {
+		w, err := zw.Create(f.name)
+		require.NoError(s.T(), err)
+		_, err = io.WriteString(w, f.content)
+		require.NoError(s.T(), err)
+	}
+	require.NoError(s.T(), zw.Close())
+	require.NoError(s.T(), os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func (s *ArchiveTestSuite) tarBytes() []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(s.T(), tw.WriteHeader(&tar.Header{Name: "config/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, f := range archiveFixture {
+		require.NoError(s.T(), tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))}))
+		_, err := io.WriteString(tw, f.content)
+		require.NoError(s.T(), err)
+	}
+	// 符号链接和逃逸路径应被忽略
+	require.NoError(s.T(), tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}))
+	require.NoError(s.T(), tw.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0644}))
+	require.NoError(s.T(), tw.Close())
+	return buf.Bytes()
+}
+
+func (s *ArchiveTestSuite) get(url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	return w
+}
+
+func (s *ArchiveTestSuite) TestListArchiveRoot() {
+	for _, name := range []string{"app.zip", "app.tar", "app.tar.gz", "app.tar.zst"} {
+		s.Run(name, func() {
+			w := s.get("/api/files?path=/builds/" + name + "!/")
+			require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+			var items []fileEntry
+			require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &items))
+			require.Len(s.T(), items, 2)
+			assert.Equal(s.T(), "config", items[0].Name)
+			assert.Equal(s.T(), "dir", items[0].Type)
+			assert.Equal(s.T(), "/builds/"+name+"!/config", items[0].Path)
+			assert.Equal(s.T(), "README.md", items[1].Name)
+			assert.Equal(s.T(), int64(9), items[1].Size)
+		})
+	}
+}
+
+func (s *ArchiveTestSuite) TestListArchiveSubdirectory() {
+	w := s.get("/api/files?path=/builds/app.zip!/config")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var items []fileEntry
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(s.T(), items, 2)
+	assert.Equal(s.T(), "db", items[0].Name)
+	assert.Equal(s.T(), "dir", items[0].Type)
+	assert.Equal(s.T(), "app.yaml", items[1].Name)
+}
+
+func (s *ArchiveTestSuite) TestListMarksArchives() {
+	w := s.get("/api/files?path=/builds")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var items []fileEntry
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &items))
+	for _, item := range items {
+		assert.True(s.T(), item.Archive, item.Name)
+	}
+}
+
+func (s *ArchiveTestSuite) TestPreviewEntry() {
+	for _, name := range []string{"app.zip", "app.tar.gz", "app.tar.zst"} {
+		s.Run(name, func() {
+			w := s.get("/api/preview?path=/builds/" + name + "!/config/db/main.yaml")
+			require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+			var resp previewResponse
+			require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(s.T(), "host: localhost\n", resp.Content)
+			assert.Equal(s.T(), "main.yaml", resp.Name)
+			assert.Equal(s.T(), "/builds/"+name+"!/config/db/main.yaml", resp.Path)
+		})
+	}
+
+	w := s.get("/api/preview?path=/builds/app.tar!/config/app.yaml&fromLine=1")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "name: app")
+}
+
+func (s *ArchiveTestSuite) TestDownloadEntry() {
+	w := s.get("/api/download?path=/builds/app.tar.zst!/README.md")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "# readme\n", w.Body.String())
+	assert.Contains(s.T(), w.Header().Get("Content-Disposition"), "README.md")
+
+	w = s.get("/api/image?path=/builds/app.zip!/README.md")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "# readme\n", w.Body.String())
+}
+
+func (s *ArchiveTestSuite) TestErrors() {
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"missing entry", "/api/preview?path=/builds/app.zip!/missing.txt", http.StatusNotFound},
+		{"missing dir", "/api/files?path=/builds/app.tar!/missing", http.StatusNotFound},
+		{"preview dir", "/api/preview?path=/builds/app.tar!/config", http.StatusBadRequest},
+		{"list file", "/api/files?path=/builds/app.zip!/README.md", http.StatusBadRequest},
+		{"escaped entry", "/api/preview?path=/builds/app.tar!/../escape.txt", http.StatusNotFound},
+		{"symlink entry", "/api/download?path=/builds/app.tar!/link", http.StatusNotFound},
+		{"traversal", "/api/files?path=/../x.zip!/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			assert.Equal(s.T(), tt.status, s.get(tt.url).Code)
+		})
+	}
+}
+
+func TestArchiveListCache(t *testing.T) {
+	root := t.TempDir()
+	big := bytes.Repeat([]byte("0123456789abcdef"), 3*decompressSpan/16)
+	longName := "logs/" + strings.Repeat("x", 120) + ".log" // 超过 100 字节，写入 PAX 扩展头
+	writeTarGz := func(files map[string][]byte, names ...string) []byte {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		for _, name := range names {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))}))
+			_, err := tw.Write(files[name])
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return buf.Bytes()
+	}
+	files := map[string][]byte{"big.bin": big, longName: []byte("tail\n"), "a.txt": []byte("first\n")}
+	archive := filepath.Join(root, "app.tar.gz")
+	data := writeTarGz(files, "a.txt", "big.bin", longName)
+	require.NoError(t, os.WriteFile(archive, data, 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	defer server.Close()
+	router := server.Handler()
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	// 条目按偏移从解压检查点读取，内容与顺序读取一致
+	for _, name := range []string{longName, "big.bin", "a.txt"} {
+		w := get("/api/download?path=/app.tar.gz!/" + name)
+		require.Equal(t, http.StatusOK, w.Code, name)
+		assert.True(t, bytes.Equal(files[name], w.Body.Bytes()), name)
+	}
+	assert.Len(t, server.archives.lru.entries, 1)
+
+	// 大小和修改时间不变时使用缓存的条目列表，不再解析压缩包
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(archive, modTime, modTime))
+	require.Equal(t, http.StatusOK, get("/api/files?path=/app.tar.gz!/").Code)
+	require.NoError(t, os.WriteFile(archive, bytes.Repeat([]byte{0}, len(data)), 0644))
+	require.NoError(t, os.Chtimes(archive, modTime, modTime))
+	assert.Equal(t, http.StatusOK, get("/api/files?path=/app.tar.gz!/").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/download?path=/app.tar.gz!/missing.txt").Code)
+
+	// 压缩包变化后重新解析
+	files["a.txt"] = []byte("second\n")
+	require.NoError(t, os.WriteFile(archive, writeTarGz(files, "big.bin", "a.txt"), 0644))
+	w := get("/api/download?path=/app.tar.gz!/a.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "second\n", w.Body.String())
+	assert.Equal(t, http.StatusNotFound, get("/api/download?path=/app.tar.gz!/"+longName).Code)
+}
+
+func TestArchiveSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveTestSuite))
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		input string
+		outer string
+		inner string
+		ok    bool
+	}{
+		{"/builds/app.zip!/config/app.yaml", "/builds/app.zip", "config/app.yaml", true},
+		{"/builds/app.tar.gz!/", "/builds/app.tar.gz", "", true},
+		{"/builds/app.zip!", "/builds/app.zip", "", true},
+		{"/weird!/dir/app.tgz!/a", "/weird!/dir/app.tgz", "a", true},
+		{"/builds/app.zip", "", "", false},
+		{"/notes!/a.txt", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			outer, inner, ok := splitArchivePath(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.outer, outer)
+			assert.Equal(t, tt.inner, inner)
+		})
+	}
+}
+
+func TestExpandLimitReader(t *testing.T) {
+	r := &expandLimitReader{r: strings.NewReader("0123456789"), remaining: 4}
+
+	data, err := io.ReadAll(r)
+	assert.ErrorIs(t, err, errArchiveTooLarge)
+	assert.Equal(t, "0123", string(data))
+}
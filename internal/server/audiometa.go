@@ -0,0 +1,477 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ---------------------------------------------------------------------------
+// MP3（ID3v2 / ID3v1 标签 + MPEG 音频帧头）
+// ---------------------------------------------------------------------------
+
+const mp3SyncScan = 64 * 1024 // 标签之后查找第一个帧头的最大范围
+
+// mpegBitrates 码率表（kbps），按 [MPEG-1?][层] 索引，层下标 0/1/2 对应 Layer I/II/III
+var mpegBitrates = [2][3][16]int{
+	{ // MPEG-2 / 2.5
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+	{ // MPEG-1
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+}
+
+// id3Frames 将 ID3v2.2/2.3/2.4 文本帧映射为统一的键
+var id3Frames = map[string]string{
+	"TIT2": "title", "TPE1": "artist", "TALB": "album", "TPE2": "albumArtist",
+	"TYER": "date", "TDRC": "date", "TCON": "genre", "TRCK": "track",
+	"TT2": "title", "TP1": "artist", "TAL": "album", "TP2": "albumArtist",
+	"TYE": "date", "TCO": "genre", "TRK": "track",
+}
+
+// mpegHeader MPEG 音频帧头
+type mpegHeader struct {
+	mpeg1           bool
+	layer           int // 1、2、3
+	bitrate         int // kbps
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+}
+
+// parseMPEGHeader 解析 4 字节帧头，不是合法帧头时返回 nil
+func parseMPEGHeader(b []byte) *mpegHeader {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return nil
+	}
+	version := (b[1] >> 3) & 3 // 0: MPEG-2.5, 2: MPEG-2, 3: MPEG-1
+	layerBits := (b[1] >> 1) & 3
+	bitrateIndex := b[2] >> 4
+	rateIndex := (b[2] >> 2) & 3
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return nil
+	}
+
+	h := &mpegHeader{mpeg1: version == 3, layer: 4 - int(layerBits), channels: 2}
+	mpeg1 := 0
+	if h.mpeg1 {
+		mpeg1 = 1
+	}
+	h.bitrate = mpegBitrates[mpeg1][h.layer-1][bitrateIndex]
+	h.sampleRate = []int{44100, 48000, 32000}[rateIndex]
+	switch version {
+	case 2:
+		h.sampleRate /= 2
+	case 0:
+		h.sampleRate /= 4
+	}
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+	switch {
+	case h.layer == 1:
+		h.samplesPerFrame = 384
+	case h.layer == 3 && !h.mpeg1:
+		h.samplesPerFrame = 576
+	default:
+		h.samplesPerFrame = 1152
+	}
+	return h
+}
+
+// parseMP3 解析 MP3：ID3 标签、第一个帧头以及 Xing/Info/VBRI 头中的总帧数
+// 没有 VBR 头时按固定码率估算时长
+func parseMP3(r io.ReaderAt, size int64) (*mediaMeta, error) {
+	meta := &mediaMeta{Format: "mp3", Tags: map[string]string{}}
+	start := int64(0)
+	if head, err := readAtFull(r, 0, 10); err == nil && string(head[:3]) == "ID3" {
+		tagSize := int64(synchsafe(head[6:10]))
+		start = 10 + tagSize
+		if head[5]&0x10 != 0 { // 带尾部标签
+			start += 10
+		}
+		parseID3v2(r, head, tagSize, meta.Tags)
+
+		// 部分 FLAC 文件前面也带有 ID3 标签
+		if magic, err := readAtFull(r, start, 4); err == nil && string(magic) == "fLaC" {
+			flac, err := parseFLAC(r, start, size)
+			if err == nil {
+				for k, v := range meta.Tags {
+					setMediaTag(flac.Tags, k, v)
+				}
+			}
+			return flac, err
+		}
+	}
+
+	end := size
+	if tail, err := readAtFull(r, size-128, 128); err == nil && string(tail[:3]) == "TAG" {
+		end -= 128
+		parseID3v1(tail, meta.Tags)
+	}
+
+	if end <= start {
+		return nil, errInvalidMedia
+	}
+	buf, err := readAtFull(r, start, int(min(mp3SyncScan, end-start)))
+	if err != nil {
+		return nil, errInvalidMedia
+	}
+	for i := 0; i+4 <= len(buf); i++ {
+		h := parseMPEGHeader(buf[i:])
+		if h == nil {
+			continue
+		}
+		frameStart := start + int64(i)
+		meta.Audio = &audioStream{
+			Codec:      "mp" + strconv.Itoa(h.layer),
+			SampleRate: h.sampleRate,
+			Channels:   h.channels,
+		}
+		if frames := mp3FrameCount(r, frameStart, h); frames > 0 {
+			meta.Duration = float64(frames) * float64(h.samplesPerFrame) / float64(h.sampleRate)
+			meta.Bitrate = int64(float64(end-frameStart) * 8 / meta.Duration)
+		} else {
+			meta.Bitrate = int64(h.bitrate) * 1000
+			meta.Duration = float64(end-frameStart) * 8 / float64(meta.Bitrate)
+		}
+		return meta, nil
+	}
+	return nil, errInvalidMedia
+}
+
+// mp3FrameCount 从第一帧中的 Xing/Info 或 VBRI 头读取总帧数，没有时返回 0
+func mp3FrameCount(r io.ReaderAt, frameStart int64, h *mpegHeader) uint32 {
+	frame, err := readAtFull(r, frameStart, 4+32+26)
+	if err != nil {
+		return 0
+	}
+	// Xing 头位于帧头和 side info 之后
+	sideInfo := 32
+	switch {
+	case h.mpeg1 && h.channels == 1:
+		sideInfo = 17
+	case !h.mpeg1 && h.channels == 2:
+		sideInfo = 17
+	case !h.mpeg1:
+		sideInfo = 9
+	}
+	xing := frame[4+sideInfo:]
+	if tag := string(xing[:4]); (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(xing[4:8])&1 != 0 {
+		return binary.BigEndian.Uint32(xing[8:12])
+	}
+	// VBRI 头固定位于帧头之后 32 字节：标识(4) 版本(2) 延迟(2) 质量(2) 字节数(4) 帧数(4)
+	if vbri := frame[36:]; string(vbri[:4]) == "VBRI" {
+		return binary.BigEndian.Uint32(vbri[14:18])
+	}
+	return 0
+}
+
+// synchsafe 解码 ID3v2 中每字节只用低 7 位的整数
+func synchsafe(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<7 | uint32(c&0x7F)
+	}
+	return v
+}
+
+// parseID3v2 解析 ID3v2 标签中的文本帧，最多读取 maxMetaChunkSize 字节
+func parseID3v2(r io.ReaderAt, header []byte, tagSize int64, tags map[string]string) {
+	major := header[3]
+	flags := header[5]
+	data, err := readAtFull(r, 10, int(min(tagSize, maxMetaChunkSize)))
+	if err != nil || major < 2 || major > 4 {
+		return
+	}
+	if flags&0x80 != 0 && major < 4 { // 整个标签做了反同步处理
+		data = bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if flags&0x40 != 0 && major >= 3 && len(data) >= 4 { // 扩展头
+		extSize := int(binary.BigEndian.Uint32(data[:4]))
+		if major == 4 {
+			extSize = int(synchsafe(data[:4]))
+		} else {
+			extSize += 4
+		}
+		if extSize > len(data) {
+			return
+		}
+		data = data[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	if major == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(data) >= headerLen && data[0] != 0 {
+		id := string(data[:idLen])
+		var size int
+		switch major {
+		case 2:
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(data[4:8]))
+		case 4:
+			size = int(synchsafe(data[4:8]))
+		}
+		if size < 0 || headerLen+size > len(data) {
+			return
+		}
+		body := data[headerLen : headerLen+size]
+		compressed := major >= 3 && data[9]&0xC0 != 0 // 压缩或加密的帧
+		if key := id3Frames[id]; key != "" && !compressed {
+			setMediaTag(tags, key, decodeID3Text(body))
+		}
+		data = data[headerLen+size:]
+	}
+}
+
+// decodeID3Text 解码 ID3v2 文本帧：编码字节 + 文本，多个值时只取第一个
+func decodeID3Text(body []byte) string {
+	if len(body) < 2 {
+		return ""
+	}
+	text := body[1:]
+	switch body[0] {
+	case 0: // ISO-8859-1
+		runes := make([]rune, 0, len(text))
+		for _, b := range text {
+			if b == 0 {
+				break
+			}
+			runes = append(runes, rune(b))
+		}
+		return string(runes)
+	case 1, 2: // UTF-16（带 BOM）/ UTF-16BE
+		bigEndian := body[0] == 2
+		if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			bigEndian, text = true, text[2:]
+		} else if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			bigEndian, text = false, text[2:]
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			var u uint16
+			if bigEndian {
+				u = binary.BigEndian.Uint16(text[i:])
+			} else {
+				u = binary.LittleEndian.Uint16(text[i:])
+			}
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		return string(utf16.Decode(units))
+	case 3: // UTF-8
+		if i := bytes.IndexByte(text, 0); i >= 0 {
+			text = text[:i]
+		}
+		return string(text)
+	}
+	return ""
+}
+
+// parseID3v1 解析文件末尾 128 字节的 ID3v1 标签，仅在 ID3v2 缺少对应字段时使用
+func parseID3v1(tail []byte, tags map[string]string) {
+	field := func(from, to int) string {
+		b := tail[from:to]
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(decodeID3Text(append([]byte{0}, b...)))
+	}
+	setMediaTag(tags, "title", field(3, 33))
+	setMediaTag(tags, "artist", field(33, 63))
+	setMediaTag(tags, "album", field(63, 93))
+	setMediaTag(tags, "date", field(93, 97))
+	if tail[125] == 0 && tail[126] != 0 { // ID3v1.1 音轨号
+		setMediaTag(tags, "track", strconv.Itoa(int(tail[126])))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// FLAC
+// ---------------------------------------------------------------------------
+
+// parseFLAC 解析 FLAC 的 STREAMINFO 和 VORBIS_COMMENT 元数据块，start 为 fLaC 标识的位置
+func parseFLAC(r io.ReaderAt, start, size int64) (*mediaMeta, error) {
+	meta := &mediaMeta{Format: "flac", Tags: map[string]string{}}
+	off := start + 4
+	var totalSamples uint64
+	for i := 0; i < maxMediaBoxes; i++ {
+		hdr, err := readAtFull(r, off, 4)
+		if err != nil {
+			return nil, err
+		}
+		last := hdr[0]&0x80 != 0
+		typ := hdr[0] & 0x7F
+		length := int64(hdr[1])<<16 | int64(hdr[2])<<8 | int64(hdr[3])
+		off += 4
+
+		switch typ {
+		case 0: // STREAMINFO
+			info, err := readAtFull(r, off, 18)
+			if err != nil {
+				return nil, err
+			}
+			// 最小/最大块大小(2+2) 最小/最大帧大小(3+3)，然后是 采样率(20 位) 声道数-1(3 位) 位深-1(5 位) 总样本数(36 位)
+			meta.Audio = &audioStream{
+				Codec:         "flac",
+				SampleRate:    int(info[10])<<12 | int(info[11])<<4 | int(info[12])>>4,
+				Channels:      int(info[12]>>1&7) + 1,
+				BitsPerSample: int(info[12]&1)<<4 | int(info[13]>>4) + 1,
+			}
+			totalSamples = uint64(info[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+		case 4: // VORBIS_COMMENT
+			if data, err := readAtFull(r, off, int(min(length, maxMetaChunkSize))); err == nil {
+				parseVorbisComments(data, meta.Tags)
+			}
+		}
+		off += length
+		if last {
+			break
+		}
+	}
+	if meta.Audio == nil {
+		return nil, errInvalidMedia
+	}
+	if meta.Audio.SampleRate > 0 && totalSamples > 0 {
+		meta.Duration = float64(totalSamples) / float64(meta.Audio.SampleRate)
+		meta.Bitrate = int64(float64(size-off) * 8 / meta.Duration)
+	}
+	return meta, nil
+}
+
+// parseVorbisComments 解析 Vorbis 注释：厂商字符串 + 若干 KEY=value（均为小端长度前缀）
+func parseVorbisComments(data []byte, tags map[string]string) {
+	next := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := binary.LittleEndian.Uint32(data[:4])
+		if uint64(n) > uint64(len(data)-4) {
+			return "", false
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, true
+	}
+	if _, ok := next(); !ok || len(data) < 4 { // 厂商字符串
+		return
+	}
+	count := binary.LittleEndian.Uint32(data[:4])
+	data = data[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return
+		}
+		if key, value, ok := strings.Cut(comment, "="); ok {
+			setMediaTag(tags, mediaTagKeys[strings.ToUpper(key)], value)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// WAV（RIFF）
+// ---------------------------------------------------------------------------
+
+// wavCodecs 将 WAVE 格式码映射为通用编码名
+var wavCodecs = map[uint16]string{
+	0x0001: "pcm", 0x0002: "adpcm_ms", 0x0003: "pcm_float", 0x0006: "alaw",
+	0x0007: "mulaw", 0x0011: "adpcm_ima", 0x0055: "mp3",
+}
+
+// wavInfoTags 将 LIST/INFO 子块映射为统一的键
+var wavInfoTags = map[string]string{
+	"INAM": "title", "IART": "artist", "IPRD": "album", "ICRD": "date",
+	"IGNR": "genre", "ICMT": "comment", "ITRK": "track",
+}
+
+// parseWAV 解析 WAV 的 fmt、data 和 LIST/INFO 块
+func parseWAV(r io.ReaderAt, size int64) (*mediaMeta, error) {
+	meta := &mediaMeta{Format: "wav", Tags: map[string]string{}}
+	var byteRate uint32
+	dataSize := int64(-1)
+	off := int64(12)
+	for i := 0; off+8 <= size && i < maxMediaBoxes; i++ {
+		hdr, err := readAtFull(r, off, 8)
+		if err != nil {
+			return nil, err
+		}
+		id := string(hdr[:4])
+		length := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+		body := off + 8
+
+		switch id {
+		case "fmt ":
+			fmtData, err := readAtFull(r, body, int(min(length, 40)))
+			if err != nil || len(fmtData) < 16 {
+				return nil, errInvalidMedia
+			}
+			format := binary.LittleEndian.Uint16(fmtData[:2])
+			if format == 0xFFFE && len(fmtData) >= 26 { // WAVE_FORMAT_EXTENSIBLE：子格式 GUID 的前两字节
+				format = binary.LittleEndian.Uint16(fmtData[24:26])
+			}
+			codec := wavCodecs[format]
+			if codec == "" {
+				codec = "0x" + strconv.FormatUint(uint64(format), 16)
+			}
+			meta.Audio = &audioStream{
+				Codec:         codec,
+				Channels:      int(binary.LittleEndian.Uint16(fmtData[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(fmtData[4:8])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(fmtData[14:16])),
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtData[8:12])
+		case "data":
+			// 流式写入的文件长度可能为 0 或 0xFFFFFFFF
+			dataSize = length
+			if length == 0 || length == 0xFFFFFFFF || body+length > size {
+				dataSize = size - body
+			}
+		case "LIST":
+			if data, err := readAtFull(r, body, int(min(length, maxMetaChunkSize))); err == nil &&
+				len(data) >= 4 && string(data[:4]) == "INFO" {
+				parseRIFFInfo(data[4:], meta.Tags)
+			}
+		}
+		if dataSize >= 0 && length == 0xFFFFFFFF {
+			break
+		}
+		off = body + length + length&1 // 块按偶数字节对齐
+	}
+	if meta.Audio == nil {
+		return nil, errInvalidMedia
+	}
+	if byteRate > 0 {
+		meta.Bitrate = int64(byteRate) * 8
+		if dataSize > 0 {
+			meta.Duration = float64(dataSize) / float64(byteRate)
+		}
+	}
+	return meta, nil
+}
+
+// parseRIFFInfo 解析 LIST/INFO 中的子块：标识(4) + 长度(4) + 以 NUL 结尾的字符串
+func parseRIFFInfo(data []byte, tags map[string]string) {
+	for len(data) >= 8 {
+		id := string(data[:4])
+		n := int(binary.LittleEndian.Uint32(data[4:8]))
+		if n < 0 || 8+n > len(data) {
+			return
+		}
+		setMediaTag(tags, wavInfoTags[id], string(data[8:8+n]))
+		data = data[min(8+n+n&1, len(data)):]
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	sniffLen         = 8 * 1024  // 内容嗅探读取的字节数
+	binaryRatio      = 0.1       // 无法解码的字符占比超过该值视为二进制
+	hexRowSize       = 16        // 十六进制转储每行字节数
+	defaultHexLimit  = 4 * 1024  // 十六进制模式默认读取字节数
+	maxHexLimit      = 64 * 1024 // 十六进制模式单次最多读取字节数
+	genericMediaType = "application/octet-stream"
+)
+
+// textMediaTypes 虽非 text/* 但内容为文本的 MIME 类型
+var textMediaTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"image/svg+xml":          true,
+}
+
+// hexRow 十六进制转储中的一行
+type hexRow struct {
+	Offset int64  `json:"offset"` // 该行起始偏移量
+	Hex    string `json:"hex"`    // 以空格分隔的十六进制字节
+	ASCII  string `json:"ascii"`  // 可打印字符，其余以 . 代替
+}
+
+// hexResponse 十六进制预览响应
+type hexResponse struct {
+	Path       string   `json:"path"`       // 相对路径
+	Name       string   `json:"name"`       // 文件名
+	Size       int64    `json:"size"`       // 文件总大小
+	Modified   string   `json:"modified"`   // 修改时间
+	MimeType   string   `json:"mimeType"`   // 检测到的 MIME 类型
+	IsBinary   bool     `json:"isBinary"`   // 是否为二进制文件
+	Offset     int64    `json:"offset"`     // 读取偏移量（按行宽对齐）
+	Limit      int64    `json:"limit"`      // 实际读取限制（不超过文件剩余大小）
+	NextOffset int64    `json:"nextOffset"` // 下一页起始偏移量
+	HasMore    bool     `json:"hasMore"`    // 是否还有更多内容
+	Rows       []hexRow `json:"rows"`       // 转储内容
+
+	Compression    string `json:"compression,omitempty"`    // 透明解压的压缩格式
+	CompressedSize int64  `json:"compressedSize,omitempty"` // 压缩文件大小
+	SizeUnknown    bool   `json:"sizeUnknown,omitempty"`    // 解压后的大小未知（size 为 0）
+}
+
+// readSniffHead 读取文件开头用于内容嗅探的字节
+func readSniffHead(r io.ReaderAt) ([]byte, error) {
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// sniffContent 根据文件开头的内容判断是否为二进制，并检测 MIME 类型
+// 判断依据：魔数（http.DetectContentType）、文本编码（见 detectTextEncoding）、NUL 字节、无法解码的字符占比；
+// 文本的 MIME 类型带有检测到的编码，例如 GBK 文本为 text/plain; charset=gb18030
+func sniffContent(name string, head []byte) (bool, string) {
+	detected := http.DetectContentType(head)
+	enc, _ := detectTextEncoding(head)
+	isBinary := len(head) > 0 && isBinaryContent(detected, enc, head)
+	mimeType := detectMimeType(name, detected, isBinary)
+	if !isBinary {
+		mimeType = withCharset(mimeType, enc)
+	}
+	return isBinary, mimeType
+}
+
+// isBinaryContent 判断内容是否为二进制，detected 为魔数检测结果，enc 为 detectTextEncoding 检测到的编码
+func isBinaryContent(detected, enc string, head []byte) bool {
+	// 魔数识别为已知二进制格式
+	if !isTextMediaType(detected) && !strings.HasPrefix(detected, genericMediaType) {
+		return true
+	}
+
+	switch enc {
+	case tableEncodingUTF16L, tableEncodingUTF16B:
+		// 带 BOM 的 UTF-16 文本中 ASCII 字符的高字节为 NUL
+		return false
+	case tableEncodingUTF8:
+		// 合法的 UTF-8，文本文件中不会出现 NUL 字节
+		return bytes.IndexByte(head, 0) >= 0
+	}
+
+	// 不是合法的 UTF-8 时按 GB18030 解码（GBK 文本），无法解码的字符和控制字符占比过高时视为二进制
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	decoded, err := textDecoder(tableEncodingGB).Bytes(head)
+	if err != nil {
+		return true
+	}
+	invalid, total := 0, 0
+	for _, r := range string(decoded) {
+		total++
+		if r == utf8.RuneError || (r < ' ' && !strings.ContainsRune("\t\n\r\f\v\x1b", r)) {
+			invalid++
+		}
+	}
+	return float64(invalid) > float64(total)*binaryRatio
+}
+
+// withCharset 将文本 MIME 类型的 charset 参数设置为检测到的非 UTF-8 编码
+func withCharset(mimeType, enc string) string {
+	if enc == tableEncodingUTF8 || !isTextMediaType(mimeType) {
+		return mimeType
+	}
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return mimeType
+	}
+	params["charset"] = enc
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// detectMimeType 优先使用魔数检测结果，仅能识别为通用类型时按扩展名推断
+// 文本内容不会采用非文本的扩展名类型（例如 .ts 在部分系统上映射为 video/mp2t）
+func detectMimeType(name, detected string, isBinary bool) string {
+	if !strings.HasPrefix(detected, genericMediaType) && !strings.HasPrefix(detected, "text/plain") {
+		return detected
+	}
+	byExt := mime.TypeByExtension(filepath.Ext(name))
+	if byExt == "" || (!isBinary && !isTextMediaType(byExt)) {
+		return detected
+	}
+	return byExt
+}
+
+// isTextMediaType 判断 MIME 类型是否表示文本内容
+func isTextMediaType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || textMediaTypes[mediaType]
+}
+
+// hexDump 将 data 按 hexRowSize 字节一行格式化，base 为 data 在文件中的起始偏移
+func hexDump(data []byte, base int64) []hexRow {
+	rows := make([]hexRow, 0, (len(data)+hexRowSize-1)/hexRowSize)
+	var hexPart, asciiPart strings.Builder
+	const digits = "0123456789abcdef"
+	for start := 0; start < len(data); start += hexRowSize {
+		line := data[start:min(start+hexRowSize, len(data))]
+		hexPart.Reset()
+		asciiPart.Reset()
+		for i, b := range line {
+			if i > 0 {
+				hexPart.WriteByte(' ')
+			}
+			hexPart.WriteByte(digits[b>>4])
+			hexPart.WriteByte(digits[b&0x0f])
+			if b >= 0x20 && b < 0x7f {
+				asciiPart.WriteByte(b)
+			} else {
+				asciiPart.WriteByte('.')
+			}
+		}
+		rows = append(rows, hexRow{
+			Offset: base + int64(start),
+			Hex:    hexPart.String(),
+			ASCII:  asciiPart.String(),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		head     []byte
+		isBinary bool
+		mimeType string
+	}{
+		{"empty file", "empty", nil, false, "text/plain; charset=utf-8"},
+		{"plain text", "a", []byte("hello world\n"), false, "text/plain; charset=utf-8"},
+		{"utf8 text", "a", []byte("你好，世界\n"), false, "text/plain; charset=utf-8"},
+		{"nul bytes", "a", []byte("abc\x00def"), true, "application/octet-stream"},
+		{"png magic", "a.bin", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true, "image/png"},
+		{"gzip magic", "a.gz", []byte{0x1f, 0x8b, 0x08, 0x00}, true, "application/x-gzip"},
+		{"invalid utf8", "a", []byte{'a', 0xff, 0xfe, 0xfd, 0xfc, 0xfb}, true, "text/plain; charset=utf-8"},
+		{"gbk text", "a.txt", []byte("\xc4\xe3\xba\xc3\xa3\xac\xca\xc0\xbd\xe7\n"), false, "text/plain; charset=gb18030"},
+		{"utf16le bom", "a.txt", []byte("\xff\xfeh\x00i\x00\n\x00"), false, "text/plain; charset=utf-16le"},
+		{"utf16be bom", "a.csv", []byte("\xfe\xff\x00h\x00i"), false, "text/csv; charset=utf-16be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isBinary, mimeType := sniffContent(tt.file, tt.head)
+			assert.Equal(t, tt.isBinary, isBinary)
+			assert.Equal(t, tt.mimeType, mimeType)
+		})
+	}
+}
+
+func TestSniffContent_TextIgnoresBinaryExtension(t *testing.T) {
+	// .ts 在部分系统的 MIME 表中映射为 video/mp2t
+	isBinary, mimeType := sniffContent("a.ts", []byte("export const a = 1\n"))
+
+	assert.False(t, isBinary)
+	assert.True(t, isTextMediaType(mimeType))
+}
+
+func TestHexDump(t *testing.T) {
+	data := []byte("ABCDEFGHIJKLMNOP\x00\x01z")
+	rows := hexDump(data, 32)
+
+	assert.Len(t, rows, 2)
+	assert.Equal(t, int64(32), rows[0].Offset)
+	assert.Equal(t, "41 42 43 44 45 46 47 48 49 4a 4b 4c 4d 4e 4f 50", rows[0].Hex)
+	assert.Equal(t, "ABCDEFGHIJKLMNOP", rows[0].ASCII)
+	assert.Equal(t, int64(48), rows[1].Offset)
+	assert.Equal(t, "00 01 7a", rows[1].Hex)
+	assert.Equal(t, "..z", rows[1].ASCII)
+}
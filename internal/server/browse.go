@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// listingTemplate 服务端渲染的目录列表页面，不依赖 JavaScript
+var listingTemplate = template.Must(template.ParseFS(templateFS, "templates/listing.html"))
+
+// listingPage 目录列表页面的模板数据
+type listingPage struct {
+	Path    string        // 当前目录
+	Crumbs  []listingLink // 面包屑导航
+	Parent  string        // 上级目录链接，根目录为空
+	Entries []listingRow  // 目录内容
+}
+
+// listingLink 页面中的链接
+type listingLink struct {
+	Name string
+	Href string
+}
+
+// listingRow 目录列表中的一行
+type listingRow struct {
+	Name     string
+	Href     string
+	Dir      bool
+	Size     string
+	Modified string
+}
+
+// handleBrowse 服务端渲染的目录浏览页面，供 curl、文本浏览器等不执行 JavaScript 的客户端使用
+// GET /browse/some/dir
+// 目录返回 HTML 表格，文件重定向到 /raw 原始内容地址
+func (s *Server) handleBrowse(c *gin.Context) {
+	reqPath := c.Param("path")
+
+	if _, inArchive, _ := s.resolveArchivePath(reqPath); !inArchive {
+		if absPath, relPath, err := s.resolvePath(reqPath); err == nil {
+			if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+				c.Redirect(http.StatusFound, s.rawURL(path.Join("/", relPath)))
+				return
+			}
+		}
+	}
+
+	items, _, ok := s.listEntries(c, reqPath)
+	if !ok {
+		return
+	}
+	s.renderListing(c, reqPath, items)
+}
+
+// renderListing 将目录内容渲染为 HTML 表格
+func (s *Server) renderListing(c *gin.Context, reqPath string, items []fileEntry) {
+	dirPath := path.Clean("/" + strings.TrimSpace(reqPath))
+	page := listingPage{
+		Path:    dirPath,
+		Crumbs:  []listingLink{{Name: "/", Href: s.browseURL("/")}},
+		Entries: make([]listingRow, 0, len(items)),
+	}
+	if dirPath != "/" {
+		page.Parent = s.browseURL(path.Dir(dirPath))
+		current := ""
+		for _, part := range strings.Split(strings.TrimPrefix(dirPath, "/"), "/") {
+			current += "/" + part
+			page.Crumbs = append(page.Crumbs, listingLink{Name: part, Href: s.browseURL(current)})
+		}
+	}
+
+	for _, item := range items {
+		row := listingRow{
+			Name:     item.Name,
+			Dir:      item.Type == "dir",
+			Size:     formatSize(item.Size),
+			Modified: listingTime(item.Modified),
+		}
+		switch {
+		case row.Dir:
+			row.Href = s.browseURL(item.Path)
+		case item.Archive:
+			row.Href = s.browseURL(item.Path + archiveSeparator)
+		default:
+			row.Href = s.rawURL(item.Path)
+		}
+		page.Entries = append(page.Entries, row)
+	}
+
+	var buf bytes.Buffer
+	if err := listingTemplate.Execute(&buf, page); err != nil {
+		abortWithError(c, http.StatusInternalServerError, "RENDER_FAILED", err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// writeListingText 以 ls -l 风格的纯文本返回目录内容：类型、大小、修改时间、名称（目录以 / 结尾）
+func writeListingText(c *gin.Context, items []fileEntry) {
+	var b strings.Builder
+	for _, item := range items {
+		kind, name := "-", item.Name
+		if item.Type == "dir" {
+			kind, name = "d", name+"/"
+		}
+		// 名称中的换行等控制字符会打乱逐行解析，按 Go 字符串字面量转义
+		if strings.ContainsFunc(name, func(r rune) bool { return r < ' ' || r == 0x7F }) {
+			name = strconv.Quote(name)
+		}
+		fmt.Fprintf(&b, "%s %12d %s %s\n", kind, item.Size, listingTime(item.Modified), name)
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
+
+// browseURL 返回目录浏览页面的地址（包含基础路径），目录地址以 / 结尾
+func (s *Server) browseURL(dirPath string) string {
+	escaped := (&url.URL{Path: path.Join("/browse", dirPath)}).EscapedPath()
+	return s.cfg.BasePath + strings.TrimSuffix(escaped, "/") + "/"
+}
+
+// listingTime 将 RFC3339 时间格式化为列表中显示的形式
+func listingTime(modified string) string {
+	t, err := time.Parse(time.RFC3339, modified)
+	if err != nil {
+		return modified
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// formatSize 将字节数格式化为易读的大小，例如 1.5 KB
+func formatSize(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+	value := float64(size)
+	units := []string{"KB", "MB", "GB", "TB", "PB"}
+	unit := ""
+	for _, u := range units {
+		value /= 1024
+		unit = u
+		if value < 1024 {
+			break
+		}
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + unit
+}
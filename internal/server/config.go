@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -12,18 +13,26 @@ import (
 
 // 默认配置值
 const (
-	defaultHost             = "127.0.0.1"      // 默认监听地址
-	defaultPort             = 3000             // 默认端口
-	defaultPreviewMax       = 1 * 1024 * 1024  // 默认预览大小限制 (1MB)
+	defaultHost          = "127.0.0.1"       // 默认监听地址
+	defaultPort          = 3000              // 默认端口
+	defaultPreviewMax    = 1 * 1024 * 1024   // 默认预览大小限制 (1MB)
+	defaultPreviewLines  = 1000              // 行模式默认返回行数
+	maxPreviewLines      = 100000            // 行模式单次最多返回行数
+	defaultThumbCacheMax = 256 * 1024 * 1024 // 默认缩略图缓存上限 (256MB)
 )
 
 // Config 服务器配置
 type Config struct {
-	Root       string // 文件浏览根目录（绝对路径）
-	Host       string // 监听地址
-	Port       int    // 监听端口
-	PreviewMax int64  // 文件预览最大字节数
-	BasePath   string // 基础路径（用于反向代理子路径部署，例如 /files）
+	Root          string // 文件浏览根目录（绝对路径）
+	Host          string // 监听地址
+	Port          int    // 监听端口
+	PreviewMax    int64  // 文件预览最大字节数
+	BasePath      string // 基础路径（用于反向代理子路径部署，例如 /files）
+	CacheDir      string // 缓存目录（缩略图等），为空时不使用磁盘缓存
+	ThumbCacheMax int64  // 缩略图缓存总大小上限
+	RawCSP        string // /raw 返回的 Content-Security-Policy，为空时不设置
+	RawOrigin     string // /raw 使用的独立源（例如 https://raw.example.com），为空时与主站同源
+	NameIndex     bool   // 是否在后台构建文件名索引，用于加速递归搜索
 }
 
 // Addr 返回监听地址，格式为 host:port
@@ -38,6 +47,11 @@ func (c Config) Addr() string {
 //	--host: 监听地址（默认 127.0.0.1）
 //	--port: 监听端口（默认 3000）
 //	--preview-max: 预览大小限制（默认 1MB）
+//	--cache-dir: 缓存目录（默认为系统用户缓存目录下的 file-browser）
+//	--thumb-cache-max: 缩略图缓存上限（默认 256MB）
+//	--raw-csp: /raw 返回的内容安全策略（默认沙箱，设为空字符串关闭）
+//	--raw-origin: /raw 使用的独立源，例如 https://raw.example.com
+//	--name-index: 后台构建文件名索引并监听变化（默认关闭，索引持久化到缓存目录）
 //
 // 环境变量：FILE_BROWSER_PATH、FILE_BROWSER_HOST 等
 func ParseConfig() (Config, error) {
@@ -49,6 +63,11 @@ func ParseConfig() (Config, error) {
 	fs.IntVar(&cfg.Port, "port", defaultPort, "port to listen on")
 	previewMax := fs.String("preview-max", "1MB", "max preview size (e.g. 1MB, 512KB)")
 	fs.StringVar(&cfg.BasePath, "base-path", "", "base path for reverse proxy deployment (e.g. /files)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", defaultCacheDir(), "cache directory for thumbnails")
+	thumbCacheMax := fs.String("thumb-cache-max", "256MB", "max total size of the thumbnail cache (e.g. 256MB, 1GB)")
+	fs.StringVar(&cfg.RawCSP, "raw-csp", defaultRawCSP, "Content-Security-Policy for /raw (empty to disable)")
+	fs.StringVar(&cfg.RawOrigin, "raw-origin", "", "separate origin serving /raw (e.g. https://raw.example.com)")
+	fs.BoolVar(&cfg.NameIndex, "name-index", false, "index file names in the background for fast recursive search (walks the whole tree and watches every directory)")
 
 	// 应用环境变量默认值（优先级低于命令行参数）
 	applyEnvDefaults(fs)
@@ -62,6 +81,15 @@ func ParseConfig() (Config, error) {
 		return Config{}, fmt.Errorf("invalid --preview-max: %w", err)
 	}
 
+	// 解析缩略图缓存上限
+	cfg.ThumbCacheMax, err = parseBytes(*thumbCacheMax)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid --thumb-cache-max: %w", err)
+	}
+	if cfg.ThumbCacheMax <= 0 {
+		cfg.ThumbCacheMax = defaultThumbCacheMax
+	}
+
 	if cfg.Root == "" {
 		return Config{}, errors.New("--path is required")
 	}
@@ -93,9 +121,27 @@ func ParseConfig() (Config, error) {
 	// 规范化 BasePath
 	cfg.BasePath = normalizeBasePath(cfg.BasePath)
 
+	// 独立源必须是 http(s)://host 形式，可带路径前缀
+	if cfg.RawOrigin != "" {
+		origin, err := url.Parse(cfg.RawOrigin)
+		if err != nil || (origin.Scheme != "http" && origin.Scheme != "https") || origin.Host == "" {
+			return Config{}, fmt.Errorf("invalid --raw-origin: %q", cfg.RawOrigin)
+		}
+		cfg.RawOrigin = strings.TrimSuffix(cfg.RawOrigin, "/")
+	}
+
 	return cfg, nil
 }
 
+// defaultCacheDir 返回默认缓存目录：用户缓存目录下的 file-browser，无法获取时使用临时目录
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "file-browser")
+}
+
 // applyEnvDefaults 从环境变量应用默认值
 // 环境变量命名规则：FILE_BROWSER_<大写参数名>
 // 例如：--preview-max 对应 FILE_BROWSER_PREVIEW_MAX
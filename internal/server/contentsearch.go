@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxContentSearchFileSize = 16 * 1024 * 1024 // 超过该大小的文件不搜索内容
+	contentMatchesPerFile    = 5                // 每个文件最多返回的匹配行数
+	contentSearchTimeout     = 10 * time.Second // 单次内容搜索的总时间预算
+	maxContentSearchWorkers  = 8                // 同时扫描的文件数上限
+)
+
+// contentResult 内容搜索结果：文件信息和匹配行
+type contentResult struct {
+	fileEntry
+	Matches []grepMatch `json:"matches"` // 匹配行（行号、偏移、片段和匹配位置）
+	HasMore bool        `json:"hasMore"` // 达到每个文件的匹配行数上限，其余匹配可通过 /api/grep 查看
+}
+
+// contentSearchResponse 内容搜索响应
+type contentSearchResponse struct {
+	Results []contentResult `json:"results"`
+	searchPage
+}
+
+// contentFile 按遍历顺序分发的一个待扫描文件
+type contentFile struct {
+	rel    string         // 相对搜索目录的路径
+	done   bool           // 是否已扫描完成（超时或取消时可能未完成）
+	result *contentResult // 匹配结果，没有匹配时为 nil
+}
+
+// contentSearch 一次内容搜索的参数和状态
+type contentSearch struct {
+	absPath string // 搜索的目录
+	relPath string // 目录相对于根目录的路径
+	match   grepMatcher
+	filter  *searchFilter
+	ignore  *ignoreMatcher // gitignore=true 时按忽略规则跳过文件和目录
+	after   string         // 游标：上一页最后扫描的文件相对搜索目录的路径
+	limit   int
+
+	mu     sync.Mutex
+	files  []contentFile // 按遍历顺序分发的文件
+	found  int           // 有匹配的文件数
+	walked bool          // 遍历完整结束（没有因结果足够、超时或取消而提前停止）
+}
+
+// handleContentSearch 在目录下递归搜索文件内容
+// GET /api/search?mode=content&path=/src&q=TODO[&regex=true&caseSensitive=true&limit=100&cursor=]
+// 默认忽略大小写，caseSensitive=true 或 ignoreCase=false 时区分大小写；
+// 使用有限数量的 worker 并发扫描，跳过二进制文件和超过 maxContentSearchFileSize 的文件，
+// 只扫描满足过滤条件（扩展名、大小、修改时间、深度）且未被忽略（gitignore=true）的文件；
+// 客户端断开时立即停止，超出时间预算时返回已找到的结果并标记 truncated，可使用 cursor 继续
+func (s *Server) handleContentSearch(c *gin.Context, query string, filter *searchFilter, after string, limit int) {
+	matcher, err := newGrepMatcher(query, c.Query("regex") == "true", queryIgnoreCase(c, true))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PATTERN", err.Error())
+		return
+	}
+
+	absPath, relPath, err := s.resolvePath(c.Query("path"))
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+
+	search := &contentSearch{absPath: absPath, relPath: relPath, match: matcher, filter: filter, ignore: s.gitIgnore(c), after: after, limit: limit}
+	search.run(c.Request.Context())
+	if c.Request.Context().Err() != nil {
+		c.Abort() // 客户端已断开
+		return
+	}
+
+	resp := contentSearchResponse{}
+	resp.Results, resp.searchPage = search.page()
+	c.JSON(http.StatusOK, resp)
+}
+
+// run 遍历目录并由 worker 池扫描文件
+// 找到 limit+1 个匹配的文件后停止分发（已分发的文件继续扫描完），超出时间预算或 parent 取消时立即停止
+func (cs *contentSearch) run(parent context.Context) {
+	ctx, cancel := context.WithTimeout(parent, contentSearchTimeout)
+	defer cancel()
+
+	type job struct {
+		seq      int
+		walkPath string
+	}
+	jobs := make(chan job, maxContentSearchWorkers*4)
+	enough := make(chan struct{})
+	var enoughOnce sync.Once
+
+	var wg sync.WaitGroup
+	for range min(max(runtime.NumCPU(), 2), maxContentSearchWorkers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue // 已停止，排空队列
+				}
+				result, err := cs.scanFile(ctx, j.walkPath)
+				if errors.Is(err, errGrepCanceled) {
+					continue
+				}
+				cs.mu.Lock()
+				cs.files[j.seq].done = true
+				if result != nil {
+					cs.files[j.seq].result = result
+					cs.found++
+					if cs.found > cs.limit {
+						enoughOnce.Do(func() { close(enough) })
+					}
+				}
+				cs.mu.Unlock()
+			}
+		}()
+	}
+
+	stopped := func() bool {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-enough:
+			return true
+		default:
+			return false
+		}
+	}
+	filepath.WalkDir(cs.absPath, func(walkPath string, d fs.DirEntry, err error) error {
+		if stopped() {
+			return filepath.SkipAll
+		}
+		if err != nil || walkPath == cs.absPath {
+			return nil // 忽略无法读取的目录
+		}
+		rel := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(walkPath, cs.absPath)), "/")
+		if cs.ignore.isIgnored(path.Join(cs.relPath, rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		skip, err := skipBeforeCursor(rel, d.IsDir(), cs.after)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(rel, "/") + 1
+		if d.IsDir() {
+			if !cs.filter.descend(depth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if skip || !d.Type().IsRegular() || !cs.filter.withinDepth(depth) {
+			return nil // 跳过游标之前的文件、符号链接等特殊文件和超出深度限制的文件
+		}
+
+		cs.mu.Lock()
+		seq := len(cs.files)
+		cs.files = append(cs.files, contentFile{rel: rel})
+		cs.mu.Unlock()
+		select {
+		case jobs <- job{seq: seq, walkPath: walkPath}:
+		case <-ctx.Done():
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	cs.walked = !stopped()
+	close(jobs)
+	wg.Wait()
+}
+
+// page 按遍历顺序整理结果：只返回连续扫描完成的前缀中的结果，
+// 超时或取消时未完成的文件留给下一页，游标指向前缀中最后一个文件
+func (cs *contentSearch) page() ([]contentResult, searchPage) {
+	results := []contentResult{}
+	complete, scanned := 0, 0
+	for i, f := range cs.files {
+		if f.done {
+			scanned++
+		}
+		if complete == i && f.done {
+			complete++
+			if f.result != nil {
+				results = append(results, *f.result)
+			}
+		}
+	}
+
+	results, page := newSearchPage(results, cs.limit, scanned, func(r contentResult) string {
+		return relToSearchDir(cs.relPath, r.Path)
+	})
+	if !page.Truncated && (complete < len(cs.files) || !cs.walked) {
+		page.Truncated = true
+		cursor := cs.after
+		if complete > 0 {
+			cursor = cs.files[complete-1].rel
+		}
+		page.Cursor = encodeSearchCursor(cursor)
+	}
+	return results, page
+}
+
+// scanFile 扫描单个文件，没有匹配、为二进制文件、超出大小限制或不满足过滤条件时返回 nil
+// 扫描被取消时返回 errGrepCanceled
+func (cs *contentSearch) scanFile(ctx context.Context, walkPath string) (*contentResult, error) {
+	file, err := os.Open(walkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxContentSearchFileSize ||
+		!cs.filter.matches(info.Name(), false, info.Size(), info.ModTime()) {
+		return nil, err
+	}
+
+	head, err := readSniffHead(file)
+	if err != nil {
+		return nil, err
+	}
+	if isBinary, _ := sniffContent(info.Name(), head); isBinary {
+		return nil, nil
+	}
+
+	scan := grepScan{reader: file, match: cs.match, limit: contentMatchesPerFile, done: ctx.Done()}
+	matches, next, err := scan.run(0, 0)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+
+	return &contentResult{
+		fileEntry: newFileEntry(path.Join("/", cs.relPath, filepath.ToSlash(strings.TrimPrefix(walkPath, cs.absPath))), info),
+		Matches:   matches,
+		HasMore:   len(matches) >= contentMatchesPerFile && next < info.Size(),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSearch_Content(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "sub"), 0755))
+	files := map[string]string{
+		"src/main.go":      "package main\n\n// TODO: handle errors\nfunc main() {}\n",
+		"src/sub/util.go":  "package sub\n// todo lower case\n",
+		"src/readme.md":    "nothing here\n",
+		"src/image.png":    "\x89PNG\r\n\x1a\n\x00\x00TODO",
+		"other/notes.txt":  "TODO outside\n",
+		"src/sub/many.txt": strings.Repeat("TODO again\n", 10),
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+	// 超过大小上限的文件不搜索
+	big, err := os.Create(filepath.Join(root, "src", "big.log"))
+	require.NoError(t, err)
+	_, err = big.WriteString("TODO big\n")
+	require.NoError(t, err)
+	require.NoError(t, big.Truncate(maxContentSearchFileSize+1))
+	require.NoError(t, big.Close())
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string) (*httptest.ResponseRecorder, contentSearchResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp contentSearchResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+	paths := func(results []contentResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Path)
+		}
+		return out
+	}
+
+	// 默认忽略大小写，结果按遍历顺序排列
+	w, resp := get("/api/search?mode=content&path=/src&q=todo")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	results := resp.Results
+	assert.Equal(t, []string{"/src/main.go", "/src/sub/many.txt", "/src/sub/util.go"}, paths(results))
+	assert.False(t, resp.Truncated)
+	assert.Empty(t, resp.Cursor)
+	assert.Equal(t, 6, resp.Scanned)
+	first := results[0]
+	require.Len(t, first.Matches, 1)
+	assert.Equal(t, int64(3), first.Matches[0].Line)
+	assert.Equal(t, int64(strings.Index(files["src/main.go"], "// TODO")), first.Matches[0].Offset)
+	assert.Equal(t, "// TODO: handle errors", first.Matches[0].Text)
+	assert.Equal(t, [][2]int{{3, 7}}, first.Matches[0].Ranges)
+	assert.False(t, first.HasMore)
+	assert.Len(t, results[1].Matches, contentMatchesPerFile)
+	assert.True(t, results[1].HasMore)
+
+	// 区分大小写、正则表达式
+	_, resp = get("/api/search?mode=content&path=/src&q=TODO&caseSensitive=true")
+	assert.Equal(t, []string{"/src/main.go", "/src/sub/many.txt"}, paths(resp.Results))
+	_, resp = get("/api/search?mode=content&path=/src&q=TODO&ignoreCase=false")
+	assert.Equal(t, []string{"/src/main.go", "/src/sub/many.txt"}, paths(resp.Results))
+	_, resp = get("/api/search?mode=content&path=/&q=" + "TODO%3A%20%5Cw%2B" + "&regex=true")
+	assert.Equal(t, []string{"/src/main.go"}, paths(resp.Results))
+
+	// 过滤条件限制扫描的文件
+	_, resp = get("/api/search?mode=content&path=/&q=todo&ext=go&maxDepth=2")
+	assert.Equal(t, []string{"/src/main.go"}, paths(resp.Results))
+
+	// 达到 limit 时标记结果不完整，通过 cursor 获取下一页
+	_, resp = get("/api/search?mode=content&path=/&q=todo&limit=2")
+	assert.Equal(t, []string{"/other/notes.txt", "/src/main.go"}, paths(resp.Results))
+	assert.True(t, resp.Truncated)
+	require.NotEmpty(t, resp.Cursor)
+	_, resp = get("/api/search?mode=content&path=/&q=todo&limit=2&cursor=" + resp.Cursor)
+	assert.Equal(t, []string{"/src/sub/many.txt", "/src/sub/util.go"}, paths(resp.Results))
+	assert.False(t, resp.Truncated)
+
+	w, _ = get("/api/search?mode=content&path=/&q=(&regex=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_PATTERN")
+	w, _ = get("/api/search?mode=content&path=/&q=x&limit=0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/search?mode=content&path=/missing&q=x")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = get("/api/search?mode=content&path=/&q=x&cursor=bad")
+	assert.Contains(t, w.Body.String(), "INVALID_CURSOR")
+}
@@ -0,0 +1,509 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	decompressCacheSize = 8           // 最多缓存的压缩文件数量
+	decompressSpan      = 1024 * 1024 // 相邻检查点之间解压后的字节数
+	decompressChunk     = 32 * 1024   // 每次向前解压的最小字节数
+)
+
+// errDecompress 压缩数据损坏或格式不受支持
+var errDecompress = errors.New("decompress failed")
+
+// 支持透明解压的单文件压缩格式
+const (
+	compressionGzip  = "gzip"
+	compressionZstd  = "zstd"
+	compressionBzip2 = "bzip2"
+	compressionXz    = "xz"
+)
+
+// compressionSuffixes 压缩格式对应的扩展名
+var compressionSuffixes = map[string][]string{
+	compressionGzip:  {".gz", ".gzip"},
+	compressionZstd:  {".zst", ".zstd"},
+	compressionBzip2: {".bz2"},
+	compressionXz:    {".xz"},
+}
+
+// detectCompression 根据文件开头的魔数识别单文件压缩格式，不是压缩文件时返回空字符串
+// tar.gz 等压缩包通过 path!/ 浏览包内文件，不在此列
+func detectCompression(name string, head []byte) string {
+	if _, ok := archiveFormatOf(name); ok {
+		return ""
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return compressionGzip
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return compressionZstd
+	case len(head) >= 4 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		return compressionBzip2
+	case bytes.HasPrefix(head, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return compressionXz
+	}
+	return ""
+}
+
+// trimCompressionExt 去掉压缩扩展名，例如 app.log.3.gz -> app.log.3，用于按内容推断 MIME 类型
+func trimCompressionExt(name, compression string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range compressionSuffixes[compression] {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// newDecompressor 按格式创建解压流，解压后的数据量受 archiveMaxExpanded 限制
+func newDecompressor(compression string, r io.Reader) (io.Reader, func() error, error) {
+	var (
+		reader io.Reader
+		closer = func() error { return nil }
+		err    error
+	)
+	switch compression {
+	case compressionGzip:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(r)
+		if err == nil {
+			reader, closer = gz, gz.Close
+		}
+	case compressionZstd:
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err == nil {
+			reader, closer = zr, func() error { zr.Close(); return nil }
+		}
+	case compressionBzip2:
+		reader = bzip2.NewReader(r)
+	case compressionXz:
+		reader, err = xz.NewReader(r)
+	default:
+		err = fmt.Errorf("unsupported compression %q", compression)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errDecompress, err)
+	}
+	return &expandLimitReader{r: reader, remaining: archiveMaxExpanded}, closer, nil
+}
+
+// 检查点内容使用的 zstd 编解码器，EncodeAll/DecodeAll 可并发调用
+var (
+	spoolEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+	spoolDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// decompressIndex 单个压缩文件的检查点索引
+// 解压后每 decompressSpan 字节保存一个检查点：该段内容以独立的 zstd 帧追加到临时文件，
+// 读取已解压过的任意位置时只需解码所在的一段，不必从文件开头重新解压；
+// 只有超出已解压位置的读取才由停在末尾的解压器继续向后解压
+type decompressIndex struct {
+	mu      sync.Mutex
+	size    int64     // 压缩文件大小
+	modTime time.Time // 压缩文件修改时间
+	total   int64     // 解压后的总大小，-1 表示尚未解压到末尾
+	// 压缩数据来源：读取时指向当前请求已打开的文件，请求之间为空
+	// 解压器同步读取输入，只会在持有 mu 时通过 source 读取数据
+	source swapReaderAt
+
+	stream      io.Reader    // 停在已解压位置的解压器，nil 表示尚未开始或已解压到末尾
+	closeStream func() error // 关闭 stream
+	spans       []int64      // spans[i] 为第 i 个检查点在 spool 中的起始位置
+	spool       *os.File     // 保存检查点内容的临时文件
+	spoolEnd    int64        // spool 中已写入的字节数
+	pending     []byte       // 最后一个检查点之后已解压、尚不足一段的内容
+	cached      []byte       // 最近解码的一段内容
+	cachedSpan  int          // cached 对应的检查点序号，-1 表示没有
+	evicted     bool         // 已被淘汰，不再保存检查点
+}
+
+// swapReaderAt 可替换底层数据来源的 io.ReaderAt
+type swapReaderAt struct {
+	r io.ReaderAt
+}
+
+func (s *swapReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if s.r == nil {
+		return 0, os.ErrClosed
+	}
+	return s.r.ReadAt(p, off)
+}
+
+// decoded 返回已解压的字节数
+func (idx *decompressIndex) decoded() int64 {
+	return int64(len(idx.spans))*decompressSpan + int64(len(idx.pending))
+}
+
+// reset 关闭解压器和临时文件并清空索引
+func (idx *decompressIndex) reset() {
+	idx.stopStream()
+	if idx.spool != nil {
+		idx.spool.Close()
+		os.Remove(idx.spool.Name())
+	}
+	idx.spool, idx.spoolEnd, idx.spans = nil, 0, nil
+	idx.pending, idx.cached, idx.cachedSpan = nil, nil, -1
+	idx.total = -1
+}
+
+// stopStream 关闭停在已解压位置的解压器
+func (idx *decompressIndex) stopStream() {
+	if idx.stream != nil {
+		idx.closeStream()
+	}
+	idx.stream, idx.closeStream = nil, nil
+}
+
+// advance 继续解压至少 want 字节（不超过当前一段的剩余空间），写满一段时保存为检查点
+func (idx *decompressIndex) advance(compression string, want int64) error {
+	if idx.stream == nil {
+		r, closer, err := newDecompressor(compression, bufio.NewReader(io.NewSectionReader(&idx.source, 0, idx.size)))
+		if err != nil {
+			return err
+		}
+		idx.stream, idx.closeStream = r, closer
+	}
+	if idx.pending == nil {
+		idx.pending = make([]byte, 0, decompressSpan)
+	}
+
+	// 按 decompressChunk 向上取整，避免逐字节读取时频繁调用解压器
+	want = (max(want, 1) + decompressChunk - 1) / decompressChunk * decompressChunk
+	end := len(idx.pending) + int(min(want, int64(decompressSpan-len(idx.pending))))
+	n, err := io.ReadFull(idx.stream, idx.pending[len(idx.pending):end])
+	idx.pending = idx.pending[:len(idx.pending)+n]
+	// 截断的压缩文件（例如仍在写入）按已解压出的内容处理
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		idx.stopStream()
+		idx.total = idx.decoded()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(idx.pending) == decompressSpan {
+		return idx.saveSpan()
+	}
+	return nil
+}
+
+// saveSpan 将写满的一段压缩后追加到临时文件，成为新的检查点
+func (idx *decompressIndex) saveSpan() error {
+	if idx.spool == nil {
+		spool, err := os.CreateTemp("", "file-browser-decompress-*")
+		if err != nil {
+			return err
+		}
+		idx.spool = spool
+	}
+	frame := spoolEncoder.EncodeAll(idx.pending, nil)
+	if _, err := idx.spool.WriteAt(frame, idx.spoolEnd); err != nil {
+		return err
+	}
+	idx.spans = append(idx.spans, idx.spoolEnd)
+	idx.spoolEnd += int64(len(frame))
+	// 刚写满的一段很可能马上被读取，直接作为最近解码的内容
+	idx.cached, idx.cachedSpan = idx.pending, len(idx.spans)-1
+	idx.pending = nil
+	return nil
+}
+
+// span 返回第 i 段已解压的内容，最后一段为尚未保存的 pending
+func (idx *decompressIndex) span(i int) ([]byte, error) {
+	if i == len(idx.spans) {
+		return idx.pending, nil
+	}
+	if i == idx.cachedSpan {
+		return idx.cached, nil
+	}
+	end := idx.spoolEnd
+	if i+1 < len(idx.spans) {
+		end = idx.spans[i+1]
+	}
+	frame := make([]byte, end-idx.spans[i])
+	if _, err := idx.spool.ReadAt(frame, idx.spans[i]); err != nil {
+		return nil, err
+	}
+	data, err := spoolDecoder.DecodeAll(frame, idx.cached[:0])
+	if err != nil {
+		return nil, err
+	}
+	idx.cached, idx.cachedSpan = data, i
+	return data, nil
+}
+
+// readAt 从检查点读取 off 处的内容，必要时继续解压
+func (idx *decompressIndex) readAt(compression string, p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+	for idx.total < 0 && idx.decoded() < end {
+		if err := idx.advance(compression, end-idx.decoded()); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for n < len(p) && off+int64(n) < idx.decoded() {
+		pos := off + int64(n)
+		i := int(pos / decompressSpan)
+		data, err := idx.span(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos-int64(i)*decompressSpan:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// decompressCache 按文件缓存检查点索引，超出容量时淘汰最久未使用的条目
+type decompressCache struct {
+	lru *lruCache[string, *decompressIndex]
+}
+
+// newDecompressCache 创建解压检查点缓存
+func newDecompressCache(max int) *decompressCache {
+	lru := newLRUCache[string, *decompressIndex](int64(max), nil)
+	// 被淘汰的索引可能正在使用，加锁后再关闭解压器和临时文件
+	lru.onEvict = func(_ string, old *decompressIndex) {
+		old.mu.Lock()
+		old.reset()
+		old.evicted = true
+		old.mu.Unlock()
+	}
+	return &decompressCache{lru: lru}
+}
+
+// get 返回 key 对应的检查点索引，压缩文件大小或修改时间变化时重建
+func (c *decompressCache) get(key string, info os.FileInfo) *decompressIndex {
+	idx := c.lru.getOrAdd(key, func() *decompressIndex { return &decompressIndex{total: -1, cachedSpan: -1} })
+
+	idx.mu.Lock()
+	if idx.size != info.Size() || !idx.modTime.Equal(info.ModTime()) {
+		idx.reset()
+		idx.size, idx.modTime = info.Size(), info.ModTime()
+	}
+	idx.mu.Unlock()
+	return idx
+}
+
+// close 关闭所有索引，删除临时文件
+func (c *decompressCache) close() {
+	c.lru.purge()
+}
+
+// decompressReader 以解压后的偏移随机读取压缩文件，实现 io.ReaderAt
+type decompressReader struct {
+	index       *decompressIndex
+	compression string
+	raw         io.ReaderAt // 本次请求已打开的压缩数据
+	rawSize     int64
+	total       int64 // 索引被淘汰后由本次请求完整解压得到的总大小，-1 表示未知
+}
+
+// ReadAt 从检查点读取 off 处的内容，超出已解压位置时继续解压
+func (d *decompressReader) ReadAt(p []byte, off int64) (int, error) {
+	d.index.mu.Lock()
+	defer d.index.mu.Unlock()
+
+	if d.index.total >= 0 && off >= d.index.total {
+		return 0, io.EOF
+	}
+	var (
+		n   int
+		err error
+	)
+	if d.index.evicted {
+		// 索引已被淘汰：从头解压本次请求的数据，不再保存检查点
+		n, err = d.readOnce(p, off)
+	} else {
+		d.index.source.r = d.raw
+		n, err = d.index.readAt(d.compression, p, off)
+		d.index.source.r = nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		if !d.index.evicted {
+			d.index.reset()
+		}
+		if !errors.Is(err, errArchiveTooLarge) && !errors.Is(err, errDecompress) {
+			err = fmt.Errorf("%w: %v", errDecompress, err)
+		}
+	}
+	return n, err
+}
+
+// stream 从头解压本次请求的数据
+func (d *decompressReader) stream() (io.Reader, func() error, error) {
+	return newDecompressor(d.compression, bufio.NewReader(io.NewSectionReader(d.raw, 0, d.rawSize)))
+}
+
+// readOnce 从头解压本次请求的数据并读取 off 处的内容
+func (d *decompressReader) readOnce(p []byte, off int64) (int, error) {
+	r, closer, err := d.stream()
+	if err != nil {
+		return 0, err
+	}
+	defer closer()
+	if _, err := io.CopyN(io.Discard, r, off); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	n, err := io.ReadFull(r, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// knownSize 返回解压后的总大小，尚未解压到末尾时返回 -1
+func (d *decompressReader) knownSize() int64 {
+	d.index.mu.Lock()
+	defer d.index.mu.Unlock()
+	if d.index.total >= 0 {
+		return d.index.total
+	}
+	return d.total
+}
+
+// evicted 返回索引是否已被淘汰
+func (d *decompressReader) evicted() bool {
+	d.index.mu.Lock()
+	defer d.index.mu.Unlock()
+	return d.index.evicted
+}
+
+// size 返回解压后的总大小，大小未知时完整解压一遍
+// 解压过程中保存的检查点随后可用于读取任意位置
+func (d *decompressReader) size() (int64, error) {
+	if total := d.knownSize(); total >= 0 {
+		return total, nil
+	}
+
+	buf := make([]byte, decompressSpan)
+	for off := int64(0); !d.evicted(); off += int64(len(buf)) {
+		_, err := d.ReadAt(buf, off)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if total := d.knownSize(); total >= 0 {
+		return total, nil
+	}
+
+	// 解压途中索引被淘汰：单独完整解压一遍统计大小
+	r, closer, err := d.stream()
+	if err != nil {
+		return 0, err
+	}
+	defer closer()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if !errors.Is(err, errArchiveTooLarge) {
+			err = fmt.Errorf("%w: %v", errDecompress, err)
+		}
+		return 0, err
+	}
+	d.index.mu.Lock()
+	d.total = n
+	d.index.mu.Unlock()
+	return n, nil
+}
+
+// decompressedInfo 透明解压后的文件信息，大小为解压后的大小，尚未解压到末尾时为 -1
+type decompressedInfo struct {
+	os.FileInfo
+	reader *decompressReader
+}
+
+func (i decompressedInfo) Size() int64 { return i.reader.knownSize() }
+
+// resolveSize 透明解压且尚未解压到末尾时完整解压一遍，之后 info.Size() 返回解压后的大小
+// 按行分页和文件内搜索需要完整的内容大小，按字节和十六进制分页只解压到所需位置
+func (src *previewSource) resolveSize() error {
+	d, ok := src.reader.(*decompressReader)
+	if !ok {
+		return nil
+	}
+	_, err := d.size()
+	return err
+}
+
+// contentSize 返回响应中的内容大小，透明解压且尚未解压到末尾时大小未知
+func contentSize(info os.FileInfo) (size int64, unknown bool) {
+	if size := info.Size(); size >= 0 {
+		return size, false
+	}
+	return 0, true
+}
+
+// decompressPreviewSource 将压缩文件包装为解压后内容的预览来源，不是压缩文件时返回 nil
+// 分页偏移、大小和行号均基于解压后的内容；不预先计算解压后的大小，首次解压到末尾后才确定
+func (s *Server) decompressPreviewSource(src *previewSource, head []byte) (*previewSource, error) {
+	compression := detectCompression(src.info.Name(), head)
+	if compression == "" {
+		return nil, nil
+	}
+
+	// 检查点索引跨请求共享，解压时读取的始终是本次请求已打开的文件，不按路径重新打开
+	reader := &decompressReader{
+		index:       s.decompress.get(src.key, src.info),
+		compression: compression,
+		raw:         src.reader,
+		rawSize:     src.info.Size(),
+		total:       -1,
+	}
+	return &previewSource{
+		reader:         reader,
+		info:           decompressedInfo{FileInfo: src.info, reader: reader},
+		path:           src.path,
+		key:            src.key + "|" + compression,
+		close:          src.close,
+		compression:    compression,
+		compressedSize: src.info.Size(),
+	}, nil
+}
+
+// decompressRequested 除非请求指定 decompress=false，否则透明解压压缩文件
+// 返回实际使用的来源、用于推断 MIME 类型的文件名和内容开头；失败时已写入错误响应，返回 false
+func (s *Server) decompressRequested(c *gin.Context, src *previewSource, head []byte) (*previewSource, string, []byte, bool) {
+	if c.Query("decompress") == "false" {
+		return src, src.info.Name(), head, true
+	}
+	decompressed, err := s.decompressPreviewSource(src, head)
+	if err == nil && decompressed != nil {
+		head, err = readSniffHead(decompressed.reader)
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "DECOMPRESS_FAILED", err.Error())
+		return nil, "", nil, false
+	}
+	if decompressed == nil {
+		return src, src.info.Name(), head, true
+	}
+	return decompressed, trimCompressionExt(src.info.Name(), decompressed.compression), head, true
+}
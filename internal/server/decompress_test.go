@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2Sample "line 1\nline 2\n" 的 bzip2 压缩数据（标准库只提供解压）
+var bzip2Sample = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x31, 0x88, 0x21, 0x68, 0x00, 0x00,
+	0x05, 0x59, 0x00, 0x00, 0x10, 0x40, 0x00, 0x30, 0x00, 0x02, 0x25, 0x20, 0x00, 0x31, 0x0c, 0x08,
+	0x12, 0x86, 0x46, 0x89, 0x31, 0x90, 0x87, 0x10, 0xf1, 0x77, 0x24, 0x53, 0x85, 0x09, 0x03, 0x18,
+	0x82, 0x16, 0x80,
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressReader(t *testing.T) {
+	var content bytes.Buffer
+	for i := 0; content.Len() < 3*decompressSpan+1000; i++ {
+		fmt.Fprintf(&content, "%08d the quick brown fox\n", i)
+	}
+	compressed := gzipBytes(t, content.Bytes())
+
+	cache := newDecompressCache(1)
+	defer cache.close()
+	raw := &countingReaderAt{r: bytes.NewReader(compressed)}
+	newReader := func() *decompressReader {
+		return &decompressReader{
+			index:       cache.get("test", fakeFileInfo{size: int64(len(compressed))}),
+			compression: compressionGzip,
+			raw:         raw,
+			rawSize:     int64(len(compressed)),
+			total:       -1,
+		}
+	}
+	reader := newReader()
+	readAt := func(off int64, n int) []byte {
+		buf := make([]byte, n)
+		got, err := reader.ReadAt(buf, off)
+		if off+int64(n) > int64(content.Len()) {
+			assert.ErrorIs(t, err, io.EOF)
+		} else {
+			require.NoError(t, err)
+		}
+		return buf[:got]
+	}
+
+	// 顺序翻页只从头解压一次，只解压到所需位置
+	for off := int64(0); off < 1500*1024; off += 64 * 1024 {
+		assert.Equal(t, content.Bytes()[off:off+64*1024], readAt(off, 64*1024))
+	}
+	assert.Equal(t, 1, raw.starts)
+	assert.Len(t, reader.index.spans, 1)
+	assert.Equal(t, int64(-1), reader.knownSize())
+
+	// 向后跳转从最近的检查点读取，跨越检查点边界的读取拼接两段内容
+	assert.Equal(t, content.Bytes()[10:20], readAt(10, 10))
+	assert.Equal(t, content.Bytes()[decompressSpan-5:decompressSpan+5], readAt(decompressSpan-5, 10))
+	assert.Equal(t, 1, raw.starts)
+
+	// 计算大小时继续解压到末尾，之后任意位置都不再从头解压
+	total, err := reader.size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(content.Len()), total)
+	assert.Len(t, reader.index.spans, 3)
+	assert.Equal(t, content.Bytes()[total-100:], readAt(total-100, 100))
+	assert.Equal(t, content.Bytes()[2*decompressSpan+7:2*decompressSpan+70], readAt(2*decompressSpan+7, 63))
+	assert.Equal(t, content.Bytes()[total-10:], readAt(total-10, 100))
+	n, err := reader.ReadAt(make([]byte, 10), total)
+	assert.Zero(t, n)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// 后续请求共享检查点，读取的是各自已打开的数据来源
+	reader = newReader()
+	assert.Equal(t, content.Bytes()[5:15], readAt(5, 10))
+	assert.Equal(t, 1, raw.starts)
+
+	// 被淘汰后本次请求仍可读取，检查点的临时文件已删除
+	spool := reader.index.spool.Name()
+	cache.close()
+	assert.NoFileExists(t, spool)
+	assert.Equal(t, content.Bytes()[100:110], readAt(100, 10))
+	total, err = reader.size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(content.Len()), total)
+}
+
+// countingReaderAt 统计从头读取的次数，即解压器重新开始的次数
+type countingReaderAt struct {
+	r      io.ReaderAt
+	starts int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == 0 {
+		c.starts++
+	}
+	return c.r.ReadAt(p, off)
+}
+
+func TestHandlePreview_Decompress(t *testing.T) {
+	root := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&content, "2026-01-02 line %d\n", i)
+	}
+	text := content.String()
+
+	var zst bytes.Buffer
+	zw, err := zstd.NewWriter(&zst)
+	require.NoError(t, err)
+	_, err = zw.Write([]byte("zstd content\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	var xzBuf bytes.Buffer
+	xw, err := xz.NewWriter(&xzBuf)
+	require.NoError(t, err)
+	_, err = xw.Write([]byte("xz content\n"))
+	require.NoError(t, err)
+	require.NoError(t, xw.Close())
+
+	files := map[string][]byte{
+		"app.log.3.gz":  gzipBytes(t, []byte(text)),
+		"app.log.4.gz":  gzipBytes(t, []byte(text)),
+		"data.txt.zst":  zst.Bytes(),
+		"notes.txt.bz2": bzip2Sample,
+		"notes.txt.xz":  xzBuf.Bytes(),
+		"broken.gz":     gzipBytes(t, []byte(text))[:20],
+		"corrupt.gz":    {0x1f, 0x8b, 0x00, 0x00},
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0644))
+	}
+
+	server, err := New(Config{Root: root, PreviewMax: 1024 * 1024})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string) (*httptest.ResponseRecorder, previewResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp previewResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	w, resp := get("/api/preview?path=/app.log.3.gz&limit=1000")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "gzip", resp.Compression)
+	assert.Equal(t, int64(len(files["app.log.3.gz"])), resp.CompressedSize)
+	assert.False(t, resp.IsBinary)
+	assert.Contains(t, resp.MimeType, "text/plain")
+	assert.Equal(t, text[:1000], resp.Content)
+	assert.True(t, resp.HasMore)
+	// 第一页只解压到所需位置，解压后的大小尚未确定
+	assert.True(t, resp.SizeUnknown)
+	assert.Zero(t, resp.Size)
+
+	// 分页偏移基于解压后的内容
+	_, resp = get(fmt.Sprintf("/api/preview?path=/app.log.3.gz&offset=%d&limit=1000", resp.NextOffset))
+	assert.Equal(t, text[1000:2000], resp.Content)
+	assert.True(t, resp.SizeUnknown)
+
+	// 大小未知时按是否读到末尾判断是否还有更多内容，读到末尾后大小确定
+	_, resp = get(fmt.Sprintf("/api/preview?path=/app.log.3.gz&offset=%d&limit=1000", len(text)-21))
+	assert.Equal(t, "2026-01-02 line 5000\n", resp.Content)
+	assert.False(t, resp.HasMore)
+	assert.False(t, resp.SizeUnknown)
+	assert.Equal(t, int64(len(text)), resp.Size)
+
+	// 按行分页需要完整的行索引，先解压到末尾确定大小
+	_, resp = get("/api/preview?path=/app.log.4.gz&fromLine=4999&lines=10")
+	assert.Equal(t, "2026-01-02 line 4999\n2026-01-02 line 5000\n", resp.Content)
+	assert.Equal(t, int64(5000), resp.TotalLines)
+	assert.False(t, resp.HasMore)
+	assert.False(t, resp.SizeUnknown)
+	assert.Equal(t, int64(len(text)), resp.Size)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/preview?path=/app.log.3.gz&mode=hex&limit=16", nil))
+	var hex hexResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hex))
+	assert.Equal(t, "gzip", hex.Compression)
+	assert.Equal(t, "2026-01-02 line ", hex.Rows[0].ASCII)
+
+	// decompress=false 查看原始压缩数据
+	_, resp = get("/api/preview?path=/app.log.3.gz&decompress=false")
+	assert.True(t, resp.IsBinary)
+	assert.Empty(t, resp.Compression)
+	assert.Equal(t, int64(len(files["app.log.3.gz"])), resp.Size)
+
+	for name, expected := range map[string]string{
+		"data.txt.zst":  "zstd content\n",
+		"notes.txt.bz2": "line 1\nline 2\n",
+		"notes.txt.xz":  "xz content\n",
+	} {
+		w, resp = get("/api/preview?path=/" + name)
+		require.Equal(t, http.StatusOK, w.Code, name)
+		assert.Equal(t, expected, resp.Content, name)
+		assert.Equal(t, int64(len(expected)), resp.Size, name)
+	}
+
+	// 截断的文件返回已能解压出的内容，损坏的文件返回错误
+	w, resp = get("/api/preview?path=/broken.gz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(text, resp.Content))
+	w, _ = get("/api/preview?path=/corrupt.gz")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "DECOMPRESS_FAILED")
+}
+
+func TestDetectCompression(t *testing.T) {
+	assert.Equal(t, compressionGzip, detectCompression("a.log.gz", []byte{0x1f, 0x8b, 0x08}))
+	assert.Equal(t, "", detectCompression("a.tar.gz", []byte{0x1f, 0x8b, 0x08}))
+	assert.Equal(t, compressionBzip2, detectCompression("a.bz2", []byte("BZh91AY")))
+	assert.Equal(t, "", detectCompression("a.txt", []byte("BZh is not bzip2")))
+	assert.Equal(t, "app.log.3", trimCompressionExt("app.log.3.GZ", compressionGzip))
+	assert.Equal(t, "data", trimCompressionExt("data", compressionXz))
+}
+
+// fakeFileInfo 仅提供大小的 os.FileInfo
+type fakeFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (f fakeFileInfo) Size() int64        { return f.size }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
@@ -0,0 +1,534 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDiffContext = 3       // 默认上下文行数
+	maxDiffContext     = 100     // 最多上下文行数
+	maxDiffCells       = 1 << 22 // 没有唯一行可对齐时，对 m*n 不超过该值的区间做完整 LCS，否则整段视为替换
+	mimeDiff           = "text/x-diff"
+	diffNoNewline      = `\ No newline at end of file`
+)
+
+// 忽略空白的方式
+const (
+	ignoreSpaceTrailing = "trailing" // 忽略行尾空白
+	ignoreSpaceChange   = "change"   // 忽略空白数量的变化（diff -b）
+	ignoreSpaceAll      = "all"      // 忽略所有空白（diff -w）
+)
+
+// diffFile 参与比较的文件信息
+type diffFile struct {
+	Path       string `json:"path"`       // 相对路径
+	Name       string `json:"name"`       // 文件名
+	Size       int64  `json:"size"`       // 文件大小
+	Modified   string `json:"modified"`   // 修改时间
+	Encoding   string `json:"encoding"`   // 检测到的编码，内容统一转为 UTF-8 后比较
+	LineEnding string `json:"lineEnding"` // 换行符：lf、crlf、mixed，无换行时为空
+	Lines      int    `json:"lines"`      // 总行数
+}
+
+// diffLine 差异块中的一行
+type diffLine struct {
+	Type      string `json:"type"`                // context、add、delete
+	Text      string `json:"text"`                // 行内容（不含换行符）
+	OldLine   int    `json:"oldLine,omitempty"`   // 在 a 中的行号（从 1 开始），新增行为 0
+	NewLine   int    `json:"newLine,omitempty"`   // 在 b 中的行号（从 1 开始），删除行为 0
+	NoNewline bool   `json:"noNewline,omitempty"` // 该行是文件最后一行且没有换行符
+}
+
+// diffHunk 一个差异块，行号与 unified diff 的 @@ 头一致
+type diffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []diffLine `json:"lines"`
+}
+
+// diffResponse 文件比较响应
+type diffResponse struct {
+	A         diffFile   `json:"a"`
+	B         diffFile   `json:"b"`
+	Identical bool       `json:"identical"`        // 内容是否相同（忽略空白时按忽略后的内容判断）
+	Binary    bool       `json:"binary,omitempty"` // 任一文件为二进制，此时只按字节比较，不返回差异
+	Additions int        `json:"additions"`        // 新增行数
+	Deletions int        `json:"deletions"`        // 删除行数
+	Hunks     []diffHunk `json:"hunks"`            // 结构化的差异块
+	Unified   string     `json:"unified"`          // unified diff 文本
+}
+
+// diffText 解码并按行切分后的文本
+type diffText struct {
+	lines      []string
+	noEOL      bool // 最后一行没有换行符
+	encoding   string
+	lineEnding string
+}
+
+// diffOp 编辑脚本中的一步，a/b 为行下标，新增行的 a、删除行的 b 为 -1
+type diffOp struct {
+	kind byte // ' '、'-'、'+'
+	a, b int
+}
+
+// handleDiff 比较两个文本文件，返回 unified diff 和结构化的差异块
+// GET /api/diff?a=/nginx.conf.bak&b=/nginx.conf[&context=3&ignoreWhitespace=trailing|change|all]
+// 两个路径均可为压缩包内路径（例如与备份包中的旧版本比较）；单个文件不超过 PreviewMax；
+// 内容按检测到的编码转为 UTF-8 并统一换行符后再比较；
+// 携带 Accept: text/x-diff 或 text/plain 时只返回 unified diff 文本，可直接用于 patch
+func (s *Server) handleDiff(c *gin.Context) {
+	if c.Query("a") == "" || c.Query("b") == "" {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "a and b are required")
+		return
+	}
+	context := defaultDiffContext
+	if v := c.Query("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxDiffContext {
+			abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", fmt.Sprintf("context must be between 0 and %d", maxDiffContext))
+			return
+		}
+		context = n
+	}
+	ignore := c.Query("ignoreWhitespace")
+	switch ignore {
+	case "", ignoreSpaceTrailing, ignoreSpaceChange, ignoreSpaceAll:
+	default:
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "ignoreWhitespace must be trailing, change or all")
+		return
+	}
+
+	maxSize := s.cfg.PreviewMax
+	if maxSize <= 0 {
+		maxSize = defaultPreviewMax
+	}
+	var (
+		files [2]diffFile
+		data  [2][]byte
+	)
+	for i, reqPath := range []string{c.Query("a"), c.Query("b")} {
+		src, ok := s.openPreviewSource(c, reqPath)
+		if !ok {
+			return
+		}
+		info := src.info
+		files[i] = diffFile{
+			Path:     src.path,
+			Name:     info.Name(),
+			Size:     info.Size(),
+			Modified: info.ModTime().UTC().Format(time.RFC3339),
+		}
+		if info.Size() > maxSize {
+			src.close()
+			abortWithError(c, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE",
+				fmt.Sprintf("%s exceeds the diff limit of %d bytes", src.path, maxSize))
+			return
+		}
+		content, err := io.ReadAll(io.NewSectionReader(src.reader, 0, info.Size()))
+		src.close()
+		if err != nil {
+			abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+			return
+		}
+		data[i] = content
+	}
+
+	resp := diffResponse{A: files[0], B: files[1], Hunks: []diffHunk{}}
+	if isDiffBinary(resp.A.Name, data[0]) || isDiffBinary(resp.B.Name, data[1]) {
+		resp.Binary = true
+		resp.Identical = bytes.Equal(data[0], data[1])
+		s.writeDiff(c, resp)
+		return
+	}
+
+	a, err := decodeDiffText(data[0])
+	if err == nil {
+		var b diffText
+		b, err = decodeDiffText(data[1])
+		if err == nil {
+			resp.A.Encoding, resp.A.LineEnding, resp.A.Lines = a.encoding, a.lineEnding, len(a.lines)
+			resp.B.Encoding, resp.B.LineEnding, resp.B.Lines = b.encoding, b.lineEnding, len(b.lines)
+			ops := diffTexts(a, b, ignore)
+			resp.Hunks = buildDiffHunks(ops, a, b, context)
+		}
+	}
+	if err != nil {
+		abortWithError(c, http.StatusUnprocessableEntity, "DECODE_FAILED", err.Error())
+		return
+	}
+
+	for _, hunk := range resp.Hunks {
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case "add":
+				resp.Additions++
+			case "delete":
+				resp.Deletions++
+			}
+		}
+	}
+	resp.Identical = len(resp.Hunks) == 0
+	resp.Unified = formatUnifiedDiff(resp.A.Path, resp.B.Path, resp.Hunks)
+	s.writeDiff(c, resp)
+}
+
+// writeDiff 根据 Accept 头返回 JSON（默认）或 unified diff 文本
+func (s *Server) writeDiff(c *gin.Context, resp diffResponse) {
+	c.Header("Vary", "Accept")
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeDiff, gin.MIMEPlain) {
+	case mimeDiff, gin.MIMEPlain:
+		text := resp.Unified
+		if resp.Binary && !resp.Identical {
+			text = fmt.Sprintf("Binary files a%s and b%s differ\n", resp.A.Path, resp.B.Path)
+		}
+		c.Data(http.StatusOK, mimeDiff+"; charset=utf-8", []byte(text))
+	default:
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// isDiffBinary 判断文件内容是否为二进制
+func isDiffBinary(name string, data []byte) bool {
+	isBinary, _ := sniffContent(name, data[:min(len(data), sniffLen)])
+	return isBinary
+}
+
+// decodeDiffText 按检测到的编码将内容转为 UTF-8，去掉 BOM，统一换行符后按行切分
+func decodeDiffText(data []byte) (diffText, error) {
+	enc, bom := detectTextEncoding(data)
+	text := diffText{encoding: enc}
+	data = data[bom:]
+	if decoder := textDecoder(enc); decoder != nil {
+		decoded, err := decoder.Bytes(data)
+		if err != nil {
+			return text, fmt.Errorf("decode %s: %w", enc, err)
+		}
+		data = decoded
+	}
+
+	crlf := bytes.Count(data, []byte("\r\n"))
+	if lf := bytes.Count(data, []byte("\n")); lf > 0 {
+		switch crlf {
+		case 0:
+			text.lineEnding = "lf"
+		case lf:
+			text.lineEnding = "crlf"
+		default:
+			text.lineEnding = "mixed"
+		}
+	}
+	if len(data) == 0 {
+		return text, nil
+	}
+
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if strings.HasSuffix(content, "\n") {
+		content = content[:len(content)-1]
+	} else {
+		text.noEOL = true
+	}
+	text.lines = strings.Split(content, "\n")
+	return text, nil
+}
+
+// diffTexts 比较两段文本，返回完整的编辑脚本
+func diffTexts(a, b diffText, ignore string) []diffOp {
+	// 将每行映射为整数，相同（按忽略空白规则）的行映射为同一个值
+	ids := make(map[string]int)
+	toIDs := func(t diffText) []int {
+		out := make([]int, len(t.lines))
+		for i, line := range t.lines {
+			key := normalizeDiffLine(line, ignore)
+			// 末行有无换行符不同时视为不同的行，与 diff 行为一致
+			if t.noEOL && i == len(t.lines)-1 {
+				key += "\x00"
+			}
+			id, ok := ids[key]
+			if !ok {
+				id = len(ids)
+				ids[key] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	x, y := toIDs(a), toIDs(b)
+
+	var pairs [][2]int
+	matchDiffLines(x, y, 0, len(x), 0, len(y), &pairs)
+
+	ops := make([]diffOp, 0, len(x)+len(y))
+	i, j := 0, 0
+	for _, p := range append(pairs, [2]int{len(x), len(y)}) {
+		for ; i < p[0]; i++ {
+			ops = append(ops, diffOp{kind: '-', a: i, b: -1})
+		}
+		for ; j < p[1]; j++ {
+			ops = append(ops, diffOp{kind: '+', a: -1, b: j})
+		}
+		if i < len(x) && j < len(y) {
+			ops = append(ops, diffOp{kind: ' ', a: i, b: j})
+			i, j = i+1, j+1
+		}
+	}
+	return ops
+}
+
+// normalizeDiffLine 按忽略空白规则返回用于比较的行内容
+func normalizeDiffLine(line, ignore string) string {
+	switch ignore {
+	case ignoreSpaceTrailing:
+		return strings.TrimRightFunc(line, unicode.IsSpace)
+	case ignoreSpaceChange:
+		var b strings.Builder
+		space := false
+		for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	case ignoreSpaceAll:
+		return strings.Join(strings.FieldsFunc(line, unicode.IsSpace), "")
+	}
+	return line
+}
+
+// matchDiffLines 找出 x[xLo:xHi] 与 y[yLo:yHi] 中相互匹配的行，按顺序追加到 pairs
+// 先去掉相同的首尾，再以两边都只出现一次的行的最长递增子序列为锚点分段递归（patience diff）；
+// 没有锚点时对较小的区间做完整 LCS，过大的区间整段视为替换，保证耗时和内存可控
+func matchDiffLines(x, y []int, xLo, xHi, yLo, yHi int, pairs *[][2]int) {
+	for xLo < xHi && yLo < yHi && x[xLo] == y[yLo] {
+		*pairs = append(*pairs, [2]int{xLo, yLo})
+		xLo, yLo = xLo+1, yLo+1
+	}
+	suffix := 0
+	for xLo < xHi-suffix && yLo < yHi-suffix && x[xHi-suffix-1] == y[yHi-suffix-1] {
+		suffix++
+	}
+	defer func(xEnd, yEnd int) {
+		for k := suffix; k > 0; k-- {
+			*pairs = append(*pairs, [2]int{xEnd - k, yEnd - k})
+		}
+	}(xHi, yHi)
+	xHi, yHi = xHi-suffix, yHi-suffix
+	if xLo == xHi || yLo == yHi {
+		return
+	}
+
+	if anchors := uniqueDiffAnchors(x, y, xLo, xHi, yLo, yHi); len(anchors) > 0 {
+		for _, anchor := range anchors {
+			matchDiffLines(x, y, xLo, anchor[0], yLo, anchor[1], pairs)
+			*pairs = append(*pairs, anchor)
+			xLo, yLo = anchor[0]+1, anchor[1]+1
+		}
+		matchDiffLines(x, y, xLo, xHi, yLo, yHi, pairs)
+		return
+	}
+
+	n, m := xHi-xLo, yHi-yLo
+	if n*m > maxDiffCells {
+		return
+	}
+	// lcs[i*(m+1)+j] 为 x[xLo+i:] 与 y[yLo+j:] 的最长公共子序列长度
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if x[xLo+i] == y[yLo+j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case x[xLo+i] == y[yLo+j]:
+			*pairs = append(*pairs, [2]int{xLo + i, yLo + j})
+			i, j = i+1, j+1
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+}
+
+// uniqueDiffAnchors 返回在两个区间中都只出现一次的行，取其在 y 中位置的最长递增子序列
+func uniqueDiffAnchors(x, y []int, xLo, xHi, yLo, yHi int) [][2]int {
+	type occurrence struct{ countX, countY, posX, posY int }
+	seen := make(map[int]*occurrence)
+	for i := xLo; i < xHi; i++ {
+		o := seen[x[i]]
+		if o == nil {
+			o = &occurrence{}
+			seen[x[i]] = o
+		}
+		o.countX++
+		o.posX = i
+	}
+	for j := yLo; j < yHi; j++ {
+		if o := seen[y[j]]; o != nil {
+			o.countY++
+			o.posY = j
+		}
+	}
+	var candidates [][2]int
+	for _, o := range seen {
+		if o.countX == 1 && o.countY == 1 {
+			candidates = append(candidates, [2]int{o.posX, o.posY})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i][0] < candidates[j][0] })
+
+	// 耐心排序求最长递增子序列：tails[k] 为长度 k+1 的子序列末尾元素的下标
+	tails := make([]int, 0, len(candidates))
+	prev := make([]int, len(candidates))
+	for i, cand := range candidates {
+		k := sort.Search(len(tails), func(k int) bool { return candidates[tails[k]][1] >= cand[1] })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	anchors := make([][2]int, len(tails))
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = prev[i], k-1 {
+		anchors[k] = candidates[i]
+	}
+	return anchors
+}
+
+// buildDiffHunks 将编辑脚本按上下文行数分组为差异块，间隔不超过 2*context 行的修改合并为一块
+func buildDiffHunks(ops []diffOp, a, b diffText, context int) []diffHunk {
+	hunks := []diffHunk{}
+	pos, oldBefore, newBefore := 0, 0, 0 // ops[:pos] 中 a、b 各经过的行数
+	for start := 0; start < len(ops); {
+		// 找到下一处修改
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// 向后扩展，直到连续的相同行超过 2*context
+		end := start
+		for same := 0; end < len(ops) && (ops[end].kind != ' ' || same < 2*context); end++ {
+			if ops[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+		}
+		// 去掉末尾多余的相同行，只保留 context 行
+		trailing := 0
+		for k := end - 1; k >= start && ops[k].kind == ' '; k-- {
+			trailing++
+		}
+		end -= max(trailing-context, 0)
+		from := max(start-context, 0)
+		for ; pos < from; pos++ {
+			if ops[pos].a >= 0 {
+				oldBefore++
+			}
+			if ops[pos].b >= 0 {
+				newBefore++
+			}
+		}
+
+		hunk := diffHunk{Lines: make([]diffLine, 0, end-from)}
+		for _, op := range ops[from:end] {
+			line := diffLine{}
+			switch op.kind {
+			case ' ':
+				line.Type, line.Text, line.OldLine, line.NewLine = "context", a.lines[op.a], op.a+1, op.b+1
+				line.NoNewline = a.noEOL && op.a == len(a.lines)-1
+				hunk.OldLines++
+				hunk.NewLines++
+			case '-':
+				line.Type, line.Text, line.OldLine = "delete", a.lines[op.a], op.a+1
+				line.NoNewline = a.noEOL && op.a == len(a.lines)-1
+				hunk.OldLines++
+			case '+':
+				line.Type, line.Text, line.NewLine = "add", b.lines[op.b], op.b+1
+				line.NoNewline = b.noEOL && op.b == len(b.lines)-1
+				hunk.NewLines++
+			}
+			hunk.Lines = append(hunk.Lines, line)
+		}
+		// 与 diff -u 一致：块为空时起始行号为其前一行
+		hunk.OldStart, hunk.NewStart = oldBefore, newBefore
+		if hunk.OldLines > 0 {
+			hunk.OldStart++
+		}
+		if hunk.NewLines > 0 {
+			hunk.NewStart++
+		}
+		hunks = append(hunks, hunk)
+		start = end
+	}
+	return hunks
+}
+
+// formatUnifiedDiff 生成 unified diff 文本，内容相同时返回空字符串
+func formatUnifiedDiff(oldPath, newPath string, hunks []diffHunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a%s\n+++ b%s\n", oldPath, newPath)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", unifiedRange(hunk.OldStart, hunk.OldLines), unifiedRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case "add":
+				b.WriteByte('+')
+			case "delete":
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+			if line.NoNewline {
+				b.WriteString(diffNoNewline + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// unifiedRange 格式化 @@ 头中的行范围，行数为 1 时省略
+func unifiedRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestFormatUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		context  int
+		expected string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{"replace", "a\nb\nc\n", "a\nB\nc\n", 3, "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"insert at start", "b\n", "a\nb\n", 0, "@@ -0,0 +1 @@\n+a\n"},
+		{"delete at end", "a\nb\n", "a\n", 1, "@@ -1,2 +1 @@\n a\n-b\n"},
+		{"from empty", "", "x\n", 3, "@@ -0,0 +1 @@\n+x\n"},
+		{"no newline", "a\nb", "a\nb\n", 3, "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+		{"both without newline", "a\nb", "A\nb", 3, "@@ -1,2 +1,2 @@\n-a\n+A\n b\n\\ No newline at end of file\n"},
+		{
+			"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", "one\n2\n3\n4\n5\n6\n7\n8\nnine\n", 1,
+			"@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -8,2 +8,2 @@\n 8\n-9\n+nine\n",
+		},
+		{
+			"merged hunks", "1\n2\n3\n4\n5\n", "one\n2\n3\n4\nfive\n", 2,
+			"@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+		{
+			"moved block", "x\nfunc a\nbody\nfunc b\nbody\ny\n", "x\nfunc b\nbody\nfunc a\nbody\ny\n", 0,
+			"@@ -2,2 +1,0 @@\n-func a\n-body\n@@ -4,0 +3,2 @@\n+body\n+func a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := decodeDiffText([]byte(tt.a))
+			require.NoError(t, err)
+			b, err := decodeDiffText([]byte(tt.b))
+			require.NoError(t, err)
+			hunks := buildDiffHunks(diffTexts(a, b, ""), a, b, tt.context)
+			got := formatUnifiedDiff("/old", "/new", hunks)
+			expected := tt.expected
+			if expected != "" {
+				expected = "--- a/old\n+++ b/new\n" + expected
+			}
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestNormalizeDiffLine(t *testing.T) {
+	assert.Equal(t, "  a  b", normalizeDiffLine("  a  b \t", ignoreSpaceTrailing))
+	assert.Equal(t, " a b", normalizeDiffLine("  a \t b  ", ignoreSpaceChange))
+	assert.Equal(t, "ab", normalizeDiffLine("  a \t b  ", ignoreSpaceAll))
+	assert.Equal(t, "a ", normalizeDiffLine("a ", ""))
+}
+
+func TestHandleDiff(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string, data []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0644))
+	}
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("名称 = 旧\r\nport = 80\r\n"))
+	require.NoError(t, err)
+	write("old.conf", gbk)
+	write("new.conf", []byte("\xef\xbb\xbf名称 = 新\nport =  80\n"))
+	write("bin.dat", []byte{0x00, 0x01, 0x02})
+	write("big.txt", []byte(strings.Repeat("x\n", 600)))
+	var lines []string
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	write("long-a.txt", []byte(strings.Join(lines, "\n")+"\n"))
+	lines[499] = "changed"
+	write("long-b.txt", []byte(strings.Join(lines, "\n")+"\n"))
+
+	server, err := New(Config{Root: root, PreviewMax: 1024})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) diffResponse {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp diffResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// 编码和换行符统一后比较
+	resp := decode(get("/api/diff?a=/old.conf&b=/new.conf"))
+	assert.Equal(t, tableEncodingGB, resp.A.Encoding)
+	assert.Equal(t, "crlf", resp.A.LineEnding)
+	assert.Equal(t, tableEncodingUTF8, resp.B.Encoding)
+	assert.Equal(t, "lf", resp.B.LineEnding)
+	assert.False(t, resp.Identical)
+	assert.Equal(t, 2, resp.Additions)
+	assert.Equal(t, 2, resp.Deletions)
+	assert.Equal(t, "--- a/old.conf\n+++ b/new.conf\n@@ -1,2 +1,2 @@\n-名称 = 旧\n-port = 80\n+名称 = 新\n+port =  80\n", resp.Unified)
+	require.Len(t, resp.Hunks, 1)
+	assert.Equal(t, diffLine{Type: "add", Text: "名称 = 新", NewLine: 1}, resp.Hunks[0].Lines[2])
+
+	// 忽略空白数量的变化
+	resp = decode(get("/api/diff?a=/old.conf&b=/new.conf&ignoreWhitespace=change&context=0"))
+	assert.Equal(t, 1, resp.Additions)
+	assert.Equal(t, "--- a/old.conf\n+++ b/new.conf\n@@ -1 +1 @@\n-名称 = 旧\n+名称 = 新\n", resp.Unified)
+
+	resp = decode(get("/api/diff?a=/old.conf&b=/old.conf"))
+	assert.True(t, resp.Identical)
+	assert.Empty(t, resp.Hunks)
+	assert.Empty(t, resp.Unified)
+
+	// 纯文本格式
+	w := get("/api/diff?a=/old.conf&b=/new.conf&ignoreWhitespace=all", "Accept", "text/x-diff")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/x-diff")
+	assert.True(t, strings.HasPrefix(w.Body.String(), "--- a/old.conf\n"))
+	w = get("/api/diff?a=/bin.dat&b=/new.conf", "Accept", "text/plain")
+	assert.Equal(t, "Binary files a/bin.dat and b/new.conf differ\n", w.Body.String())
+
+	resp = decode(get("/api/diff?a=/bin.dat&b=/bin.dat"))
+	assert.True(t, resp.Binary)
+	assert.True(t, resp.Identical)
+
+	// 超出 PreviewMax
+	w = get("/api/diff?a=/big.txt&b=/new.conf")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "FILE_TOO_LARGE")
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/diff?a=/old.conf").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/diff?a=/old.conf&b=/new.conf&ignoreWhitespace=tabs").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/diff?a=/old.conf&b=/new.conf&context=-1").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/diff?a=/old.conf&b=/missing.conf").Code)
+	assert.NotEqual(t, http.StatusOK, get("/api/diff?a=/old.conf&b=/../../etc/passwd").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/diff?a=/&b=/old.conf").Code)
+
+	// 大文件中的单行修改只产生一个差异块
+	server.cfg.PreviewMax = 0
+	resp = decode(get("/api/diff?a=/long-a.txt&b=/long-b.txt"))
+	require.Len(t, resp.Hunks, 1)
+	assert.Equal(t, 497, resp.Hunks[0].OldStart)
+	assert.Equal(t, 7, resp.Hunks[0].OldLines)
+	assert.Equal(t, 1, resp.Additions)
+}
@@ -0,0 +1,617 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxDupeJobs        = 16      // 最多保留的扫描任务数（含已结束的），超出时淘汰最早结束的任务
+	maxRunningDupeJobs = 2       // 最多同时运行的扫描任务数
+	maxDupeWorkers     = 4       // 每个任务计算哈希的最大并发数
+	dupePartialSize    = 4096    // 部分哈希读取文件开头和结尾各 4KB
+	dupeReadChunk      = 1 << 20 // 计算完整哈希时每次读取的字节数，读取之间检查任务是否已取消
+	defaultDupeLimit   = 100     // 默认每页返回的重复组数
+	maxDupeLimit       = 1000    // 每页最多返回的重复组数
+)
+
+// 扫描任务的状态
+const (
+	dupeRunning  = "running"
+	dupeDone     = "done"
+	dupeCanceled = "canceled"
+	dupeFailed   = "failed"
+)
+
+// 扫描任务的阶段
+const (
+	dupePhaseScan    = "scan"    // 遍历目录，按大小分组
+	dupePhasePartial = "partial" // 按文件首尾的部分哈希分组
+	dupePhaseFull    = "full"    // 按完整内容的哈希分组
+)
+
+// errTooManyJobs 同时运行的扫描任务数已达上限
+var errTooManyJobs = errors.New("too many running jobs")
+
+// dupeFile 扫描到的一个文件
+type dupeFile struct {
+	abs  string
+	rel  string // 相对根目录，/ 分隔
+	info fs.FileInfo
+	key  string // 当前阶段的哈希，读取失败时为空
+	full bool   // 部分哈希已覆盖整个文件，key 即为完整内容的哈希
+}
+
+// dupeGroup 一组内容相同的文件
+type dupeGroup struct {
+	Size   int64       `json:"size"`   // 单个文件的大小
+	Hash   string      `json:"hash"`   // 文件内容的 SHA-256
+	Files  []fileEntry `json:"files"`  // 按路径排序
+	Wasted int64       `json:"wasted"` // 只保留一份时可以节省的空间
+}
+
+// dupeSummary 扫描结果汇总
+type dupeSummary struct {
+	Groups int   `json:"groups"` // 重复组数
+	Files  int   `json:"files"`  // 重复组中的文件总数
+	Wasted int64 `json:"wasted"` // 所有重复组可以节省的总空间
+}
+
+// dupeProgress 扫描进度
+type dupeProgress struct {
+	Files       int64 `json:"files"`       // 已遍历的文件数
+	Candidates  int64 `json:"candidates"`  // 与其他文件大小相同、需要比较内容的文件数
+	BytesHashed int64 `json:"bytesHashed"` // 已读取并计算哈希的字节数
+	BytesTotal  int64 `json:"bytesTotal"`  // 需要读取的总字节数（含之前阶段已读取的），进入完整哈希阶段后才确定
+}
+
+// dupeJobStatus 扫描任务状态响应
+type dupeJobStatus struct {
+	ID       string       `json:"id"`
+	Path     string       `json:"path"`               // 扫描的目录
+	State    string       `json:"state"`              // running、done、canceled 或 failed
+	Phase    string       `json:"phase"`              // scan、partial 或 full
+	Progress dupeProgress `json:"progress"`           // 扫描进度
+	Started  string       `json:"started"`            // 开始时间（RFC3339 格式）
+	Finished string       `json:"finished,omitempty"` // 结束时间，运行中为空
+	Error    string       `json:"error,omitempty"`    // 失败原因
+
+	Summary *dupeSummary `json:"summary,omitempty"` // 结果汇总（仅 done 时返回）
+	Groups  []dupeGroup  `json:"groups,omitempty"`  // 当前页的重复组，按可节省空间从大到小排序
+	Offset  int64        `json:"offset,omitempty"`  // 当前页起始组的序号
+	HasMore bool         `json:"hasMore,omitempty"` // 是否还有下一页
+}
+
+// dupeJob 一个在后台运行的重复文件扫描任务
+type dupeJob struct {
+	id      string
+	relPath string
+	minSize int64
+	ignore  *ignoreMatcher
+	started time.Time
+	cancel  context.CancelFunc
+	done    chan struct{} // 任务结束后关闭
+
+	files       atomic.Int64
+	candidates  atomic.Int64
+	bytesHashed atomic.Int64
+	bytesTotal  atomic.Int64
+
+	mu       sync.Mutex
+	state    string
+	phase    string
+	finished time.Time
+	err      string
+	summary  dupeSummary
+	groups   []dupeGroup
+}
+
+// dupeJobs 管理扫描任务
+type dupeJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*dupeJob
+}
+
+// newDupeJobs 创建扫描任务管理器
+func newDupeJobs() *dupeJobs {
+	return &dupeJobs{jobs: make(map[string]*dupeJob)}
+}
+
+// start 在后台启动扫描任务，运行中的任务数已达上限时返回 errTooManyJobs
+// 保留的任务超出 maxDupeJobs 时淘汰最早结束的任务
+func (m *dupeJobs) start(absPath string, job *dupeJob) error {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		if j.state == dupeRunning {
+			running++
+		}
+		j.mu.Unlock()
+	}
+	if running >= maxRunningDupeJobs {
+		return fmt.Errorf("%w: at most %d duplicate scans can run at the same time", errTooManyJobs, maxRunningDupeJobs)
+	}
+	for len(m.jobs) >= maxDupeJobs {
+		var oldest *dupeJob
+		for _, j := range m.jobs {
+			j.mu.Lock()
+			if j.state != dupeRunning && (oldest == nil || j.finished.Before(oldest.finished)) {
+				oldest = j
+			}
+			j.mu.Unlock()
+		}
+		if oldest == nil {
+			break
+		}
+		delete(m.jobs, oldest.id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job.id = hex.EncodeToString(id[:])
+	job.started = time.Now()
+	job.cancel = cancel
+	job.done = make(chan struct{})
+	job.state = dupeRunning
+	job.phase = dupePhaseScan
+	m.jobs[job.id] = job
+
+	go func() {
+		defer close(job.done)
+		defer cancel()
+		job.finish(job.run(ctx, absPath))
+	}()
+	return nil
+}
+
+// get 按 id 返回任务，不存在时返回 nil
+func (m *dupeJobs) get(id string) *dupeJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id]
+}
+
+// list 返回所有任务的状态（不含结果），按开始时间排序
+func (m *dupeJobs) list() []dupeJobStatus {
+	m.mu.Lock()
+	jobs := make([]*dupeJob, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].started.Before(jobs[k].started) })
+	statuses := make([]dupeJobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		status := j.status(0, 0)
+		status.Groups, status.HasMore = nil, false
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// remove 取消任务并等待其结束，然后删除任务，返回任务的最终状态
+func (m *dupeJobs) remove(job *dupeJob) dupeJobStatus {
+	job.cancel()
+	<-job.done
+	m.mu.Lock()
+	delete(m.jobs, job.id)
+	m.mu.Unlock()
+	return job.status(0, 0)
+}
+
+// close 取消所有运行中的任务并等待其结束
+func (m *dupeJobs) close() {
+	m.mu.Lock()
+	jobs := make([]*dupeJob, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mu.Unlock()
+	for _, j := range jobs {
+		j.cancel()
+		<-j.done
+	}
+}
+
+// status 返回任务状态；任务已完成且 limit > 0 时附带从第 offset 组起的 limit 个重复组
+func (j *dupeJob) status(offset, limit int64) dupeJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := dupeJobStatus{
+		ID:    j.id,
+		Path:  "/" + j.relPath,
+		State: j.state,
+		Phase: j.phase,
+		Progress: dupeProgress{
+			Files:       j.files.Load(),
+			Candidates:  j.candidates.Load(),
+			BytesHashed: j.bytesHashed.Load(),
+			BytesTotal:  j.bytesTotal.Load(),
+		},
+		Started: j.started.UTC().Format(time.RFC3339),
+		Error:   j.err,
+	}
+	if !j.finished.IsZero() {
+		status.Finished = j.finished.UTC().Format(time.RFC3339)
+	}
+	if j.state != dupeDone {
+		return status
+	}
+	summary := j.summary
+	status.Summary = &summary
+	status.Groups = []dupeGroup{}
+	if limit > 0 && offset < int64(len(j.groups)) {
+		end := min(offset+limit, int64(len(j.groups)))
+		status.Groups = j.groups[offset:end]
+		status.HasMore = end < int64(len(j.groups))
+	}
+	status.Offset = offset
+	return status
+}
+
+// setPhase 进入扫描的下一阶段
+func (j *dupeJob) setPhase(phase string) {
+	j.mu.Lock()
+	j.phase = phase
+	j.mu.Unlock()
+}
+
+// finish 记录任务结果：被取消、失败或完成
+func (j *dupeJob) finish(groups []dupeGroup, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = time.Now()
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.state = dupeCanceled
+	case err != nil:
+		j.state = dupeFailed
+		j.err = err.Error()
+	default:
+		j.state = dupeDone
+		j.groups = groups
+		j.summary = dupeSummary{Groups: len(groups)}
+		for _, g := range groups {
+			j.summary.Files += len(g.Files)
+			j.summary.Wasted += g.Wasted
+		}
+	}
+}
+
+// run 依次按大小、部分哈希、完整哈希分组，返回按可节省空间从大到小排序的重复组
+// 每个阶段只处理上一阶段中至少有两个文件的组
+func (j *dupeJob) run(ctx context.Context, absPath string) ([]dupeGroup, error) {
+	groups, err := j.scan(ctx, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	j.setPhase(dupePhasePartial)
+	if groups, err = j.hashGroups(ctx, groups, j.partialHash); err != nil {
+		return nil, err
+	}
+
+	j.setPhase(dupePhaseFull)
+	total := j.bytesHashed.Load()
+	for _, g := range groups {
+		if !g[0].full {
+			total += g[0].info.Size() * int64(len(g))
+		}
+	}
+	j.bytesTotal.Store(total)
+	if groups, err = j.hashGroups(ctx, groups, j.fullHash); err != nil {
+		return nil, err
+	}
+
+	var result []dupeGroup
+	for _, g := range groups {
+		g = dropHardLinks(g)
+		if len(g) < 2 {
+			continue
+		}
+		sort.Slice(g, func(a, b int) bool { return g[a].rel < g[b].rel })
+		size := g[0].info.Size()
+		group := dupeGroup{Size: size, Hash: g[0].key, Wasted: size * int64(len(g)-1)}
+		for _, f := range g {
+			group.Files = append(group.Files, newFileEntry("/"+f.rel, f.info))
+		}
+		result = append(result, group)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Wasted != result[b].Wasted {
+			return result[a].Wasted > result[b].Wasted
+		}
+		return result[a].Files[0].Path < result[b].Files[0].Path
+	})
+	return result, nil
+}
+
+// scan 遍历目录，把不小于 minSize 的普通文件按大小分组，只返回至少有两个文件的组
+// 跳过符号链接和被忽略的路径（gitignore=true）
+func (j *dupeJob) scan(ctx context.Context, absPath string) ([][]*dupeFile, error) {
+	bySize := make(map[int64][]*dupeFile)
+	err := filepath.WalkDir(absPath, func(walkPath string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if walkPath == absPath {
+				return err // 扫描目录本身无法读取
+			}
+			return nil // 忽略错误继续
+		}
+		if walkPath == absPath || d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+
+		rel := path.Join(j.relPath, filepath.ToSlash(strings.TrimPrefix(walkPath, absPath+string(os.PathSeparator))))
+		if j.ignore.isIgnored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		j.files.Add(1)
+		info, err := d.Info()
+		if err != nil || info.Size() < j.minSize {
+			return nil
+		}
+		switch files := bySize[info.Size()]; len(files) {
+		case 0:
+		case 1:
+			j.candidates.Add(2)
+		default:
+			j.candidates.Add(1)
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], &dupeFile{abs: walkPath, rel: rel, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]*dupeFile
+	for _, files := range bySize {
+		if len(files) > 1 {
+			groups = append(groups, files)
+		}
+	}
+	return groups, nil
+}
+
+// hashGroups 由 worker 池为每个文件计算哈希（保存到 key），再按哈希把每组拆分为更小的组
+// 无法读取的文件被丢弃，只返回至少有两个文件的组；ctx 取消后返回 ctx 的错误
+func (j *dupeJob) hashGroups(ctx context.Context, groups [][]*dupeFile, hash func(ctx context.Context, f *dupeFile) (string, error)) ([][]*dupeFile, error) {
+	files := make(chan *dupeFile)
+	var wg sync.WaitGroup
+	for range min(max(runtime.NumCPU(), 2), maxDupeWorkers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range files {
+				key, err := hash(ctx, f)
+				if err != nil {
+					key = ""
+				}
+				f.key = key
+			}
+		}()
+	}
+dispatch:
+	for _, g := range groups {
+		for _, f := range g {
+			select {
+			case files <- f:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+	}
+	close(files)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result [][]*dupeFile
+	for _, g := range groups {
+		byKey := make(map[string][]*dupeFile)
+		var keys []string
+		for _, f := range g {
+			if f.key == "" {
+				continue
+			}
+			if _, ok := byKey[f.key]; !ok {
+				keys = append(keys, f.key)
+			}
+			byKey[f.key] = append(byKey[f.key], f)
+		}
+		for _, key := range keys {
+			if len(byKey[key]) > 1 {
+				result = append(result, byKey[key])
+			}
+		}
+	}
+	return result, nil
+}
+
+// partialHash 计算文件开头和结尾各 dupePartialSize 字节的哈希
+// 文件不超过 2*dupePartialSize 字节时直接计算整个文件的哈希，完整哈希阶段不再重复读取
+func (j *dupeJob) partialHash(ctx context.Context, f *dupeFile) (string, error) {
+	size := f.info.Size()
+	if size <= 2*dupePartialSize {
+		f.full = true
+		return j.fullHash(ctx, f)
+	}
+	file, err := os.Open(f.abs)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 2*dupePartialSize)
+	if _, err := io.ReadFull(file, buf[:dupePartialSize]); err != nil {
+		return "", err
+	}
+	if _, err := file.ReadAt(buf[dupePartialSize:], size-dupePartialSize); err != nil {
+		return "", err
+	}
+	j.bytesHashed.Add(int64(len(buf)))
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fullHash 计算整个文件的 SHA-256，每读取 dupeReadChunk 字节检查一次 ctx 并更新进度
+// 文件在扫描后大小发生变化时返回错误
+func (j *dupeJob) fullHash(ctx context.Context, f *dupeFile) (string, error) {
+	if f.full && f.key != "" {
+		return f.key, nil
+	}
+	file, err := os.Open(f.abs)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	buf := make([]byte, dupeReadChunk)
+	var read int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := file.Read(buf)
+		h.Write(buf[:n])
+		read += int64(n)
+		j.bytesHashed.Add(int64(n))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if read != f.info.Size() {
+		return "", fmt.Errorf("%s: file changed during scan", f.rel)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dropHardLinks 去掉与组内前面的文件是同一文件（硬链接）的路径，硬链接不占用额外空间
+func dropHardLinks(files []*dupeFile) []*dupeFile {
+	kept := files[:0]
+next:
+	for _, f := range files {
+		for _, k := range kept {
+			if os.SameFile(f.info, k.info) {
+				continue next
+			}
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// handleDupesStart 在后台启动重复文件扫描
+// POST /api/dupes?path=/share[&minSize=1MB&gitignore=true]
+// 先按大小分组，再按文件首尾的部分哈希和完整内容的 SHA-256 逐步缩小范围；
+// 默认跳过空文件，跳过符号链接，硬链接到同一文件的路径不算重复。返回 202 和任务状态，
+// 通过 GET /api/dupes/:id 查询进度和结果，DELETE /api/dupes/:id 取消并删除任务
+func (s *Server) handleDupesStart(c *gin.Context) {
+	minSize := int64(1)
+	if v := c.Query("minSize"); v != "" {
+		size, err := parseBytes(v)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "invalid minSize: "+err.Error())
+			return
+		}
+		minSize = max(size, 1)
+	}
+
+	absPath, relPath, err := s.resolvePath(c.Query("path"))
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(absPath); err == nil && !info.IsDir() {
+			err = errNotADir
+		}
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+
+	job := &dupeJob{relPath: relPath, minSize: minSize, ignore: s.gitIgnore(c)}
+	if err := s.dupes.start(absPath, job); err != nil {
+		if errors.Is(err, errTooManyJobs) {
+			abortWithError(c, http.StatusTooManyRequests, "TOO_MANY_JOBS", err.Error())
+			return
+		}
+		abortWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, job.status(0, 0))
+}
+
+// handleDupesList 列出所有扫描任务的状态（不含结果）
+// GET /api/dupes
+func (s *Server) handleDupesList(c *gin.Context) {
+	c.JSON(http.StatusOK, s.dupes.list())
+}
+
+// handleDupesStatus 返回扫描任务的状态和进度，任务完成后附带重复组
+// GET /api/dupes/:id?offset=0&limit=100
+func (s *Server) handleDupesStatus(c *gin.Context) {
+	offset, limit, err := parseOffsetLimit(c, maxDupeLimit)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultDupeLimit
+	}
+	job := s.dupes.get(c.Param("id"))
+	if job == nil {
+		abortWithError(c, http.StatusNotFound, "JOB_NOT_FOUND", fmt.Sprintf("job %q not found", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, job.status(offset, limit))
+}
+
+// handleDupesCancel 取消扫描任务（运行中时等待其停止）并删除任务，返回任务的最终状态
+// DELETE /api/dupes/:id
+func (s *Server) handleDupesCancel(c *gin.Context) {
+	job := s.dupes.get(c.Param("id"))
+	if job == nil {
+		abortWithError(c, http.StatusNotFound, "JOB_NOT_FOUND", fmt.Sprintf("job %q not found", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, s.dupes.remove(job))
+}
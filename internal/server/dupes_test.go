@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDupes(t *testing.T) {
+	root := t.TempDir()
+	big := bytes.Repeat([]byte("0123456789abcdef"), 1024) // 16KB，超过部分哈希覆盖的范围
+	tail := bytes.Clone(big)
+	tail[len(tail)/2] = 'x' // 首尾相同、中间不同，只能由完整哈希区分
+	head := bytes.Clone(big)
+	head[0] = 'x'
+	files := map[string][]byte{
+		"a/big.bin":        big,
+		"b/big copy.bin":   big,
+		"c/big.bin":        big,
+		"c/middle.bin":     tail,
+		"c/head.bin":       head,
+		"docs/readme.txt":  []byte("hello"),
+		"docs/readme2.txt": []byte("hello"),
+		"docs/other.txt":   []byte("world"),
+		"empty1":           nil,
+		"empty2":           nil,
+		"logs/debug.log":   []byte("hello"),
+		".gitignore":       []byte("logs/\n"),
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), content, 0644))
+	}
+	// 硬链接不占用额外空间，符号链接被跳过
+	require.NoError(t, os.Link(filepath.Join(root, "a/big.bin"), filepath.Join(root, "a/link.bin")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "a/big.bin"), filepath.Join(root, "a/symlink.bin")))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	defer server.Close()
+	router := server.Handler()
+	do := func(method, target string) (*httptest.ResponseRecorder, dupeJobStatus) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var status dupeJobStatus
+		if w.Code < 300 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		}
+		return w, status
+	}
+	scan := func(target string) dupeJobStatus {
+		w, status := do(http.MethodPost, target)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		require.NotEmpty(t, status.ID)
+		<-server.dupes.get(status.ID).done
+		w, status = do(http.MethodGet, "/api/dupes/"+status.ID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.Equal(t, dupeDone, status.State)
+		return status
+	}
+	paths := func(g dupeGroup) []string {
+		var out []string
+		for _, f := range g.Files {
+			out = append(out, f.Path)
+		}
+		return out
+	}
+
+	// 按可节省空间排序；空文件、符号链接和硬链接不算重复
+	status := scan("/api/dupes?path=/")
+	require.Len(t, status.Groups, 2)
+	assert.Equal(t, []string{"/a/big.bin", "/b/big copy.bin", "/c/big.bin"}, paths(status.Groups[0]))
+	assert.Equal(t, int64(len(big)), status.Groups[0].Size)
+	assert.Equal(t, int64(2*len(big)), status.Groups[0].Wasted)
+	assert.Len(t, status.Groups[0].Hash, 64)
+	assert.Equal(t, []string{"/docs/readme.txt", "/docs/readme2.txt", "/logs/debug.log"}, paths(status.Groups[1]))
+	assert.Equal(t, int64(10), status.Groups[1].Wasted)
+	assert.Equal(t, &dupeSummary{Groups: 2, Files: 6, Wasted: int64(2*len(big)) + 10}, status.Summary)
+	assert.Equal(t, dupePhaseFull, status.Phase)
+	assert.Equal(t, int64(13), status.Progress.Files)
+	assert.Equal(t, status.Progress.BytesTotal, status.Progress.BytesHashed)
+	assert.NotEmpty(t, status.Finished)
+
+	// 子目录、最小大小和忽略规则
+	status = scan("/api/dupes?path=/docs")
+	require.Len(t, status.Groups, 1)
+	assert.Equal(t, []string{"/docs/readme.txt", "/docs/readme2.txt"}, paths(status.Groups[0]))
+	status = scan("/api/dupes?path=/&minSize=1KB")
+	require.Len(t, status.Groups, 1)
+	assert.Len(t, status.Groups[0].Files, 3)
+	status = scan("/api/dupes?path=/&gitignore=true")
+	require.Len(t, status.Groups, 2)
+	assert.Equal(t, []string{"/docs/readme.txt", "/docs/readme2.txt"}, paths(status.Groups[1]))
+
+	// 分页
+	w, page := do(http.MethodGet, "/api/dupes/"+status.ID+"?offset=1&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, page.Groups, 1)
+	assert.Equal(t, int64(1), page.Offset)
+	assert.False(t, page.HasMore)
+	assert.Equal(t, 2, page.Summary.Groups)
+	_, page = do(http.MethodGet, "/api/dupes/"+status.ID+"?limit=1")
+	assert.True(t, page.HasMore)
+
+	// 任务列表不含结果
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/dupes", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []dupeJobStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list, 4)
+	assert.Empty(t, list[0].Groups)
+
+	// 删除任务
+	w, _ = do(http.MethodDelete, "/api/dupes/"+status.ID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = do(http.MethodGet, "/api/dupes/"+status.ID)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "JOB_NOT_FOUND")
+
+	// 参数错误
+	w, _ = do(http.MethodPost, "/api/dupes?path=/docs/readme.txt")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_PATH")
+	w, _ = do(http.MethodPost, "/api/dupes?path=/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = do(http.MethodPost, "/api/dupes?path=/&minSize=abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = do(http.MethodGet, "/api/dupes/"+page.ID+"?limit=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDupeJobs_Cancel(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte("same"), 0644))
+	}
+
+	// 取消后各阶段立即停止
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	job := &dupeJob{minSize: 1}
+	_, err := job.run(ctx, root)
+	assert.ErrorIs(t, err, context.Canceled)
+	job.finish(nil, err)
+	assert.Equal(t, dupeCanceled, job.status(0, 10).State)
+	assert.Nil(t, job.status(0, 10).Summary)
+
+	groups := [][]*dupeFile{{{abs: filepath.Join(root, "a.txt"), rel: "a.txt"}, {abs: filepath.Join(root, "b.txt"), rel: "b.txt"}}}
+	_, err = job.hashGroups(ctx, groups, job.fullHash)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 同时运行的任务数有上限
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	for i := range maxRunningDupeJobs {
+		running := &dupeJob{id: string(rune('a' + i)), state: dupeRunning, cancel: func() {}, done: make(chan struct{})}
+		close(running.done)
+		server.dupes.jobs[running.id] = running
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/dupes?path=/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "TOO_MANY_JOBS")
+
+	// 取消运行中的任务：等待任务停止后删除
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/dupes/a", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, server.dupes.get("a"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/dupes?path=/", nil))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	// 保留的任务数有上限，淘汰最早结束的任务
+	server.Close()
+	for i := range maxDupeJobs {
+		finished := &dupeJob{id: string(rune('A' + i)), state: dupeDone, finished: time.Now().Add(time.Duration(i) * time.Second)}
+		server.dupes.jobs[finished.id] = finished
+	}
+	delete(server.dupes.jobs, "b")
+	job = &dupeJob{minSize: 1}
+	require.NoError(t, server.dupes.start(root, job))
+	<-job.done
+	assert.Len(t, server.dupes.jobs, maxDupeJobs)
+	assert.Nil(t, server.dupes.get("A"))
+}
@@ -0,0 +1,395 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EXIF 标签
+const (
+	exifTagMake             = 0x010f // 相机厂商
+	exifTagModel            = 0x0110 // 相机型号
+	exifTagOrientation      = 0x0112 // 方向
+	exifTagSoftware         = 0x0131 // 软件
+	exifTagDateTime         = 0x0132 // 修改时间
+	exifTagExifIFD          = 0x8769 // Exif 子 IFD 偏移
+	exifTagGPSIFD           = 0x8825 // GPS 子 IFD 偏移
+	exifTagExposureTime     = 0x829a // 曝光时间
+	exifTagFNumber          = 0x829d // 光圈
+	exifTagISO              = 0x8827 // 感光度
+	exifTagDateTimeOriginal = 0x9003 // 拍摄时间
+	exifTagOffsetTimeOrig   = 0x9011 // 拍摄时区
+	exifTagFocalLength      = 0x920a // 焦距
+	exifTagLensModel        = 0xa434 // 镜头型号
+	gpsTagLatitudeRef       = 0x0001
+	gpsTagLatitude          = 0x0002
+	gpsTagLongitudeRef      = 0x0003
+	gpsTagLongitude         = 0x0004
+	gpsTagAltitudeRef       = 0x0005
+	gpsTagAltitude          = 0x0006
+)
+
+// exifInfo 常用 EXIF 字段
+type exifInfo struct {
+	Make         string   `json:"make,omitempty"`         // 相机厂商
+	Model        string   `json:"model,omitempty"`        // 相机型号
+	LensModel    string   `json:"lensModel,omitempty"`    // 镜头型号
+	Software     string   `json:"software,omitempty"`     // 软件
+	DateTime     string   `json:"dateTime,omitempty"`     // 拍摄时间（ISO 8601，含时区时带偏移）
+	Orientation  int      `json:"orientation,omitempty"`  // 方向（1-8）
+	ExposureTime string   `json:"exposureTime,omitempty"` // 曝光时间，例如 1/125
+	FNumber      float64  `json:"fNumber,omitempty"`      // 光圈值
+	ISO          int      `json:"iso,omitempty"`          // 感光度
+	FocalLength  float64  `json:"focalLength,omitempty"`  // 焦距（毫米）
+	GPS          *gpsInfo `json:"gps,omitempty"`          // GPS 位置
+}
+
+// gpsInfo GPS 位置
+type gpsInfo struct {
+	Latitude  float64  `json:"latitude"`           // 纬度（南纬为负）
+	Longitude float64  `json:"longitude"`          // 经度（西经为负）
+	Altitude  *float64 `json:"altitude,omitempty"` // 海拔（米，海平面以下为负）
+}
+
+// errNoExif 文件中不包含 EXIF 数据
+var errNoExif = errors.New("no exif data")
+
+// tiffData EXIF 中的 TIFF 结构（字节序 + 原始数据）
+type tiffData struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// tiffEntry IFD 中的一个条目
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte // 值的原始字节（已根据偏移量定位）
+}
+
+// tiffTypeSizes TIFF 数据类型对应的字节数
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// jpegSegments JPEG 文件中与元数据相关的段
+type jpegSegments struct {
+	exif []byte   // APP1 Exif 中的 TIFF 数据
+	icc  [][]byte // APP2 ICC_PROFILE 分块，按序号排列
+}
+
+// readJPEGSegments 读取 JPEG 图像数据之前的 APP1 Exif 和 APP2 ICC_PROFILE 段
+func readJPEGSegments(r io.Reader) (*jpegSegments, error) {
+	br := bufio.NewReader(r)
+	var marker [2]byte
+	if _, err := io.ReadFull(br, marker[:]); err != nil || marker != [2]byte{0xff, 0xd8} {
+		return nil, errNoExif
+	}
+
+	segments := &jpegSegments{}
+	for {
+		if _, err := io.ReadFull(br, marker[:]); err != nil || marker[0] != 0xff {
+			return segments, nil
+		}
+		// 图像数据开始或文件结束，之后不会再有 APP 段
+		if marker[1] == 0xda || marker[1] == 0xd9 {
+			return segments, nil
+		}
+
+		var length uint16
+		if err := binary.Read(br, binary.BigEndian, &length); err != nil || length < 2 {
+			return segments, nil
+		}
+		if marker[1] != 0xe1 && marker[1] != 0xe2 {
+			if _, err := br.Discard(int(length) - 2); err != nil {
+				return segments, nil
+			}
+			continue
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(br, segment); err != nil {
+			return segments, nil
+		}
+		switch {
+		case marker[1] == 0xe1 && segments.exif == nil && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			segments.exif = segment[6:]
+		case marker[1] == 0xe2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) && len(segment) > 14:
+			// 分块格式：ICC_PROFILE\0 + 序号(从 1 开始) + 总块数 + 数据
+			seq, total := int(segment[12]), int(segment[13])
+			if seq < 1 || seq > total {
+				continue
+			}
+			if len(segments.icc) < total {
+				segments.icc = append(segments.icc, make([][]byte, total-len(segments.icc))...)
+			}
+			segments.icc[seq-1] = segment[14:]
+		}
+	}
+}
+
+// readJPEGExif 从 JPEG 文件的 APP1 段中提取 EXIF 的 TIFF 数据
+func readJPEGExif(r io.Reader) ([]byte, error) {
+	segments, err := readJPEGSegments(r)
+	if err != nil {
+		return nil, err
+	}
+	if segments.exif == nil {
+		return nil, errNoExif
+	}
+	return segments.exif, nil
+}
+
+// parseTIFF 解析 TIFF 头，返回可用于读取 IFD 的结构
+func parseTIFF(data []byte) (*tiffData, error) {
+	if len(data) < 8 {
+		return nil, errNoExif
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errNoExif
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, errNoExif
+	}
+	return &tiffData{data: data, order: order}, nil
+}
+
+// firstIFD 返回 IFD0 的偏移量
+func (t *tiffData) firstIFD() uint32 {
+	return t.order.Uint32(t.data[4:8])
+}
+
+// readIFD 读取 offset 处的 IFD，返回其中的条目和下一个 IFD 的偏移量
+func (t *tiffData) readIFD(offset uint32) ([]tiffEntry, uint32, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, 0, errNoExif
+	}
+	count := uint32(t.order.Uint16(t.data[offset:]))
+	end := uint64(offset) + 2 + uint64(count)*12
+	if end+4 > uint64(len(t.data)) {
+		return nil, 0, errNoExif
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		raw := t.data[offset+2+i*12 : offset+2+(i+1)*12]
+		entry := tiffEntry{
+			tag:   t.order.Uint16(raw[0:2]),
+			typ:   t.order.Uint16(raw[2:4]),
+			count: t.order.Uint32(raw[4:8]),
+		}
+		size := uint64(tiffTypeSizes[entry.typ]) * uint64(entry.count)
+		if size <= 4 {
+			entry.value = raw[8 : 8+size]
+		} else {
+			// 超过 4 字节的值存放在偏移量指向的位置
+			valueOffset := uint64(t.order.Uint32(raw[8:12]))
+			if valueOffset+size > uint64(len(t.data)) {
+				continue
+			}
+			entry.value = t.data[valueOffset : valueOffset+size]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, t.order.Uint32(t.data[end:]), nil
+}
+
+// uint 返回整数类型条目的第 i 个值
+func (t *tiffData) uint(e tiffEntry, i int) (uint32, bool) {
+	switch e.typ {
+	case 3: // SHORT
+		if len(e.value) >= (i+1)*2 {
+			return uint32(t.order.Uint16(e.value[i*2:])), true
+		}
+	case 4: // LONG
+		if len(e.value) >= (i+1)*4 {
+			return t.order.Uint32(e.value[i*4:]), true
+		}
+	}
+	return 0, false
+}
+
+// string 返回 ASCII 类型条目的值（去掉末尾的 NUL 和空白）
+func (t *tiffData) string(e tiffEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	value := e.value
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(string(value))
+}
+
+// rational 返回 RATIONAL/SRATIONAL 类型条目的第 i 个值（分子、分母）
+func (t *tiffData) rational(e tiffEntry, i int) (int64, int64, bool) {
+	if (e.typ != 5 && e.typ != 10) || len(e.value) < (i+1)*8 {
+		return 0, 0, false
+	}
+	num, den := t.order.Uint32(e.value[i*8:]), t.order.Uint32(e.value[i*8+4:])
+	if e.typ == 10 {
+		return int64(int32(num)), int64(int32(den)), den != 0
+	}
+	return int64(num), int64(den), den != 0
+}
+
+// float 返回 RATIONAL 条目第 i 个值的浮点数形式
+func (t *tiffData) float(e tiffEntry, i int) (float64, bool) {
+	num, den, ok := t.rational(e, i)
+	if !ok {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
+
+// ifdMap 读取 IFD 并按标签建立索引，读取失败时返回空 map
+func (t *tiffData) ifdMap(offset uint32) map[uint16]tiffEntry {
+	entries, _, err := t.readIFD(offset)
+	if err != nil {
+		return nil
+	}
+	result := make(map[uint16]tiffEntry, len(entries))
+	for _, e := range entries {
+		result[e.tag] = e
+	}
+	return result
+}
+
+// parseExif 解析 TIFF 数据中的常用 EXIF 字段
+func parseExif(data []byte) (*exifInfo, error) {
+	tiff, err := parseTIFF(data)
+	if err != nil {
+		return nil, err
+	}
+	ifd0 := tiff.ifdMap(tiff.firstIFD())
+	if ifd0 == nil {
+		return nil, errNoExif
+	}
+
+	info := &exifInfo{
+		Make:     tiff.string(ifd0[exifTagMake]),
+		Model:    tiff.string(ifd0[exifTagModel]),
+		Software: tiff.string(ifd0[exifTagSoftware]),
+		DateTime: exifDateTime(tiff.string(ifd0[exifTagDateTime]), ""),
+	}
+	if v, ok := tiff.uint(ifd0[exifTagOrientation], 0); ok && v >= 1 && v <= 8 {
+		info.Orientation = int(v)
+	}
+
+	// Exif 子 IFD：拍摄参数
+	if offset, ok := tiff.uint(ifd0[exifTagExifIFD], 0); ok {
+		sub := tiff.ifdMap(offset)
+		if dt := exifDateTime(tiff.string(sub[exifTagDateTimeOriginal]), tiff.string(sub[exifTagOffsetTimeOrig])); dt != "" {
+			info.DateTime = dt
+		}
+		info.LensModel = tiff.string(sub[exifTagLensModel])
+		if num, den, ok := tiff.rational(sub[exifTagExposureTime], 0); ok && num > 0 {
+			if num < den {
+				info.ExposureTime = fmt.Sprintf("1/%d", (den+num/2)/num)
+			} else {
+				info.ExposureTime = strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64)
+			}
+		}
+		if v, ok := tiff.float(sub[exifTagFNumber], 0); ok {
+			info.FNumber = math.Round(v*10) / 10
+		}
+		if v, ok := tiff.uint(sub[exifTagISO], 0); ok {
+			info.ISO = int(v)
+		}
+		if v, ok := tiff.float(sub[exifTagFocalLength], 0); ok {
+			info.FocalLength = math.Round(v*10) / 10
+		}
+	}
+
+	// GPS 子 IFD：经纬度和海拔
+	if offset, ok := tiff.uint(ifd0[exifTagGPSIFD], 0); ok {
+		info.GPS = parseGPS(tiff, tiff.ifdMap(offset))
+	}
+	return info, nil
+}
+
+// parseGPS 解析 GPS 子 IFD，缺少经纬度时返回 nil
+func parseGPS(tiff *tiffData, gps map[uint16]tiffEntry) *gpsInfo {
+	lat, okLat := gpsCoordinate(tiff, gps[gpsTagLatitude], tiff.string(gps[gpsTagLatitudeRef]), "S")
+	lon, okLon := gpsCoordinate(tiff, gps[gpsTagLongitude], tiff.string(gps[gpsTagLongitudeRef]), "W")
+	if !okLat || !okLon {
+		return nil
+	}
+	info := &gpsInfo{Latitude: lat, Longitude: lon}
+	if alt, ok := tiff.float(gps[gpsTagAltitude], 0); ok {
+		if ref := gps[gpsTagAltitudeRef]; len(ref.value) > 0 && ref.value[0] == 1 {
+			alt = -alt
+		}
+		info.Altitude = &alt
+	}
+	return info
+}
+
+// gpsCoordinate 将 度/分/秒 三个 RATIONAL 转换为十进制度数，ref 等于 negativeRef 时取负
+func gpsCoordinate(tiff *tiffData, e tiffEntry, ref, negativeRef string) (float64, bool) {
+	var parts [3]float64
+	for i := range parts {
+		v, ok := tiff.float(e, i)
+		if !ok {
+			return 0, false
+		}
+		parts[i] = v
+	}
+	value := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		value = -value
+	}
+	return math.Round(value*1e7) / 1e7, true
+}
+
+// exifDateTime 将 EXIF 时间（2006:01:02 15:04:05）转换为 ISO 8601 格式，offset 形如 +08:00
+func exifDateTime(value, offset string) string {
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return ""
+	}
+	if _, err := time.Parse("-07:00", offset); err == nil {
+		return t.Format("2006-01-02T15:04:05") + offset
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// exifOrientation 读取 JPEG 的 EXIF 方向（1-8），没有或无法解析时返回 1
+func exifOrientation(r io.Reader) int {
+	data, err := readJPEGExif(r)
+	if err != nil {
+		return 1
+	}
+	tiff, err := parseTIFF(data)
+	if err != nil {
+		return 1
+	}
+	entries, _, err := tiff.readIFD(tiff.firstIFD())
+	if err != nil {
+		return 1
+	}
+	for _, e := range entries {
+		if e.tag != exifTagOrientation {
+			continue
+		}
+		if v, ok := tiff.uint(e, 0); ok && v >= 1 && v <= 8 {
+			return int(v)
+		}
+	}
+	return 1
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ignoreCacheSize = 4096 // 最多缓存的忽略规则文件数量
+
+// ignoreRule .gitignore 中的一条规则
+type ignoreRule struct {
+	re       *regexp.Regexp
+	negate   bool // ! 开头：重新包含之前被忽略的路径
+	dirOnly  bool // / 结尾：只匹配目录
+	anchored bool // 开头或中间含 /：按相对规则文件所在目录的路径匹配，否则按名称匹配任意层级
+}
+
+// parseIgnoreRules 解析 .gitignore 格式的规则，跳过空行、注释和无效的模式
+func parseIgnoreRules(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// 去掉末尾未转义的空格
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		expr, err := globToRegexp(line)
+		if err != nil {
+			continue
+		}
+		if rule.re, err = regexp.Compile(expr); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignoreFile 一个忽略规则文件及其规则相对的目录
+type ignoreFile struct {
+	base  string // 规则相对的目录（相对根目录，/ 分隔，根目录为空）
+	rules []ignoreRule
+}
+
+// match 按规则判断 rel（相对根目录）是否被忽略，后面的规则优先；没有规则匹配时 matched 为 false
+func (f *ignoreFile) match(rel string, isDir bool) (matched, ignored bool) {
+	if f == nil {
+		return false, false
+	}
+	if f.base != "" {
+		if !strings.HasPrefix(rel, f.base+"/") {
+			return false, false
+		}
+		rel = rel[len(f.base)+1:]
+	}
+	name := path.Base(rel)
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		rule := f.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := name
+		if rule.anchored {
+			target = rel
+		}
+		if rule.re.MatchString(target) {
+			return true, !rule.negate
+		}
+	}
+	return false, false
+}
+
+// ignoreCache 按绝对路径缓存解析后的忽略规则文件，文件变化（大小或修改时间）后重新解析，超出容量时淘汰最久未使用的条目
+type ignoreCache struct {
+	lru *lruCache[string, *ignoreCacheEntry] // 规则文件的绝对路径 -> 解析结果
+
+	globalOnce sync.Once
+	globalPath string // git 全局忽略文件的路径，没有时为空
+}
+
+// ignoreCacheEntry 规则文件的解析结果及解析时的文件状态
+type ignoreCacheEntry struct {
+	size    int64
+	modTime time.Time
+	rules   []ignoreRule
+}
+
+// newIgnoreCache 创建忽略规则缓存
+func newIgnoreCache(max int) *ignoreCache {
+	return &ignoreCache{lru: newLRUCache[string, *ignoreCacheEntry](int64(max), nil)}
+}
+
+// get 返回规则文件解析后的规则，文件不存在或无法读取时返回 nil
+func (c *ignoreCache) get(absPath string) []ignoreRule {
+	info, err := os.Stat(absPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	if entry, ok := c.lru.get(absPath); ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.rules
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil
+	}
+	entry := &ignoreCacheEntry{size: info.Size(), modTime: info.ModTime(), rules: parseIgnoreRules(data)}
+	c.lru.add(absPath, entry)
+	return entry.rules
+}
+
+// global 返回 git 全局忽略文件（core.excludesFile）的规则
+func (c *ignoreCache) global() []ignoreRule {
+	c.globalOnce.Do(func() { c.globalPath = globalExcludesFile() })
+	if c.globalPath == "" {
+		return nil
+	}
+	return c.get(c.globalPath)
+}
+
+// globalExcludesFile 返回 git 全局忽略文件的路径：~/.gitconfig 或 $XDG_CONFIG_HOME/git/config 中的 core.excludesFile，
+// 未设置时为 $XDG_CONFIG_HOME/git/ignore（默认 ~/.config/git/ignore）
+func globalExcludesFile() string {
+	home, _ := os.UserHomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	var configs []string
+	if configHome != "" {
+		configs = append(configs, filepath.Join(configHome, "git", "config"))
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig")) // 优先级更高，后读取
+	}
+	excludes := ""
+	for _, config := range configs {
+		if v := readGitConfigValue(config, "core", "excludesfile"); v != "" {
+			excludes = v
+		}
+	}
+	if strings.HasPrefix(excludes, "~/") && home != "" {
+		excludes = filepath.Join(home, excludes[2:])
+	}
+	if excludes == "" && configHome != "" {
+		excludes = filepath.Join(configHome, "git", "ignore")
+	}
+	return excludes
+}
+
+// readGitConfigValue 从 git 配置文件中读取 section.key 的值（名称不区分大小写），只支持简单的 key = value 形式
+func readGitConfigValue(configPath, section, key string) string {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+	value, current := "", ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || current != section || !strings.EqualFold(strings.TrimSpace(k), key) {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return value
+}
+
+// ignoreMatcher 一次请求中按 .gitignore、.git/info/exclude 和全局忽略文件判断路径是否被忽略
+// 规则文件通过 ignoreCache 共享，各目录的规则在请求内只查找一次；不能并发使用
+type ignoreMatcher struct {
+	root    string
+	cache   *ignoreCache
+	global  *ignoreFile
+	dirs    map[string]ignoreDir // 相对根目录的目录 -> 该目录下的规则文件
+	ignored map[string]bool      // isIgnoredPath 中已判断过的目录
+}
+
+// ignoreDir 一个目录下的规则文件，没有时为 nil
+type ignoreDir struct {
+	gitignore *ignoreFile // .gitignore
+	exclude   *ignoreFile // .git/info/exclude（仓库根目录）
+}
+
+// gitIgnore 请求携带 gitignore=true 时返回忽略规则的匹配器，否则返回 nil（不忽略任何路径）
+func (s *Server) gitIgnore(c *gin.Context) *ignoreMatcher {
+	if c.Query("gitignore") != "true" {
+		return nil
+	}
+	m := &ignoreMatcher{
+		root:    s.cfg.Root,
+		cache:   s.ignores,
+		dirs:    make(map[string]ignoreDir),
+		ignored: make(map[string]bool),
+	}
+	if rules := s.ignores.global(); rules != nil {
+		m.global = &ignoreFile{rules: rules}
+	}
+	return m
+}
+
+// dir 返回相对根目录的目录 rel 下的规则文件
+func (m *ignoreMatcher) dir(rel string) ignoreDir {
+	if d, ok := m.dirs[rel]; ok {
+		return d
+	}
+	var d ignoreDir
+	abs := filepath.Join(m.root, filepath.FromSlash(rel))
+	if rules := m.cache.get(filepath.Join(abs, ".gitignore")); rules != nil {
+		d.gitignore = &ignoreFile{base: rel, rules: rules}
+	}
+	if rules := m.cache.get(filepath.Join(abs, ".git", "info", "exclude")); rules != nil {
+		d.exclude = &ignoreFile{base: rel, rules: rules}
+	}
+	m.dirs[rel] = d
+	return d
+}
+
+// isIgnored 判断相对根目录的路径 rel 是否被忽略，不检查上级目录本身是否被忽略（遍历时已跳过被忽略的目录）
+// 优先级从高到低：各级 .gitignore（越深越优先）、.git/info/exclude、全局忽略文件；.git 目录总是被忽略
+func (m *ignoreMatcher) isIgnored(rel string, isDir bool) bool {
+	if m == nil || rel == "" {
+		return false
+	}
+	if path.Base(rel) == ".git" {
+		return true
+	}
+
+	// 上级目录，从深到浅
+	var parents []string
+	for p := path.Dir(rel); ; p = path.Dir(p) {
+		if p == "." {
+			p = ""
+		}
+		parents = append(parents, p)
+		if p == "" {
+			break
+		}
+	}
+	for _, p := range parents {
+		if matched, ignored := m.dir(p).gitignore.match(rel, isDir); matched {
+			return ignored
+		}
+	}
+	for _, p := range parents {
+		if matched, ignored := m.dir(p).exclude.match(rel, isDir); matched {
+			return ignored
+		}
+	}
+	_, ignored := m.global.match(rel, isDir)
+	return ignored
+}
+
+// isIgnoredPath 判断路径是否被忽略，同时检查 from（相对根目录的搜索目录）之下的各级上级目录，用于不经过遍历的索引条目
+func (m *ignoreMatcher) isIgnoredPath(rel string, isDir bool, from string) bool {
+	if m == nil {
+		return false
+	}
+	for i := len(from) + 1; i < len(rel); i++ {
+		if rel[i] != '/' {
+			continue
+		}
+		dir := rel[:i]
+		ignored, ok := m.ignored[dir]
+		if !ok {
+			ignored = m.isIgnored(dir, true)
+			m.ignored[dir] = ignored
+		}
+		if ignored {
+			return true
+		}
+	}
+	return m.isIgnored(rel, isDir)
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreFile_Match(t *testing.T) {
+	rules := parseIgnoreRules([]byte(`# 注释
+node_modules/
+*.log
+!keep.log
+/build
+docs/**/*.tmp
+**/cache
+\#hash
+` + "trailing\\ \n"))
+	f := &ignoreFile{base: "app", rules: rules}
+
+	tests := []struct {
+		rel           string
+		isDir         bool
+		matched, want bool
+	}{
+		{"app/node_modules", true, true, true},
+		{"app/src/node_modules", true, true, true},
+		{"app/node_modules", false, false, false}, // 只匹配目录
+		{"app/debug.log", false, true, true},
+		{"app/src/keep.log", false, true, false}, // 重新包含
+		{"app/build", true, true, true},
+		{"app/src/build", true, false, false}, // 以 / 开头只匹配规则所在目录
+		{"app/docs/a/b/x.tmp", false, true, true},
+		{"app/docs/x.tmp", false, true, true},
+		{"app/x.tmp", false, false, false},
+		{"app/a/b/cache", true, true, true},
+		{"app/trailing ", false, true, true},
+		{"app/#hash", false, true, true},
+		{"other/debug.log", false, false, false}, // 不在规则所在目录下
+	}
+	for _, tt := range tests {
+		matched, ignored := f.match(tt.rel, tt.isDir)
+		assert.Equal(t, tt.matched, matched, tt.rel)
+		assert.Equal(t, tt.want, ignored, tt.rel)
+	}
+}
+
+func TestGlobalExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	assert.Equal(t, filepath.Join(home, ".config", "git", "ignore"), globalExcludesFile())
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[user]\n\tname = x\n[core]\n\texcludesFile = \"~/.gitignore_global\"\n"), 0644))
+	assert.Equal(t, filepath.Join(home, ".gitignore_global"), globalExcludesFile())
+}
+
+func TestGitIgnore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "config", "git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "config", "git", "ignore"), []byte(".DS_Store\n"), 0644))
+
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":                   "node_modules/\ndist\n*.log\n!keep.log\n",
+		".git/info/exclude":            "local.txt\n",
+		".git/HEAD":                    "ref: refs/heads/main\n",
+		"src/app.js":                   "TODO app\n",
+		"src/debug.log":                "TODO log\n",
+		"src/keep.log":                 "TODO keep\n",
+		"src/.DS_Store":                "x",
+		"local.txt":                    "TODO local\n",
+		"node_modules/lib/app.js":      "TODO lib\n",
+		"dist/app.js":                  "TODO dist\n",
+		"go/.gitignore":                "vendor/\n",
+		"go/vendor/mod/app.go":         "TODO vendor\n",
+		"go/app.go":                    "TODO go\n",
+		"go/pkg/node_modules/x/app.js": "TODO nested\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+
+	for _, index := range []bool{false, true} {
+		server, err := New(Config{Root: root, NameIndex: index})
+		require.NoError(t, err)
+		defer server.Close()
+		if index {
+			require.Eventually(t, func() bool { return server.names.status().Ready }, 5*time.Second, 10*time.Millisecond)
+		}
+		router := server.Handler()
+		search := func(target string) []string {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var resp searchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			var paths []string
+			for _, r := range resp.Results {
+				paths = append(paths, r.Path)
+			}
+			return paths
+		}
+
+		// 默认不忽略
+		assert.Len(t, search("/api/search?path=/&q=app&recursive=true"), 6, "index=%v", index)
+
+		// 根目录和子目录的 .gitignore、.git/info/exclude、全局忽略文件和 .git 目录
+		assert.ElementsMatch(t, []string{"/src/app.js", "/go/app.go"},
+			search("/api/search?path=/&q=app&recursive=true&gitignore=true"), "index=%v", index)
+		assert.ElementsMatch(t, []string{"/src/keep.log"},
+			search("/api/search?path=/&q=.log&recursive=true&gitignore=true"), "index=%v", index)
+		assert.Empty(t, search("/api/search?path=/&q=local&recursive=true&gitignore=true"), "index=%v", index)
+		assert.Empty(t, search("/api/search?path=/&q=DS_Store&recursive=true&gitignore=true"), "index=%v", index)
+		assert.Empty(t, search("/api/search?path=/&q=HEAD&recursive=true&gitignore=true"), "index=%v", index)
+
+		// 在子目录中搜索时仍应用上级目录的规则
+		assert.Equal(t, []string{"/go/app.go"}, search("/api/search?path=/go&q=app&recursive=true&gitignore=true"), "index=%v", index)
+		assert.Equal(t, []string{"/go/app.go"}, search("/api/search?path=/go&q=app&mode=fuzzy&recursive=true&gitignore=true"), "index=%v", index)
+
+		// 单层目录
+		assert.Equal(t, []string{"/go"}, search("/api/search?path=/&q=go&gitignore=true"), "index=%v", index)
+	}
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+
+	// 内容搜索
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search?mode=content&path=/&q=TODO&gitignore=true", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var content contentSearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &content))
+	var paths []string
+	for _, r := range content.Results {
+		paths = append(paths, r.Path)
+	}
+	assert.Equal(t, []string{"/go/app.go", "/src/app.js", "/src/keep.log"}, paths)
+
+	// 目录列表
+	list := func(target string) []string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var items []fileEntry
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{".git", "dist", "go", "node_modules", "src", ".gitignore", "local.txt"}, list("/api/files?path=/"))
+	assert.Equal(t, []string{"go", "src", ".gitignore"}, list("/api/files?path=/&gitignore=true"))
+	assert.Equal(t, []string{"app.js", "keep.log"}, list("/api/files?path=/src&gitignore=true"))
+
+	// 规则文件变化后重新解析
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/\n*.log\n"), 0644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(root, ".gitignore"), later, later))
+	assert.Equal(t, []string{"dist", "go", "src", ".gitignore"}, list("/api/files?path=/&gitignore=true"))
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultGrepContext = 3           // 默认上下文行数
+	maxGrepContext     = 20          // 最多上下文行数
+	defaultGrepLimit   = 100         // 默认最多返回的匹配行数
+	maxGrepLimit       = 1000        // 单次最多返回的匹配行数
+	maxGrepLineSize    = 1024 * 1024 // 单行参与匹配的最大字节数，超出部分忽略
+	maxGrepText        = 512         // 返回的行内容最大字节数，超出时截取第一个匹配附近的内容
+	maxGrepRanges      = 20          // 每行最多返回的匹配位置数
+	grepCancelCheck    = 4096        // 每扫描多少行检查一次客户端是否断开
+)
+
+// errGrepCanceled 客户端断开连接，停止扫描
+var errGrepCanceled = errors.New("grep canceled")
+
+// grepLine 匹配行或上下文行
+type grepLine struct {
+	Line      int64  `json:"line"`                // 行号（从 1 开始）
+	Offset    int64  `json:"offset"`              // 行起始字节偏移，可直接作为 /api/preview 的 offset
+	Text      string `json:"text"`                // 行内容（不含换行符）
+	Truncated bool   `json:"truncated,omitempty"` // 行内容是否被截断
+}
+
+// grepMatch 一个匹配行及其上下文
+type grepMatch struct {
+	grepLine
+	Ranges [][2]int   `json:"ranges"`           // 匹配在 text 中的字节范围 [start, end)
+	Before []grepLine `json:"before,omitempty"` // 之前的上下文行
+	After  []grepLine `json:"after,omitempty"`  // 之后的上下文行
+}
+
+// grepResponse 文件内搜索响应
+type grepResponse struct {
+	Path        string      `json:"path"`                  // 相对路径
+	Name        string      `json:"name"`                  // 文件名
+	Size        int64       `json:"size"`                  // 文件大小（透明解压时为解压后的大小）
+	Modified    string      `json:"modified"`              // 修改时间
+	Compression string      `json:"compression,omitempty"` // 透明解压的压缩格式
+	Matches     []grepMatch `json:"matches"`               // 匹配结果
+	Offset      int64       `json:"offset"`                // 本次扫描的起始偏移（已对齐到行首）
+	NextOffset  int64       `json:"nextOffset"`            // 下次扫描的起始偏移
+	HasMore     bool        `json:"hasMore"`               // 达到 limit 后文件是否还有未扫描的内容
+}
+
+// grepMatcher 在一行中查找匹配位置
+type grepMatcher func(line []byte) [][2]int
+
+// handleGrep 在单个文件中搜索，返回匹配行的行号、字节偏移和上下文
+// GET /api/grep?path=/app.log&pattern=ERROR[&regex=true&ignoreCase=true&context=3&limit=100&offset=0]
+// 默认区分大小写，ignoreCase=true 或 caseSensitive=false 时忽略大小写；
+// 流式逐行扫描，不会把文件整体读入内存；达到 limit 后停止，通过 nextOffset 继续；
+// 返回的 offset 可直接用于 /api/preview?offset= 跳转；压缩文件透明解压后搜索
+func (s *Server) handleGrep(c *gin.Context) {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "pattern is required")
+		return
+	}
+	matcher, err := newGrepMatcher(pattern, c.Query("regex") == "true", queryIgnoreCase(c, false))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PATTERN", err.Error())
+		return
+	}
+	context := defaultGrepContext
+	if v := c.Query("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxGrepContext {
+			abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", fmt.Sprintf("context must be between 0 and %d", maxGrepContext))
+			return
+		}
+		context = n
+	}
+	offset, limit, err := parseOffsetLimit(c, maxGrepLimit)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_RANGE", err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultGrepLimit
+	}
+
+	src, ok := s.openPreviewSource(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	defer src.close()
+
+	head, err := readSniffHead(src.reader)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+		return
+	}
+	src, sniffName, head, ok := s.decompressRequested(c, src, head)
+	if !ok {
+		return
+	}
+	if isBinary, _ := sniffContent(sniffName, head); isBinary {
+		abortWithError(c, http.StatusUnsupportedMediaType, "BINARY_FILE", "cannot search in a binary file")
+		return
+	}
+	// 搜索范围是整个文件，透明解压时先确定解压后的大小
+	if err := src.resolveSize(); err != nil {
+		abortWithError(c, statusFromErr(err), "DECOMPRESS_FAILED", err.Error())
+		return
+	}
+
+	// 从指定偏移继续时，借助行索引确定所在行的行号和行首
+	var line int64
+	if offset > 0 {
+		idx, err := s.lines.get(src.key, src.reader, src.info)
+		if err != nil {
+			abortWithError(c, statusFromErr(err), "READ_FAILED", err.Error())
+			return
+		}
+		line, err = idx.lineNumber(src.reader, offset)
+		if err == nil {
+			offset, err = idx.lineOffset(src.reader, line)
+		}
+		idx.mu.Unlock()
+		if err != nil {
+			abortWithError(c, statusFromErr(err), "READ_FAILED", err.Error())
+			return
+		}
+	}
+
+	info := src.info
+	resp := grepResponse{
+		Path:        src.path,
+		Name:        info.Name(),
+		Size:        info.Size(),
+		Modified:    info.ModTime().UTC().Format(time.RFC3339),
+		Compression: src.compression,
+		Offset:      offset,
+	}
+	scan := grepScan{
+		reader:  io.NewSectionReader(src.reader, offset, info.Size()-offset),
+		match:   matcher,
+		context: context,
+		limit:   int(limit),
+		done:    c.Request.Context().Done(),
+	}
+	resp.Matches, resp.NextOffset, err = scan.run(offset, line)
+	if errors.Is(err, errGrepCanceled) {
+		c.Abort()
+		return
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "READ_FAILED", err.Error())
+		return
+	}
+	resp.HasMore = resp.NextOffset < info.Size()
+	c.JSON(http.StatusOK, resp)
+}
+
+// queryIgnoreCase 解析是否忽略大小写，所有搜索接口同时接受 caseSensitive 和 ignoreCase 两种写法
+// 两者都未指定时使用接口的默认值 fallback，同时指定时以 caseSensitive 为准
+func queryIgnoreCase(c *gin.Context, fallback bool) bool {
+	if v, ok := c.GetQuery("caseSensitive"); ok {
+		return v != "true"
+	}
+	if v, ok := c.GetQuery("ignoreCase"); ok {
+		return v == "true"
+	}
+	return fallback
+}
+
+// newGrepMatcher 创建匹配函数：默认按字面量匹配，regex=true 时使用 RE2 正则表达式
+func newGrepMatcher(pattern string, isRegex, ignoreCase bool) (grepMatcher, error) {
+	if !isRegex && !ignoreCase {
+		needle := []byte(pattern)
+		return func(line []byte) [][2]int {
+			var ranges [][2]int
+			for start := 0; len(ranges) < maxGrepRanges; {
+				i := bytes.Index(line[start:], needle)
+				if i < 0 {
+					break
+				}
+				ranges = append(ranges, [2]int{start + i, start + i + len(needle)})
+				start += i + len(needle)
+			}
+			return ranges
+		}, nil
+	}
+
+	expr := pattern
+	if !isRegex {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(line []byte) [][2]int {
+		var ranges [][2]int
+		for _, loc := range re.FindAllIndex(line, maxGrepRanges) {
+			// 空匹配（例如 ^ 或 x*）没有可高亮的内容，但仍视为该行匹配
+			ranges = append(ranges, [2]int{loc[0], loc[1]})
+		}
+		return ranges
+	}, nil
+}
+
+// grepScan 一次逐行扫描的状态
+type grepScan struct {
+	reader  io.Reader
+	match   grepMatcher
+	context int
+	limit   int
+	done    <-chan struct{}
+}
+
+// run 从 offset（第 line 行，从 0 开始）开始扫描，返回匹配结果和下次扫描的起始偏移
+// 达到 limit 后继续读取最后一个匹配之后的上下文行，下次从最后一个匹配的下一行开始
+func (g *grepScan) run(offset, line int64) ([]grepMatch, int64, error) {
+	reader := bufio.NewReaderSize(g.reader, lineIndexBufferSize)
+	matches := []grepMatch{}
+	before := make([]grepLine, 0, g.context) // 最近的上下文行
+	var pending []int                        // 仍在收集之后上下文的匹配下标
+	next := offset
+
+	var buf []byte
+	for pos := offset; ; line++ {
+		if line%grepCancelCheck == 0 {
+			select {
+			case <-g.done:
+				return nil, 0, errGrepCanceled
+			default:
+			}
+		}
+
+		// 读取一行，超长部分只计入偏移，不参与匹配
+		buf = buf[:0]
+		size := 0
+		var err error
+		for {
+			var chunk []byte
+			chunk, err = reader.ReadSlice('\n')
+			size += len(chunk)
+			if room := maxGrepLineSize - len(buf); room > 0 {
+				buf = append(buf, chunk[:min(len(chunk), room)]...)
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				break
+			}
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		if size == 0 {
+			return matches, next, nil // 文件末尾
+		}
+		text := bytes.TrimSuffix(bytes.TrimSuffix(buf, []byte{'\n'}), []byte{'\r'})
+		lineStart := pos
+		pos += int64(size)
+
+		// 达到 limit 后不再匹配，只收集最后一个匹配之后的上下文
+		var ranges [][2]int
+		checked := len(matches) < g.limit
+		if checked {
+			ranges = g.match(text)
+		} else if len(pending) == 0 {
+			return matches, next, nil
+		}
+		isMatch := ranges != nil
+
+		current := grepLine{Line: line + 1, Offset: lineStart}
+		current.Text, ranges, current.Truncated = grepExcerpt(text, ranges)
+		kept := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, current)
+			if len(matches[i].After) < g.context {
+				kept = append(kept, i)
+			}
+		}
+		pending = kept
+
+		if isMatch {
+			matches = append(matches, grepMatch{
+				grepLine: current,
+				Ranges:   ranges,
+				Before:   append([]grepLine(nil), before...),
+			})
+			if g.context > 0 {
+				pending = append(pending, len(matches)-1)
+			}
+			before = before[:0]
+		} else if g.context > 0 {
+			if len(before) == g.context {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, current)
+		}
+		if checked {
+			next = pos
+		}
+		if errors.Is(err, io.EOF) {
+			return matches, next, nil
+		}
+	}
+}
+
+// grepExcerpt 返回用于展示的行内容；超过 maxGrepText 时截取第一个匹配附近的内容，
+// 截断处对齐到字符边界，ranges 相应平移并去掉超出范围的部分
+func grepExcerpt(text []byte, ranges [][2]int) (string, [][2]int, bool) {
+	if ranges == nil {
+		ranges = [][2]int{}
+	}
+	if len(text) <= maxGrepText {
+		return string(text), ranges, false
+	}
+
+	start := 0
+	if len(ranges) > 0 {
+		start = max(ranges[0][0]-maxGrepText/4, 0)
+	}
+	end := min(start+maxGrepText, len(text))
+	start = max(end-maxGrepText, 0)
+	for start > 0 && start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	excerpt := trimIncompleteRune(text[start:end])
+
+	kept := [][2]int{}
+	for _, r := range ranges {
+		if r[0] >= start && r[1] <= start+len(excerpt) {
+			kept = append(kept, [2]int{r[0] - start, r[1] - start})
+		}
+	}
+	return string(excerpt), kept, true
+}
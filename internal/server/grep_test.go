@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrepExcerpt(t *testing.T) {
+	text, ranges, truncated := grepExcerpt([]byte("short line"), [][2]int{{0, 5}})
+	assert.Equal(t, "short line", text)
+	assert.Equal(t, [][2]int{{0, 5}}, ranges)
+	assert.False(t, truncated)
+
+	// 长行截取第一个匹配附近的内容，不拆开多字节字符
+	long := []byte(strings.Repeat("中", 400) + "ERROR" + strings.Repeat("x", 1000))
+	text, ranges, truncated = grepExcerpt(long, [][2]int{{1200, 1205}})
+	assert.True(t, truncated)
+	assert.LessOrEqual(t, len(text), maxGrepText)
+	require.Len(t, ranges, 1)
+	assert.Equal(t, "ERROR", text[ranges[0][0]:ranges[0][1]])
+	assert.True(t, strings.HasPrefix(text, "中"))
+}
+
+func TestHandleGrep(t *testing.T) {
+	root := t.TempDir()
+	var b strings.Builder
+	for i := 1; i <= 3000; i++ {
+		switch {
+		case i%1000 == 0:
+			fmt.Fprintf(&b, "line %d ERROR disk full (error)\r\n", i)
+		case i == 1500:
+			fmt.Fprintf(&b, "line %d error: timeout\r\n", i)
+		default:
+			fmt.Fprintf(&b, "line %d ok\r\n", i)
+		}
+	}
+	content := b.String()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.log"), []byte(content), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.log.1.gz"), gzipBytes(t, []byte(content)), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "image.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0644))
+	// GBK 编码的“名称 = 旧”
+	require.NoError(t, os.WriteFile(filepath.Join(root, "gbk.conf"), []byte("\xc3\xfb\xb3\xc6 = \xbe\xc9\nport = 80\n"), 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string) (*httptest.ResponseRecorder, grepResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp grepResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	w, resp := get("/api/grep?path=/app.log&pattern=ERROR&context=2")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Matches, 3)
+	m := resp.Matches[0]
+	assert.Equal(t, int64(1000), m.Line)
+	assert.Equal(t, int64(strings.Index(content, "line 1000 ")), m.Offset)
+	assert.Equal(t, "line 1000 ERROR disk full (error)", m.Text)
+	assert.Equal(t, [][2]int{{10, 15}}, m.Ranges)
+	require.Len(t, m.Before, 2)
+	assert.Equal(t, "line 998 ok", m.Before[0].Text)
+	require.Len(t, m.After, 2)
+	assert.Equal(t, int64(1002), m.After[1].Line)
+	assert.Empty(t, resp.Matches[2].After)
+	assert.False(t, resp.HasMore)
+	assert.Equal(t, int64(len(content)), resp.NextOffset)
+
+	// 忽略大小写、正则表达式
+	_, resp = get("/api/grep?path=/app.log&pattern=error&ignoreCase=true&context=0")
+	require.Len(t, resp.Matches, 4)
+	assert.Equal(t, [][2]int{{10, 15}, {27, 32}}, resp.Matches[0].Ranges)
+	assert.Nil(t, resp.Matches[0].Before)
+	_, resp = get("/api/grep?path=/app.log&pattern=error&caseSensitive=false&context=0")
+	assert.Len(t, resp.Matches, 4)
+	_, resp = get("/api/grep?path=/app.log&pattern=ERROR&ignoreCase=true&caseSensitive=true&context=0")
+	assert.Len(t, resp.Matches, 3) // 同时指定时以 caseSensitive 为准
+	_, resp = get("/api/grep?path=/app.log&pattern=" + "error%3A%20%5Cw%2B" + "&regex=true")
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, int64(1500), resp.Matches[0].Line)
+
+	// 达到 limit 后通过 nextOffset 继续，行号保持连续
+	_, resp = get("/api/grep?path=/app.log&pattern=ERROR&limit=1&context=1")
+	require.Len(t, resp.Matches, 1)
+	assert.True(t, resp.HasMore)
+	assert.Len(t, resp.Matches[0].After, 1)
+	_, resp = get(fmt.Sprintf("/api/grep?path=/app.log&pattern=ERROR&limit=1&offset=%d", resp.NextOffset))
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, int64(2000), resp.Matches[0].Line)
+	assert.Equal(t, int64(strings.Index(content, "line 2000 ")), resp.Matches[0].Offset)
+
+	// 偏移落在行中间时对齐到行首
+	mid := int64(strings.Index(content, "line 1999 ") + 3)
+	_, resp = get(fmt.Sprintf("/api/grep?path=/app.log&pattern=line&limit=1&offset=%d", mid))
+	assert.Equal(t, int64(1999), resp.Matches[0].Line)
+	assert.Equal(t, mid-3, resp.Offset)
+
+	// 压缩文件透明解压后搜索，偏移基于解压后的内容
+	_, resp = get("/api/grep?path=/app.log.1.gz&pattern=ERROR&limit=1&offset=100")
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, "gzip", resp.Compression)
+	assert.Equal(t, int64(strings.Index(content, "line 1000 ")), resp.Matches[0].Offset)
+
+	w, _ = get("/api/grep?path=/app.log")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/grep?path=/app.log&pattern=(&regex=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_PATTERN")
+	w, _ = get("/api/grep?path=/app.log&pattern=x&context=100")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/grep?path=/image.png&pattern=PNG")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	// 非 UTF-8 编码的文本不是二进制文件
+	w, resp = get("/api/grep?path=/gbk.conf&pattern=port")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, int64(2), resp.Matches[0].Line)
+	w, _ = get("/api/grep?path=/missing.log&pattern=x")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
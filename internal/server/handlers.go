@@ -1,8 +1,13 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path"
@@ -11,29 +16,64 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
 // fileEntry 文件/目录信息，用于 API 响应
 type fileEntry struct {
-	Name     string `json:"name"`     // 文件名
-	Path     string `json:"path"`     // 相对路径（以 / 开头）
-	Type     string `json:"type"`     // 类型：file 或 dir
-	Size     int64  `json:"size"`     // 文件大小（字节）
-	Modified string `json:"modified"` // 修改时间（RFC3339 格式）
+	Name     string  `json:"name"`               // 文件名
+	Path     string  `json:"path"`               // 相对路径（以 / 开头）
+	Type     string  `json:"type"`               // 类型：file 或 dir
+	Size     int64   `json:"size"`               // 文件大小（字节）
+	Modified string  `json:"modified"`           // 修改时间（RFC3339 格式）
+	Archive  bool    `json:"archive,omitempty"`  // 是否为可浏览的压缩包（通过 path!/ 访问包内内容）
+	Width    int     `json:"width,omitempty"`    // 图片/视频显示宽度（仅 withMeta=image 或 media 时返回）
+	Height   int     `json:"height,omitempty"`   // 图片/视频显示高度（仅 withMeta=image 或 media 时返回）
+	Duration float64 `json:"duration,omitempty"` // 音视频时长，单位秒（仅 withMeta=media 时返回）
+	Codec    string  `json:"codec,omitempty"`    // 音视频编码，有视频时为视频编码（仅 withMeta=media 时返回）
+
+	MatchedBy string `json:"matchedBy,omitempty"` // 搜索结果的匹配方式：name、pinyin 或 initials（仅搜索时返回）
+}
+
+// newFileEntry 根据文件信息构建 fileEntry，itemPath 为以 / 开头的相对路径
+func newFileEntry(itemPath string, info os.FileInfo) fileEntry {
+	item := fileEntry{
+		Name:     info.Name(),
+		Path:     itemPath,
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+	}
+	if info.IsDir() {
+		item.Type = "dir"
+	} else {
+		item.Type = "file"
+		item.Size = info.Size()
+		_, item.Archive = archiveFormatOf(info.Name())
+	}
+	return item
 }
 
 // previewResponse 文件预览响应
 type previewResponse struct {
-	Path     string `json:"path"`               // 相对路径
-	Name     string `json:"name"`               // 文件名
-	Content  string `json:"content"`            // 文件内容（文本）
-	Size     int64  `json:"size"`               // 文件总大小
-	Modified string `json:"modified"`           // 修改时间
-	Offset   int64  `json:"offset,omitempty"`   // 读取偏移量
-	Limit    int64  `json:"limit,omitempty"`    // 读取限制
-	HasMore  bool   `json:"hasMore"`            // 是否还有更多内容
+	Path       string `json:"path"`                 // 相对路径
+	Name       string `json:"name"`                 // 文件名
+	Content    string `json:"content"`              // 文件内容（文本）
+	Size       int64  `json:"size"`                 // 文件总大小
+	Modified   string `json:"modified"`             // 修改时间
+	Offset     int64  `json:"offset,omitempty"`     // 读取偏移量
+	Limit      int64  `json:"limit,omitempty"`      // 读取限制
+	HasMore    bool   `json:"hasMore"`              // 是否还有更多内容
+	NextOffset int64  `json:"nextOffset"`           // 下一页起始偏移量（已对齐到字符/行边界）
+	FromLine   int64  `json:"fromLine,omitempty"`   // 行模式：起始行号（从 1 开始）
+	Lines      int64  `json:"lines,omitempty"`      // 行模式：实际返回的行数
+	TotalLines int64  `json:"totalLines,omitempty"` // 行模式：文件总行数
+	IsBinary   bool   `json:"isBinary"`             // 是否为二进制文件（二进制文件不返回文本内容）
+	MimeType   string `json:"mimeType"`             // 检测到的 MIME 类型
+
+	Compression    string `json:"compression,omitempty"`    // 透明解压的压缩格式：gzip、zstd、bzip2、xz
+	CompressedSize int64  `json:"compressedSize,omitempty"` // 压缩文件大小（透明解压时 size、offset 均为解压后的值）
+	SizeUnknown    bool   `json:"sizeUnknown,omitempty"`    // 透明解压且尚未解压到末尾，解压后的大小未知（size 为 0）
 }
 
 // errorResponse 错误响应
@@ -58,32 +98,86 @@ func statusFromErr(err error) int {
 	if errors.Is(err, os.ErrNotExist) {
 		return http.StatusNotFound // 404: 文件或目录不存在
 	}
+	if errors.Is(err, errArchiveTooLarge) || errors.Is(err, errEntryTooLarge) {
+		return http.StatusRequestEntityTooLarge // 413: 压缩包或条目超出限制
+	}
+	if errors.Is(err, errDecompress) {
+		return http.StatusUnprocessableEntity // 422: 压缩数据损坏
+	}
 	return http.StatusBadRequest // 400: 其他错误
 }
 
 // handleFiles 处理目录列表请求
 // GET /api/files?path=/some/path
-// 返回指定目录下的文件和子目录列表，按类型（目录优先）和名称排序
+// GET /api/files?path=/builds/app.zip!/config
+// GET /api/files?path=/photos&withMeta=image,media
+// 返回指定目录（或压缩包内目录）下的文件和子目录列表，按类型（目录优先）和名称排序；
+// withMeta=image 时为图片附带显示尺寸，便于前端按比例布局相册；
+// withMeta=media 时为音视频附带时长、编码和分辨率；gitignore=true 时隐藏被 .gitignore 等规则忽略的条目；
+// 根据 Accept 头返回 JSON（默认）、ls -l 风格的纯文本（text/plain）或 HTML 表格（text/html）
 func (s *Server) handleFiles(c *gin.Context) {
 	reqPath := c.Query("path")
+	items, absDir, ok := s.listEntries(c, reqPath)
+	if !ok {
+		return
+	}
+
+	if absDir != "" {
+		for _, kind := range strings.Split(c.Query("withMeta"), ",") {
+			switch strings.TrimSpace(kind) {
+			case "image":
+				addImageSizes(absDir, items)
+			case "media":
+				addMediaMeta(absDir, items)
+			}
+		}
+	}
+
+	c.Header("Vary", "Accept")
+	switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain, gin.MIMEHTML) {
+	case gin.MIMEPlain:
+		writeListingText(c, items)
+	case gin.MIMEHTML:
+		s.renderListing(c, reqPath, items)
+	default:
+		c.JSON(http.StatusOK, items)
+	}
+}
+
+// listEntries 列出目录（或压缩包内目录）的内容，按类型（目录优先）和名称排序
+// gitignore=true 时跳过被忽略的条目（压缩包内目录不适用）
+// 返回的 absDir 为目录的绝对路径，压缩包内目录为空；失败时已写入错误响应，返回 false
+func (s *Server) listEntries(c *gin.Context, reqPath string) ([]fileEntry, string, bool) {
+	// 压缩包内的虚拟目录
+	target, inArchive, err := s.resolveArchivePath(reqPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return nil, "", false
+	}
+	if inArchive {
+		items, ok := s.listArchiveFiles(c, target)
+		return items, "", ok
+	}
+
 	absPath, relPath, err := s.resolvePath(reqPath)
 	if err != nil {
 		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
-		return
+		return nil, "", false
 	}
 
 	// 读取目录内容
 	entries, err := os.ReadDir(absPath)
 	if err != nil {
 		abortWithError(c, statusFromErr(err), "READ_DIR_FAILED", err.Error())
-		return
+		return nil, "", false
 	}
 
-	// 构建文件列表，跳过符号链接（安全考虑）
+	// 构建文件列表，跳过符号链接（安全考虑）；gitignore=true 时跳过被忽略的条目
+	ignore := s.gitIgnore(c)
 	items := make([]fileEntry, 0, len(entries))
 	for _, entry := range entries {
 		// 跳过符号链接，防止符号链接攻击
-		if entry.Type()&os.ModeSymlink != 0 {
+		if entry.Type()&os.ModeSymlink != 0 || ignore.isIgnored(path.Join(relPath, entry.Name()), entry.IsDir()) {
 			continue
 		}
 
@@ -92,19 +186,7 @@ func (s *Server) handleFiles(c *gin.Context) {
 			continue
 		}
 
-		itemPath := path.Join("/", relPath, entry.Name())
-		item := fileEntry{
-			Name:     entry.Name(),
-			Path:     itemPath,
-			Modified: info.ModTime().UTC().Format(time.RFC3339),
-		}
-		if info.IsDir() {
-			item.Type = "dir"
-		} else {
-			item.Type = "file"
-			item.Size = info.Size()
-		}
-		items = append(items, item)
+		items = append(items, newFileEntry(path.Join("/", relPath, entry.Name()), info))
 	}
 
 	// 排序：目录优先，然后按名称（忽略大小写）排序
@@ -114,121 +196,426 @@ func (s *Server) handleFiles(c *gin.Context) {
 		}
 		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
 	})
+	return items, absPath, true
+}
 
-	c.JSON(http.StatusOK, items)
+// listArchiveFiles 列出压缩包内的目录内容，失败时已写入错误响应
+func (s *Server) listArchiveFiles(c *gin.Context, target archiveTarget) ([]fileEntry, bool) {
+	entries, err := s.listArchive(target)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "READ_ARCHIVE_FAILED", err.Error())
+		return nil, false
+	}
+	items, err := listArchiveDir(target, entries)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "READ_DIR_FAILED", err.Error())
+		return nil, false
+	}
+	return items, true
 }
 
 // handlePreview 处理文件预览请求
 // GET /api/preview?path=/file.txt&offset=0&limit=1024
-// 返回文件内容（文本），支持分页
+// GET /api/preview?path=/file.txt&fromLine=2000000&lines=200
+// GET /api/preview?path=/file.bin&mode=hex&offset=0&limit=4096
+// 返回文件内容（文本），支持按字节或按行分页；二进制文件可通过 mode=hex 获取十六进制转储
 func (s *Server) handlePreview(c *gin.Context) {
-	reqPath := c.Query("path")
-	absPath, relPath, err := s.resolvePath(reqPath)
+	src, ok := s.openPreviewSource(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	defer src.close()
+	info := src.info
+
+	// mode=hex 返回十六进制转储；传入 fromLine 或 lines 时按行号分页
+	hexMode := c.Query("mode") == "hex"
+	lineMode := !hexMode && (c.Query("fromLine") != "" || c.Query("lines") != "")
+
+	// 解析分页参数
+	var (
+		offset, limit, fromLine, lines int64
+		err                            error
+	)
+	switch {
+	case hexMode:
+		hexMax := int64(maxHexLimit)
+		if s.cfg.PreviewMax > 0 {
+			hexMax = min(hexMax, s.cfg.PreviewMax)
+		}
+		offset, limit, err = parseOffsetLimit(c, hexMax)
+		if limit == 0 {
+			limit = min(defaultHexLimit, hexMax)
+		}
+	case lineMode:
+		fromLine, lines, err = parseLineRange(c)
+	default:
+		offset, limit, err = parseOffsetLimit(c, s.cfg.PreviewMax)
+	}
 	if err != nil {
-		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		abortWithError(c, http.StatusBadRequest, "INVALID_RANGE", err.Error())
 		return
 	}
 
-	// 检查文件状态
-	info, err := os.Stat(absPath)
+	// 根据文件开头的内容判断是否为二进制
+	head, err := readSniffHead(src.reader)
 	if err != nil {
-		abortWithError(c, statusFromErr(err), "STAT_FAILED", err.Error())
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
 		return
 	}
-	if info.IsDir() {
-		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", "path is a directory")
+
+	// gzip/zstd/bzip2/xz 压缩的单个文件（如轮转后的 app.log.3.gz）透明解压，decompress=false 时查看原始字节
+	src, sniffName, head, ok := s.decompressRequested(c, src, head)
+	if !ok {
 		return
 	}
+	info = src.info
+	isBinary, mimeType := sniffContent(sniffName, head)
 
-	// 解析分页参数
-	offset, limit, err := parseOffsetLimit(c, s.cfg.PreviewMax)
+	if hexMode {
+		s.previewHex(c, src, offset, limit, isBinary, mimeType)
+		return
+	}
+
+	resp := previewResponse{
+		Path:     src.path,
+		Name:     info.Name(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+		IsBinary: isBinary,
+		MimeType: mimeType,
+
+		Compression:    src.compression,
+		CompressedSize: src.compressedSize,
+	}
+
+	// 二进制文件不返回文本内容，由前端改用 mode=hex 查看
+	if isBinary {
+		resp.Size, resp.SizeUnknown = contentSize(info)
+		resp.Offset = offset
+		resp.NextOffset = offset
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// 按行分页需要完整的行索引，透明解压时先解压到末尾；按字节分页只解压到所需位置
+	if lineMode {
+		if err = src.resolveSize(); err == nil {
+			err = s.readPreviewLines(src, fromLine, lines, &resp)
+		}
+	} else {
+		err = s.readPreviewBytes(src.reader, info.Size(), offset, limit, &resp)
+	}
+	if errors.Is(err, errDecompress) || errors.Is(err, errArchiveTooLarge) {
+		abortWithError(c, statusFromErr(err), "DECOMPRESS_FAILED", err.Error())
+		return
+	}
 	if err != nil {
-		abortWithError(c, http.StatusBadRequest, "INVALID_RANGE", err.Error())
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
 		return
 	}
 
-	// 打开文件
-	file, err := os.Open(absPath)
+	resp.Size, resp.SizeUnknown = contentSize(info)
+	c.JSON(http.StatusOK, resp)
+}
+
+// previewSource 预览内容的来源：普通文件或读入内存的压缩包条目
+type previewSource struct {
+	reader io.ReaderAt
+	info   os.FileInfo
+	path   string // 展示路径（以 / 开头）
+	key    string // 行索引缓存键
+	close  func() error
+
+	compression    string // 透明解压的压缩格式，未解压时为空
+	compressedSize int64  // 透明解压时压缩文件的大小
+}
+
+// openPreviewSource 打开请求路径对应的文件或压缩包条目
+// 失败时已写入错误响应，返回 false
+func (s *Server) openPreviewSource(c *gin.Context, reqPath string) (*previewSource, bool) {
+	target, inArchive, err := s.resolveArchivePath(reqPath)
 	if err != nil {
-		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to open file")
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return nil, false
+	}
+	if inArchive {
+		reader, entry, err := s.readArchiveEntry(target)
+		if errors.Is(err, errNotAFile) {
+			abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", err.Error())
+			return nil, false
+		}
+		if err != nil {
+			abortWithError(c, statusFromErr(err), "READ_ARCHIVE_FAILED", err.Error())
+			return nil, false
+		}
+		return &previewSource{
+			reader: reader,
+			info:   entry,
+			path:   target.displayPath(entry.name),
+			// 压缩包变化后缓存键随之变化，旧索引会被自然淘汰
+			key:   fmt.Sprintf("%s%s%s@%d", target.absPath, archiveSeparator, entry.name, target.info.ModTime().UnixNano()),
+			close: func() error { return nil },
+		}, true
+	}
+
+	absPath, relPath, err := s.resolvePath(reqPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return nil, false
+	}
+
+	// 只打开普通文件，FIFO 等特殊文件会阻塞读取
+	file, info, err := openRegularFile(absPath)
+	if errors.Is(err, errNotAFile) {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", err.Error())
+		return nil, false
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "STAT_FAILED", err.Error())
+		return nil, false
+	}
+
+	return &previewSource{
+		reader: file,
+		info:   info,
+		path:   path.Join("/", relPath),
+		key:    absPath,
+		close:  file.Close,
+	}, true
+}
+
+// previewHex 返回十六进制转储，offset 向下对齐到 hexRowSize 的整数倍
+// 内容大小未知（透明解压尚未解压到末尾）时读取到末尾才确定没有更多内容
+func (s *Server) previewHex(c *gin.Context, src *previewSource, offset, limit int64, isBinary bool, mimeType string) {
+	info := src.info
+	size := info.Size()
+	readLimit := limit
+	if size >= 0 {
+		offset = min(offset, size)
+	}
+	offset -= offset % hexRowSize
+	if size >= 0 {
+		readLimit = min(limit, size-offset)
+	}
+
+	data := make([]byte, readLimit)
+	n, err := src.reader.ReadAt(data, offset)
+	eof := errors.Is(err, io.EOF)
+	if errors.Is(err, errDecompress) || errors.Is(err, errArchiveTooLarge) {
+		abortWithError(c, statusFromErr(err), "DECOMPRESS_FAILED", err.Error())
 		return
 	}
-	defer file.Close()
+	if err != nil && !eof {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+		return
+	}
+	hasMore := !eof
+	if size >= 0 {
+		hasMore = offset+int64(n) < size
+	}
+
+	total, unknown := contentSize(info)
+	c.JSON(http.StatusOK, hexResponse{
+		Path:       src.path,
+		Name:       info.Name(),
+		Size:       total,
+		Modified:   info.ModTime().UTC().Format(time.RFC3339),
+		MimeType:   mimeType,
+		IsBinary:   isBinary,
+		Offset:     offset,
+		Limit:      readLimit,
+		NextOffset: offset + int64(n),
+		HasMore:    hasMore,
+		Rows:       hexDump(data[:n], offset),
+
+		Compression:    src.compression,
+		CompressedSize: src.compressedSize,
+		SizeUnknown:    unknown,
+	})
+}
+
+// readPreviewBytes 按字节范围读取预览内容
+// 起始位置回退到字符起点，末尾截掉不完整的多字节字符，保证内容不会拆开 UTF-8 字符；
+// size 为 -1 表示大小未知（透明解压尚未解压到末尾），此时读取到末尾才确定没有更多内容
+func (s *Server) readPreviewBytes(r io.ReaderAt, size, offset, limit int64, resp *previewResponse) error {
+	sizeKnown := size >= 0
 
 	// 计算实际读取范围
 	if offset < 0 {
 		offset = 0
 	}
-	if offset > info.Size() {
-		offset = info.Size()
+	if sizeKnown && offset > size {
+		offset = size
 	}
+	offset = alignRuneStart(r, offset)
 
 	readLimit := limit
 	if readLimit == 0 {
-		readLimit = info.Size()
+		readLimit = size
+		if !sizeKnown {
+			readLimit = defaultPreviewMax
+		}
 	}
 	// 限制最大预览大小
 	if s.cfg.PreviewMax > 0 {
 		readLimit = min(readLimit, s.cfg.PreviewMax)
 	}
 
-	remaining := info.Size() - offset
-	if readLimit > remaining {
-		readLimit = remaining
+	if sizeKnown {
+		readLimit = min(readLimit, size-offset)
 	}
 
 	// 读取文件内容
 	content := make([]byte, readLimit)
-	n, err := file.ReadAt(content, offset)
+	n, err := r.ReadAt(content, offset)
 	if err != nil && !errors.Is(err, io.EOF) {
-		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
-		return
+		return err
+	}
+	content = content[:n]
+	atEnd := errors.Is(err, io.EOF)
+	if sizeKnown {
+		atEnd = offset+int64(n) >= size
+	}
+
+	// 未读到文件末尾时，去掉被截断的最后一个字符
+	if !atEnd {
+		trimmed := trimIncompleteRune(content)
+		if len(trimmed) == 0 && n > 0 {
+			// limit 小于 offset 处字符的长度：读完这个字符，保证按 nextOffset 翻页时总能前进
+			head := make([]byte, utf8.UTFMax)
+			m, _ := r.ReadAt(head, offset)
+			_, runeLen := utf8.DecodeRune(head[:m])
+			trimmed = head[:runeLen]
+		}
+		content = trimmed
 	}
 
-	// 构建响应
-	resp := previewResponse{
-		Path:     path.Join("/", relPath),
-		Name:     info.Name(),
-		Content:  string(content[:n]),
-		Size:     info.Size(),
-		Modified: info.ModTime().UTC().Format(time.RFC3339),
-		Offset:   offset,
-		Limit:    readLimit,
-		HasMore:  offset+int64(n) < info.Size(),
+	resp.Content = string(content)
+	resp.Offset = offset
+	resp.Limit = readLimit
+	resp.NextOffset = offset + int64(len(content))
+	resp.HasMore = !atEnd
+	if sizeKnown {
+		resp.HasMore = resp.NextOffset < size
 	}
-
-	c.JSON(http.StatusOK, resp)
+	return nil
 }
 
-// handleImage 处理图片请求
-// GET /api/image?path=/image.png
-// 直接返回图片内容，支持 HTTP 缓存
-func (s *Server) handleImage(c *gin.Context) {
-	reqPath := c.Query("path")
-	absPath, _, err := s.resolvePath(reqPath)
+// readPreviewLines 按行读取预览内容
+// 借助行偏移索引直接定位到起始行，单次返回的内容不超过 PreviewMax
+func (s *Server) readPreviewLines(src *previewSource, fromLine, lines int64, resp *previewResponse) error {
+	idx, err := s.lines.get(src.key, src.reader, src.info)
 	if err != nil {
-		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
-		return
+		return err
 	}
+	defer idx.mu.Unlock()
 
-	info, err := os.Stat(absPath)
+	start, err := idx.lineOffset(src.reader, fromLine-1)
 	if err != nil {
-		abortWithError(c, statusFromErr(err), "STAT_FAILED", err.Error())
-		return
+		return err
+	}
+
+	var (
+		buf       bytes.Buffer
+		count     int64
+		pos       = start // 已读取内容的结束偏移
+		lineStart int     // 当前行在 buf 中的起始位置
+		linePos   = start // 当前行在文件中的起始偏移
+	)
+	reader := bufio.NewReaderSize(io.NewSectionReader(src.reader, start, idx.size-start), lineIndexBufferSize)
+	for count < lines {
+		chunk, err := reader.ReadSlice('\n')
+
+		// 超出预览上限：丢弃未读完的行；若一行都没读完，则按字符边界截断该行
+		if s.cfg.PreviewMax > 0 && int64(buf.Len()+len(chunk)) > s.cfg.PreviewMax {
+			if count > 0 {
+				buf.Truncate(lineStart)
+				pos = linePos
+			} else {
+				buf.Write(chunk[:s.cfg.PreviewMax-int64(buf.Len())])
+				kept := trimIncompleteRune(buf.Bytes())
+				buf.Truncate(len(kept))
+				pos = start + int64(buf.Len())
+			}
+			break
+		}
+
+		buf.Write(chunk)
+		pos += int64(len(chunk))
+		if err == nil {
+			count++
+			lineStart = buf.Len()
+			linePos = pos
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			// 文件末尾没有换行符的最后一行
+			if buf.Len() > lineStart {
+				count++
+			}
+			break
+		}
+		return err
 	}
-	if info.IsDir() {
-		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", "path is a directory")
-		return
+
+	resp.Content = buf.String()
+	resp.Offset = start
+	resp.NextOffset = pos
+	resp.HasMore = pos < idx.size
+	resp.FromLine = fromLine
+	resp.Lines = count
+	resp.TotalLines = idx.totalLines()
+	return nil
+}
+
+// alignRuneStart 若 offset 落在多字节字符中间，将其回退到该字符的起始位置
+func alignRuneStart(r io.ReaderAt, offset int64) int64 {
+	if offset <= 0 {
+		return offset
 	}
+	start := max(offset-(utf8.UTFMax-1), 0)
+	buf := make([]byte, 2*utf8.UTFMax)
+	n, _ := r.ReadAt(buf, start)
+	buf = buf[:n]
 
-	file, err := os.Open(absPath)
-	if err != nil {
-		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to open file")
-		return
+	at := int(offset - start)
+	if at >= len(buf) || utf8.RuneStart(buf[at]) {
+		return offset
 	}
-	defer file.Close()
+	for i := at - 1; i >= 0; i-- {
+		if !utf8.RuneStart(buf[i]) {
+			continue
+		}
+		// 仅当该字符是合法的多字节字符且跨越 offset 时才回退
+		r, size := utf8.DecodeRune(buf[i:])
+		if r != utf8.RuneError && i+size > at {
+			return start + int64(i)
+		}
+		break
+	}
+	return offset
+}
 
-	httpServeContent(c, info.Name(), info.ModTime(), file)
+// trimIncompleteRune 去掉末尾不完整的多字节字符
+func trimIncompleteRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			return data
+		}
+	}
+	return data
+}
+
+// handleImage 处理图片请求
+// GET /api/image?path=/image.png
+// 直接返回图片内容，支持 HTTP 缓存
+func (s *Server) handleImage(c *gin.Context) {
+	s.serveFile(c, c.Query("path"), false)
 }
 
 // handleHealth 健康检查端点
@@ -241,7 +628,21 @@ func (s *Server) handleHealth(c *gin.Context) {
 // GET /api/download?path=/file.txt
 // 设置 Content-Disposition 头，触发浏览器下载
 func (s *Server) handleDownload(c *gin.Context) {
-	reqPath := c.Query("path")
+	s.serveFile(c, c.Query("path"), true)
+}
+
+// serveFile 返回文件或压缩包条目的原始内容，attachment 为 true 时触发浏览器下载
+func (s *Server) serveFile(c *gin.Context, reqPath string, attachment bool) {
+	target, inArchive, err := s.resolveArchivePath(reqPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+	if inArchive {
+		s.serveArchiveEntry(c, target, attachment)
+		return
+	}
+
 	absPath, _, err := s.resolvePath(reqPath)
 	if err != nil {
 		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
@@ -266,10 +667,46 @@ func (s *Server) handleDownload(c *gin.Context) {
 	defer file.Close()
 
 	// 设置下载头，触发浏览器下载行为
-	c.Header("Content-Disposition", "attachment; filename=\""+info.Name()+"\"")
+	if attachment {
+		c.Header("Content-Disposition", "attachment; filename=\""+info.Name()+"\"")
+	}
 	httpServeContent(c, info.Name(), info.ModTime(), file)
 }
 
+// serveArchiveEntry 返回压缩包条目的原始内容
+// 不超过 archiveMaxBuffered 的条目读入内存以支持 Range 请求，更大的条目直接流式输出
+func (s *Server) serveArchiveEntry(c *gin.Context, target archiveTarget, attachment bool) {
+	rc, entry, err := s.openArchiveEntry(target)
+	if errors.Is(err, errNotAFile) {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", err.Error())
+		return
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "READ_ARCHIVE_FAILED", err.Error())
+		return
+	}
+	defer rc.Close()
+
+	if attachment {
+		c.Header("Content-Disposition", "attachment; filename=\""+entry.Name()+"\"")
+	}
+	if entry.size > archiveMaxBuffered {
+		contentType := mime.TypeByExtension(path.Ext(entry.name))
+		if contentType == "" {
+			contentType = genericMediaType
+		}
+		c.DataFromReader(http.StatusOK, entry.size, contentType, rc, nil)
+		return
+	}
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read archive entry")
+		return
+	}
+	httpServeContent(c, entry.Name(), entry.modTime, bytes.NewReader(data))
+}
+
 // parseOffsetLimit 解析分页参数
 // 返回 offset 和 limit，并进行边界检查
 func parseOffsetLimit(c *gin.Context, maxLimit int64) (int64, int64, error) {
@@ -306,17 +743,96 @@ func parseOffsetLimit(c *gin.Context, maxLimit int64) (int64, int64, error) {
 	return offset, limit, nil
 }
 
+// parseLineRange 解析行模式分页参数
+// fromLine 从 1 开始，默认 1；lines 默认 defaultPreviewLines，最大 maxPreviewLines
+func parseLineRange(c *gin.Context) (int64, int64, error) {
+	fromLine := int64(1)
+	lines := int64(defaultPreviewLines)
+	var err error
+
+	if v := c.Query("fromLine"); v != "" {
+		fromLine, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, 0, errors.New("invalid fromLine")
+		}
+	}
+	if v := c.Query("lines"); v != "" {
+		lines, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, 0, errors.New("invalid lines")
+		}
+	}
+
+	// 参数校验
+	if fromLine < 1 {
+		return 0, 0, errors.New("fromLine must be >= 1")
+	}
+	if lines < 1 {
+		return 0, 0, errors.New("lines must be >= 1")
+	}
+	lines = min(lines, maxPreviewLines)
+
+	return fromLine, lines, nil
+}
+
 // handleSearch 处理搜索请求
 // GET /api/search?path=/&q=keyword&recursive=true
-// 在指定目录下搜索包含关键词的文件/目录
+// GET /api/search?path=/&q=**/*.yaml&mode=glob&recursive=true
+// GET /api/search?path=/&q=hndlrtst&mode=fuzzy&recursive=true
+// GET /api/search?path=/&q=keyword&mode=content
+// GET /api/search?path=/&ext=zip&minSize=1GB&modifiedAfter=2026-10-12&recursive=true
+// 在指定目录下搜索文件名匹配的文件/目录：mode 为 substring（默认）、exact、glob、regex 或 fuzzy（按得分排序，见 handleFuzzySearch），
+// caseSensitive=true（或 ignoreCase=false）区分大小写，wholeWord=true 按整词匹配；mode=content 时搜索文件内容；
+// 可按类型、扩展名、大小、修改时间和深度过滤（见 parseSearchFilter），有过滤条件时 q 可以为空；
+// gitignore=true 时跳过 .gitignore、.git/info/exclude 和全局忽略文件忽略的条目（以及 .git 目录）；
+// 结果按目录遍历顺序排列，每页最多 limit 个（默认 100，最多 1000），truncated 时使用返回的 cursor 获取下一页；
+// 文件名搜索可使用 stream=ndjson|sse 边遍历边返回结果（见 streamSearch）
 func (s *Server) handleSearch(c *gin.Context) {
 	reqPath := c.Query("path")
 	query := strings.TrimSpace(c.Query("q"))
 	recursive := c.Query("recursive") == "true"
 
-	// 空查询返回空结果
-	if query == "" {
-		c.JSON(http.StatusOK, []fileEntry{})
+	filter, err := parseSearchFilter(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+	after, limit, err := parseSearchPage(c)
+	if errors.Is(err, errInvalidCursor) {
+		abortWithError(c, http.StatusBadRequest, "INVALID_CURSOR", err.Error())
+		return
+	}
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+	stream, err := parseSearchStream(c)
+	mode := c.Query("mode")
+	if err == nil && stream != "" && (mode == "content" || mode == nameModeFuzzy) {
+		err = fmt.Errorf("stream is not supported for %s search", mode)
+	}
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+
+	// 空查询返回空结果；设置了过滤条件时按条件列出所有条目
+	if query == "" && (mode == "content" || !filter.active()) {
+		if stream != "" {
+			newSearchStream(c, stream).send("done", searchStreamDone{})
+			return
+		}
+		c.JSON(http.StatusOK, searchResponse{Results: []fileEntry{}})
+		return
+	}
+	if mode == "content" {
+		s.handleContentSearch(c, query, filter, after, limit)
+		return
+	}
+	// 查询只编译一次，在遍历中复用
+	match, err := newNameQuery(query, mode, !queryIgnoreCase(c, true), c.Query("wholeWord") == "true", c.Query("pinyin") != "false")
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_QUERY", err.Error())
 		return
 	}
 
@@ -326,113 +842,180 @@ func (s *Server) handleSearch(c *gin.Context) {
 		return
 	}
 
+	search := &nameSearch{absPath: absPath, relPath: relPath, match: match, filter: filter, ignore: s.gitIgnore(c), after: after, limit: limit}
+	if match.fuzzy != nil {
+		s.handleFuzzySearch(c, search, recursive)
+		return
+	}
+	if stream != "" {
+		s.streamSearch(c, search, recursive, stream)
+		return
+	}
 	var results []fileEntry
-	queryLower := strings.ToLower(query) // 不区分大小写搜索
+	var scanned int
 
-	// 根据参数选择搜索模式
+	// 根据参数选择搜索模式，递归搜索优先使用文件名索引
 	if recursive {
-		results = s.searchRecursive(absPath, relPath, queryLower, 100)
+		var ok bool
+		if results, scanned, ok = s.names.search(search); !ok {
+			results, scanned = s.searchRecursive(c.Request.Context(), search)
+		}
 	} else {
-		results = s.searchDir(absPath, relPath, queryLower)
+		results, scanned = s.searchDir(search)
+	}
+	if c.Request.Context().Err() != nil {
+		c.Abort() // 客户端已断开
+		return
 	}
 
-	// 排序：目录优先，然后按名称排序
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Type != results[j].Type {
-			return results[i].Type == "dir"
-		}
-		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
-	})
+	resp := searchResponse{}
+	resp.Results, resp.searchPage = newSearchPage(results, limit, scanned, search.relOf)
+	if resp.Results == nil {
+		resp.Results = []fileEntry{}
+	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	c.JSON(http.StatusOK, results)
+// nameSearch 一次文件名搜索的参数
+// 各种搜索方式都按目录遍历顺序返回游标 after 之后最多 limit+1 个结果，多出的一个用于判断是否还有下一页
+type nameSearch struct {
+	absPath string // 搜索的目录
+	relPath string // 目录相对于根目录的路径
+	match   *nameQuery
+	filter  *searchFilter
+	ignore  *ignoreMatcher // gitignore=true 时按忽略规则跳过条目，否则为 nil
+	after   string         // 游标：上一页最后一个结果相对搜索目录的路径
+	limit   int
 }
 
-// searchDir 在单个目录下搜索（非递归）
-func (s *Server) searchDir(absPath, relPath, queryLower string) []fileEntry {
-	entries, err := os.ReadDir(absPath)
+// relOf 返回结果相对搜索目录的路径
+func (q *nameSearch) relOf(item fileEntry) string {
+	return relToSearchDir(q.relPath, item.Path)
+}
+
+// searchDir 在单个目录下搜索（非递归），返回结果和检查的条目数
+func (s *Server) searchDir(q *nameSearch) ([]fileEntry, int) {
+	var results []fileEntry
+	scanned := s.readDirSearch(q, func(item fileEntry) bool {
+		results = append(results, item)
+		return len(results) <= q.limit // 已多找到一个结果，可以确定还有下一页
+	})
+	return results, scanned
+}
+
+// readDirSearch 按名称顺序检查单个目录下游标之后的条目，把匹配的条目交给 emit，emit 返回 false 时停止
+// 返回检查的条目数
+func (s *Server) readDirSearch(q *nameSearch, emit func(fileEntry) bool) int {
+	entries, err := os.ReadDir(q.absPath)
 	if err != nil {
-		return nil
+		return 0
 	}
 
-	var results []fileEntry
+	scanned := 0
 	for _, entry := range entries {
-		// 跳过符号链接
-		if entry.Type()&os.ModeSymlink != 0 {
+		// 跳过符号链接、被忽略的条目和游标之前的条目（ReadDir 按名称排序）
+		if entry.Type()&os.ModeSymlink != 0 || q.ignore.isIgnored(path.Join(q.relPath, entry.Name()), entry.IsDir()) ||
+			(q.after != "" && !walkOrderLess(q.after, entry.Name())) {
 			continue
 		}
+		scanned++
 
-		// 匹配文件名（不区分大小写）
-		nameLower := strings.ToLower(entry.Name())
-		if !strings.Contains(nameLower, queryLower) {
+		// 匹配文件名（glob 含 / 时匹配相对路径，单层目录下即文件名）
+		matchedBy := q.match.matchedBy(entry.Name(), entry.Name(), nil)
+		if matchedBy == "" {
 			continue
 		}
 
 		info, err := entry.Info()
-		if err != nil {
+		if err != nil || !q.filter.matches(entry.Name(), info.IsDir(), info.Size(), info.ModTime()) {
 			continue
 		}
 
-		itemPath := path.Join("/", relPath, entry.Name())
-		item := fileEntry{
-			Name:     entry.Name(),
-			Path:     itemPath,
-			Modified: info.ModTime().UTC().Format(time.RFC3339),
-		}
-		if info.IsDir() {
-			item.Type = "dir"
-		} else {
-			item.Type = "file"
-			item.Size = info.Size()
+		item := newFileEntry(path.Join("/", q.relPath, entry.Name()), info)
+		item.MatchedBy = matchedBy
+		if !emit(item) {
+			break
 		}
-		results = append(results, item)
 	}
 
-	return results
+	return scanned
 }
 
-// searchRecursive 递归搜索目录树
-// maxResults 限制最大结果数量，防止性能问题
-func (s *Server) searchRecursive(absPath, relPath, queryLower string, maxResults int) []fileEntry {
+// searchRecursive 递归搜索目录树，返回结果和检查的条目数
+// 找到 limit+1 个结果后停止遍历
+func (s *Server) searchRecursive(ctx context.Context, q *nameSearch) ([]fileEntry, int) {
 	var results []fileEntry
+	scanned := s.walkSearch(ctx, q, func(item fileEntry) bool {
+		results = append(results, item)
+		return len(results) <= q.limit // 已多找到一个结果，可以确定还有下一页
+	}, nil)
+	return results, scanned
+}
+
+// walkSearch 递归遍历目录树，按遍历顺序把游标之后匹配的条目交给 emit，emit 返回 false 时停止遍历
+// 每向下遍历一个目录（包括搜索目录本身）调用一次 onDir（可为 nil），参数为已检查的条目数；
+// ctx 取消后立即停止遍历。返回检查的条目数
+func (s *Server) walkSearch(ctx context.Context, q *nameSearch, emit func(fileEntry) bool, onDir func(scanned int)) int {
+	scanned := 0
 
-	filepath.WalkDir(absPath, func(walkPath string, d os.DirEntry, err error) error {
+	filepath.WalkDir(q.absPath, func(walkPath string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll // 客户端已断开
+		}
 		if err != nil {
 			return nil // 忽略错误继续
 		}
 
-		// 跳过符号链接
+		// 跳过符号链接和搜索目录本身
 		if d.Type()&os.ModeSymlink != 0 {
 			return nil
 		}
+		if walkPath == q.absPath {
+			if onDir != nil {
+				onDir(scanned)
+			}
+			return nil
+		}
 
-		// 达到最大结果数，停止搜索
-		if len(results) >= maxResults {
-			return filepath.SkipAll
+		itemRel := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(walkPath, q.absPath)), "/")
+		// 被忽略的目录不再向下搜索
+		if q.ignore.isIgnored(path.Join(q.relPath, itemRel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		skip, err := skipBeforeCursor(itemRel, d.IsDir(), q.after)
+		if err != nil {
+			return err
 		}
 
-		// 匹配文件名
-		nameLower := strings.ToLower(d.Name())
-		if strings.Contains(nameLower, queryLower) {
-			info, err := d.Info()
-			if err == nil {
-				relItemPath := filepath.Join(relPath, strings.TrimPrefix(walkPath, absPath))
-				item := fileEntry{
-					Name:     d.Name(),
-					Path:     path.Join("/", filepath.ToSlash(relItemPath)),
-					Modified: info.ModTime().UTC().Format(time.RFC3339),
+		// 匹配文件名，glob 含 / 时匹配相对搜索目录的路径
+		if !skip {
+			scanned++
+			if matchedBy := q.match.matchedBy(d.Name(), itemRel, nil); matchedBy != "" {
+				info, err := d.Info()
+				if err == nil && q.filter.matches(d.Name(), info.IsDir(), info.Size(), info.ModTime()) {
+					item := newFileEntry(path.Join("/", q.relPath, itemRel), info)
+					item.MatchedBy = matchedBy
+					if !emit(item) {
+						return filepath.SkipAll
+					}
 				}
-				if d.IsDir() {
-					item.Type = "dir"
-				} else {
-					item.Type = "file"
-					item.Size = info.Size()
-				}
-				results = append(results, item)
 			}
 		}
 
+		if d.IsDir() {
+			// 达到深度限制的目录不再向下搜索
+			if !q.filter.descend(strings.Count(itemRel, "/") + 1) {
+				return filepath.SkipDir
+			}
+			if onDir != nil {
+				onDir(scanned)
+			}
+		}
 		return nil
 	})
 
-	return results
+	return scanned
 }
@@ -1,11 +1,16 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -137,6 +142,120 @@ func (s *HandlerTestSuite) TestHandlePreview_NegativeOffset() {
 	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
 }
 
+func (s *HandlerTestSuite) TestHandlePreview_RuneBoundary() {
+	// "你好" 每个字符 3 字节，limit=4 会截在第二个字符中间
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.tmpDir, "utf8.txt"), []byte("你好"), 0644))
+
+	w := s.makeRequest(http.MethodGet, "/api/preview?path=/utf8.txt&offset=0&limit=4")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var resp previewResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "你", resp.Content)
+	assert.Equal(s.T(), int64(3), resp.NextOffset)
+	assert.True(s.T(), resp.HasMore)
+
+	// 从字符中间开始时回退到字符起点
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/utf8.txt&offset=4")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "好", resp.Content)
+	assert.Equal(s.T(), int64(3), resp.Offset)
+	assert.Equal(s.T(), int64(6), resp.NextOffset)
+	assert.False(s.T(), resp.HasMore)
+}
+
+func (s *HandlerTestSuite) TestHandlePreview_LimitSmallerThanRune() {
+	// limit 小于一个字符的长度时返回完整的一个字符，按 nextOffset 翻页不会停在原地
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.tmpDir, "utf8.txt"), []byte("中文"), 0644))
+
+	for _, limit := range []int{1, 2} {
+		var contents []string
+		offset := int64(0)
+		for range 4 {
+			w := s.makeRequest(http.MethodGet, fmt.Sprintf("/api/preview?path=/utf8.txt&offset=%d&limit=%d", offset, limit))
+			require.Equal(s.T(), http.StatusOK, w.Code)
+			var resp previewResponse
+			require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.True(s.T(), utf8.ValidString(resp.Content))
+			contents = append(contents, resp.Content)
+			offset = resp.NextOffset
+			if !resp.HasMore {
+				break
+			}
+		}
+		assert.Equal(s.T(), []string{"中", "文"}, contents, "limit=%d", limit)
+		assert.Equal(s.T(), int64(6), offset)
+	}
+}
+
+func (s *HandlerTestSuite) TestHandlePreview_LineMode() {
+	var b strings.Builder
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&b, "row %d\n", i)
+	}
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.tmpDir, "lines.log"), []byte(b.String()), 0644))
+
+	w := s.makeRequest(http.MethodGet, "/api/preview?path=/lines.log&fromLine=2048&lines=2")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var resp previewResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "row 2048\nrow 2049\n", resp.Content)
+	assert.Equal(s.T(), int64(2048), resp.FromLine)
+	assert.Equal(s.T(), int64(2), resp.Lines)
+	assert.Equal(s.T(), int64(3000), resp.TotalLines)
+	assert.Equal(s.T(), resp.Offset+int64(len(resp.Content)), resp.NextOffset)
+	assert.True(s.T(), resp.HasMore)
+
+	// 超出总行数返回空内容
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/lines.log&fromLine=5000")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "", resp.Content)
+	assert.False(s.T(), resp.HasMore)
+}
+
+func (s *HandlerTestSuite) TestHandlePreview_InvalidLineRange() {
+	w := s.makeRequest(http.MethodGet, "/api/preview?path=/test.txt&fromLine=0")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/test.txt&lines=abc")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *HandlerTestSuite) TestHandlePreview_Binary() {
+	data := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 40)...)
+	require.NoError(s.T(), os.WriteFile(filepath.Join(s.tmpDir, "image.bin"), data, 0644))
+
+	// 文本模式只返回检测结果，不返回内容
+	w := s.makeRequest(http.MethodGet, "/api/preview?path=/image.bin")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var resp previewResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(s.T(), resp.IsBinary)
+	assert.Equal(s.T(), "image/png", resp.MimeType)
+	assert.Empty(s.T(), resp.Content)
+
+	// 十六进制模式，offset 向下对齐到行宽
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/image.bin&mode=hex&offset=20&limit=16")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var hex hexResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &hex))
+	assert.Equal(s.T(), int64(16), hex.Offset)
+	assert.Equal(s.T(), int64(32), hex.NextOffset)
+	assert.True(s.T(), hex.HasMore)
+	require.Len(s.T(), hex.Rows, 1)
+	assert.Equal(s.T(), int64(16), hex.Rows[0].Offset)
+	assert.Equal(s.T(), int64(16), hex.Limit)
+
+	// limit 超出文件剩余大小时返回实际读取的限制
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/image.bin&mode=hex&offset=32&limit=64")
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &hex))
+	assert.Equal(s.T(), int64(16), hex.Limit)
+	assert.Equal(s.T(), int64(48), hex.NextOffset)
+	assert.False(s.T(), hex.HasMore)
+
+	w = s.makeRequest(http.MethodGet, "/api/preview?path=/image.bin&mode=hex&limit=-1")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
 func (s *HandlerTestSuite) TestHandleDownload_File() {
 	w := s.makeRequest(http.MethodGet, "/api/download?path=/test.txt")
 
@@ -167,7 +286,7 @@ func (s *HandlerTestSuite) TestHandleSearch_EmptyQuery() {
 	w := s.makeRequest(http.MethodGet, "/api/search?path=/&q=")
 
 	assert.Equal(s.T(), http.StatusOK, w.Code)
-	assert.JSONEq(s.T(), `[]`, w.Body.String())
+	assert.JSONEq(s.T(), `{"results":[],"truncated":false,"scanned":0}`, w.Body.String())
 }
 
 func (s *HandlerTestSuite) TestHandleSearch_Recursive() {
@@ -176,6 +295,194 @@ func (s *HandlerTestSuite) TestHandleSearch_Recursive() {
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 }
 
+func (s *HandlerTestSuite) TestHandleSearch_Modes() {
+	dir := filepath.Join(s.tmpDir, "modes")
+	require.NoError(s.T(), os.MkdirAll(filepath.Join(dir, "config", "prod"), 0755))
+	for _, name := range []string{"config/app.yaml", "config/prod/db.YAML", "app.log", "catalog.txt"} {
+		require.NoError(s.T(), os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	defer os.RemoveAll(dir)
+
+	search := func(query string) []string {
+		w := s.makeRequest(http.MethodGet, "/api/search?path=/modes&"+query)
+		require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+		var resp searchResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+		var paths []string
+		for _, r := range resp.Results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+
+	assert.Equal(s.T(), []string{"/modes/config/app.yaml", "/modes/config/prod/db.YAML"}, search("q=**/*.yaml&mode=glob&recursive=true"))
+	assert.Equal(s.T(), []string{"/modes/config/app.yaml"}, search("q=**/*.yaml&mode=glob&recursive=true&caseSensitive=true"))
+	assert.Equal(s.T(), []string{"/modes/config/prod/db.YAML"}, search("q=config/*/*&mode=glob&recursive=true"))
+	assert.Equal(s.T(), []string{"/modes/app.log"}, search("q=*.log&mode=glob"))
+	assert.Equal(s.T(), []string{"/modes/config/prod"}, search("q=PROD&mode=exact&recursive=true"))
+	assert.Empty(s.T(), search("q=PROD&mode=exact&recursive=true&caseSensitive=true"))
+	assert.Equal(s.T(), []string{"/modes/app.log", "/modes/config/app.yaml"}, search("q=%5Eapp%5C.&mode=regex&recursive=true"))
+	assert.Equal(s.T(), []string{"/modes/app.log"}, search("q=log&wholeWord=true&recursive=true"))
+	assert.Len(s.T(), search("q=log&recursive=true"), 2)
+
+	w := s.makeRequest(http.MethodGet, "/api/search?path=/&q=(&mode=regex")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "INVALID_QUERY")
+	w = s.makeRequest(http.MethodGet, "/api/search?path=/&q=[a&mode=glob")
+	assert.Contains(s.T(), w.Body.String(), "INVALID_QUERY")
+	w = s.makeRequest(http.MethodGet, "/api/search?path=/&q=x&mode=unknown")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "INVALID_QUERY")
+}
+
+func (s *HandlerTestSuite) TestHandleSearch_Filters() {
+	dir := filepath.Join(s.tmpDir, "filters")
+	require.NoError(s.T(), os.MkdirAll(filepath.Join(dir, "builds", "old"), 0755))
+	files := map[string]int{
+		"builds/app-1.0.zip":     2048,
+		"builds/app-1.1.ZIP":     10,
+		"builds/old/app-0.9.zip": 4096,
+		"builds/app.tar.gz":      3000,
+		"notes.txt":              5,
+	}
+	lastWeek := time.Date(2026, 10, 5, 12, 0, 0, 0, time.UTC)
+	for name, size := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(s.T(), os.WriteFile(p, make([]byte, size), 0644))
+		if strings.Contains(name, "old") {
+			require.NoError(s.T(), os.Chtimes(p, lastWeek, lastWeek))
+		}
+	}
+	defer os.RemoveAll(dir)
+
+	search := func(query string) []string {
+		w := s.makeRequest(http.MethodGet, "/api/search?path=/filters&recursive=true&"+query)
+		require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+		var resp searchResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+		var paths []string
+		for _, r := range resp.Results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+
+	// 有过滤条件时 q 可以为空
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/app-1.0.zip", "/filters/builds/app-1.1.ZIP", "/filters/builds/old/app-0.9.zip"}, search("ext=zip"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/app-1.0.zip", "/filters/builds/old/app-0.9.zip"}, search("ext=zip&minSize=2KB"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/app-1.1.ZIP", "/filters/notes.txt"}, search("maxSize=1KB"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/app.tar.gz"}, search("ext=.tar.gz,rar"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/old/app-0.9.zip"}, search("q=app&modifiedBefore=2026-10-06"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds/old/app-0.9.zip"}, search("modifiedAfter=2026-10-05T00:00:00Z&modifiedBefore=2026-10-05T13:00:00Z"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds", "/filters/builds/old"}, search("type=dir"))
+	assert.ElementsMatch(s.T(), []string{"/filters/builds", "/filters/notes.txt"}, search("maxDepth=1"))
+	assert.Len(s.T(), search("type=file&maxDepth=2"), 4)
+	// 空查询按整词匹配时不会卡住
+	assert.Len(s.T(), search("type=file&maxDepth=2&wholeWord=true"), 4)
+	assert.ElementsMatch(s.T(), []string{"/filters/notes.txt"}, search("q=notes&type=file"))
+
+	// 分页：逐页获取的结果与一次获取的结果一致
+	var paged []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(s.T(), pages, 10)
+		w := s.makeRequest(http.MethodGet, "/api/search?path=/filters&recursive=true&type=file&limit=2&cursor="+cursor)
+		var resp searchResponse
+		require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.LessOrEqual(s.T(), len(resp.Results), 2)
+		for _, r := range resp.Results {
+			paged = append(paged, r.Path)
+		}
+		if !resp.Truncated {
+			assert.Empty(s.T(), resp.Cursor)
+			break
+		}
+		cursor = resp.Cursor
+	}
+	assert.Equal(s.T(), search("type=file&limit=1000"), paged)
+	nonRecursive := s.makeRequest(http.MethodGet, "/api/search?path=/filters/builds&q=app&limit=1")
+	var resp searchResponse
+	require.NoError(s.T(), json.Unmarshal(nonRecursive.Body.Bytes(), &resp))
+	assert.True(s.T(), resp.Truncated)
+	nonRecursive = s.makeRequest(http.MethodGet, "/api/search?path=/filters/builds&q=app&limit=1&cursor="+resp.Cursor)
+	require.NoError(s.T(), json.Unmarshal(nonRecursive.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "/filters/builds/app-1.1.ZIP", resp.Results[0].Path)
+
+	w := s.makeRequest(http.MethodGet, "/api/search?path=/filters&q=")
+	assert.JSONEq(s.T(), `{"results":[],"truncated":false,"scanned":0}`, w.Body.String())
+	w = s.makeRequest(http.MethodGet, "/api/search?path=/filters&q=app&cursor=%21%21")
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "INVALID_CURSOR")
+	for _, query := range []string{"type=link", "minSize=abc", "minSize=2MB&maxSize=1MB", "modifiedAfter=yesterday", "maxDepth=0", "limit=0"} {
+		w := s.makeRequest(http.MethodGet, "/api/search?path=/filters&"+query)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code, query)
+		assert.Contains(s.T(), w.Body.String(), "INVALID_PARAMS", query)
+	}
+}
+
+func (s *HandlerTestSuite) TestHandleFiles_AcceptText() {
+	req := httptest.NewRequest(http.MethodGet, "/api/files?path=/", nil)
+	req.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Header().Get("Content-Type"), "text/plain")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(s.T(), lines, 2)
+	assert.Regexp(s.T(), `^d +0 \d{4}-\d{2}-\d{2} \d{2}:\d{2} subdir/$`, lines[0])
+	assert.Regexp(s.T(), `^- +11 \d{4}-\d{2}-\d{2} \d{2}:\d{2} test\.txt$`, lines[1])
+}
+
+func (s *HandlerTestSuite) TestHandleFiles_AcceptHTML() {
+	req := httptest.NewRequest(http.MethodGet, "/api/files?path=/subdir", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(s.T(), w.Body.String(), `<a href="/raw/subdir/nested.txt">nested.txt</a>`)
+	assert.Contains(s.T(), w.Body.String(), `<a href="/browse/">../</a>`)
+}
+
+func (s *HandlerTestSuite) TestHandleBrowse_Directory() {
+	w := s.makeRequest(http.MethodGet, "/browse/")
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.NotContains(s.T(), body, "<script")
+	assert.Contains(s.T(), body, `<a href="/browse/subdir/">subdir/</a>`)
+	assert.Contains(s.T(), body, `<td class="size">11 B</td>`)
+	assert.NotContains(s.T(), body, "../")
+
+	w = s.makeRequest(http.MethodGet, "/browse/subdir")
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `<a href="/browse/subdir/">subdir</a>`)
+}
+
+func (s *HandlerTestSuite) TestHandleBrowse_File() {
+	w := s.makeRequest(http.MethodGet, "/browse/subdir/nested.txt")
+
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+	assert.Equal(s.T(), "/raw/subdir/nested.txt", w.Header().Get("Location"))
+}
+
+func (s *HandlerTestSuite) TestHandleBrowse_NonExistent() {
+	w := s.makeRequest(http.MethodGet, "/browse/missing/")
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/browse/../../etc/")
+	assert.NotEqual(s.T(), http.StatusOK, w.Code)
+}
+
+func (s *HandlerTestSuite) TestFormatSize() {
+	assert.Equal(s.T(), "0 B", formatSize(0))
+	assert.Equal(s.T(), "1023 B", formatSize(1023))
+	assert.Equal(s.T(), "1.5 KB", formatSize(1536))
+	assert.Equal(s.T(), "2.0 GB", formatSize(2<<30))
+}
+
 func (s *HandlerTestSuite) TestStatusFromErr() {
 	tests := []struct {
 		name     string
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errInvalidQuery 查询语法错误（JSONPath、JSON Pointer，以及文件名搜索的 glob 和正则表达式）
+var errInvalidQuery = errors.New("invalid query")
+
+// 路径段类型
+const (
+	segmentName     = iota // .name 或 ['name']
+	segmentIndex           // [n]
+	segmentWildcard        // .* 或 [*]
+	segmentSlice           // [start:end]
+)
+
+// pathSegment JSONPath 中的一段
+type pathSegment struct {
+	kind      int
+	name      string
+	index     int
+	start     int
+	end       int  // 切片结束位置（不含），-1 表示到末尾
+	recursive bool // 由 .. 引入，可匹配任意深度
+}
+
+// pathStep 值在文档中的一步位置：对象键或数组下标
+type pathStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// matches 判断路径段是否匹配一步位置
+func (seg pathSegment) matches(step pathStep) bool {
+	switch seg.kind {
+	case segmentName:
+		return step.isKey && step.key == seg.name
+	case segmentIndex:
+		return !step.isKey && step.index == seg.index
+	case segmentSlice:
+		return !step.isKey && step.index >= seg.start && (seg.end < 0 || step.index < seg.end)
+	}
+	return true // 通配符
+}
+
+// parseJSONPath 解析 JSONPath 子集：$、.name、['name']、[n]、[start:end]、*、..（递归下降）
+// 流式解析时无法预知数组长度，因此不支持负数下标
+func parseJSONPath(query string) ([]pathSegment, error) {
+	if !strings.HasPrefix(query, "$") {
+		return nil, fmt.Errorf("%w: JSONPath must start with $", errInvalidQuery)
+	}
+	var segments []pathSegment
+	rest := query[1:]
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] != '[':
+			return nil, fmt.Errorf("%w: unexpected %q", errInvalidQuery, rest[0])
+		}
+
+		var seg pathSegment
+		var err error
+		if strings.HasPrefix(rest, "[") {
+			seg, rest, err = parseBracketSegment(rest)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("%w: empty name", errInvalidQuery)
+			case "*":
+				seg.kind = segmentWildcard
+			default:
+				seg = pathSegment{kind: segmentName, name: name}
+			}
+		}
+		seg.recursive = recursive
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// parseBracketSegment 解析 [...] 形式的路径段，返回路径段和剩余部分
+func parseBracketSegment(s string) (pathSegment, string, error) {
+	// 带引号的名称中可能含有 ]
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		quote := s[1]
+		end := strings.IndexByte(s[2:], quote)
+		if end < 0 || !strings.HasPrefix(s[2+end+1:], "]") {
+			return pathSegment{}, "", fmt.Errorf("%w: unterminated name", errInvalidQuery)
+		}
+		return pathSegment{kind: segmentName, name: s[2 : 2+end]}, s[2+end+2:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, "", fmt.Errorf("%w: missing ]", errInvalidQuery)
+	}
+	inner, rest := strings.TrimSpace(s[1:end]), s[end+1:]
+	if inner == "*" {
+		return pathSegment{kind: segmentWildcard}, rest, nil
+	}
+	if from, to, ok := strings.Cut(inner, ":"); ok {
+		seg := pathSegment{kind: segmentSlice, end: -1}
+		var err error
+		if from != "" {
+			if seg.start, err = strconv.Atoi(from); err != nil || seg.start < 0 {
+				return pathSegment{}, "", fmt.Errorf("%w: invalid slice %q", errInvalidQuery, inner)
+			}
+		}
+		if to != "" {
+			if seg.end, err = strconv.Atoi(to); err != nil || seg.end < 0 {
+				return pathSegment{}, "", fmt.Errorf("%w: invalid slice %q", errInvalidQuery, inner)
+			}
+		}
+		return seg, rest, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return pathSegment{}, "", fmt.Errorf("%w: invalid index %q", errInvalidQuery, inner)
+	}
+	return pathSegment{kind: segmentIndex, index: index}, rest, nil
+}
+
+// matchPath 判断位置 steps 是否完全匹配路径
+func matchPath(segments []pathSegment, steps []pathStep) bool {
+	if len(segments) == 0 {
+		return len(steps) == 0
+	}
+	seg := segments[0]
+	if seg.recursive {
+		for i := range steps {
+			if seg.matches(steps[i]) && matchPath(segments[1:], steps[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(steps) > 0 && seg.matches(steps[0]) && matchPath(segments[1:], steps[1:])
+}
+
+// prefixMatch 判断 steps 之下是否可能存在匹配的位置，用于跳过无关的子树
+func prefixMatch(segments []pathSegment, steps []pathStep) bool {
+	if len(steps) == 0 {
+		return true
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if segments[0].recursive {
+		return true
+	}
+	return segments[0].matches(steps[0]) && prefixMatch(segments[1:], steps[1:])
+}
+
+// parseJSONPointer 解析 RFC 6901 JSON Pointer，空字符串表示根节点
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: JSON Pointer must start with /", errInvalidQuery)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// escapePointerToken 按 RFC 6901 转义 JSON Pointer 中的一段
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// stepsPointer 将位置转换为 JSON Pointer
+func stepsPointer(steps []pathStep) string {
+	var b strings.Builder
+	for _, step := range steps {
+		b.WriteByte('/')
+		if step.isKey {
+			b.WriteString(escapePointerToken(step.key))
+		} else {
+			b.WriteString(strconv.Itoa(step.index))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	lineIndexStride     = 1024 // 每隔多少行记录一个检查点
+	lineIndexCacheSize  = 64   // 最多缓存的文件索引数量
+	lineIndexBufferSize = 64 * 1024
+)
+
+// lineIndex 单个文件的行偏移索引
+// 只记录每 lineIndexStride 行的起始偏移（稀疏检查点），
+// 定位任意行时从最近的检查点向后扫描，内存占用约为 行数/1024*8 字节
+type lineIndex struct {
+	mu          sync.Mutex
+	size        int64     // 已索引的文件大小
+	modTime     time.Time // 已索引时的修改时间
+	checkpoints []int64   // checkpoints[i] 为第 i*lineIndexStride 行（从 0 开始）的起始偏移
+	newlines    int64     // 已扫描到的换行符数量
+	lastByte    byte      // 已扫描的最后一个字节
+}
+
+// totalLines 返回文件总行数，末尾没有换行符的最后一行也计入
+func (idx *lineIndex) totalLines() int64 {
+	if idx.size > 0 && idx.lastByte != '\n' {
+		return idx.newlines + 1
+	}
+	return idx.newlines
+}
+
+// update 使索引与文件当前状态一致
+// 文件变大时从上次扫描位置继续（适用于追加写入的日志），变小或被改写时重建
+func (idx *lineIndex) update(r io.ReaderAt, info os.FileInfo) error {
+	size := info.Size()
+	switch {
+	case size == idx.size && info.ModTime().Equal(idx.modTime):
+		return nil
+	case size < idx.size || (size == idx.size && !info.ModTime().Equal(idx.modTime)):
+		idx.reset()
+	}
+
+	reader := bufio.NewReaderSize(io.NewSectionReader(r, idx.size, size-idx.size), lineIndexBufferSize)
+	buf := make([]byte, lineIndexBufferSize)
+	pos := idx.size
+	for {
+		n, err := reader.Read(buf)
+		for i := 0; i < n; i++ {
+			if buf[i] != '\n' {
+				continue
+			}
+			idx.newlines++
+			if idx.newlines%lineIndexStride == 0 {
+				idx.checkpoints = append(idx.checkpoints, pos+int64(i)+1)
+			}
+		}
+		if n > 0 {
+			pos += int64(n)
+			idx.lastByte = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			idx.reset()
+			return err
+		}
+	}
+
+	idx.size = size
+	idx.modTime = info.ModTime()
+	return nil
+}
+
+// reset 清空索引
+func (idx *lineIndex) reset() {
+	idx.size = 0
+	idx.modTime = time.Time{}
+	idx.checkpoints = []int64{0}
+	idx.newlines = 0
+	idx.lastByte = 0
+}
+
+// lineOffset 返回第 line 行（从 0 开始）的起始字节偏移
+// 超出总行数时返回文件大小
+func (idx *lineIndex) lineOffset(r io.ReaderAt, line int64) (int64, error) {
+	if line <= 0 {
+		return 0, nil
+	}
+	if line >= idx.totalLines() {
+		return idx.size, nil
+	}
+
+	// 从最近的检查点开始，向后跳过剩余的行
+	start := idx.checkpoints[line/lineIndexStride]
+	skip := line % lineIndexStride
+	if skip == 0 {
+		return start, nil
+	}
+
+	reader := bufio.NewReaderSize(io.NewSectionReader(r, start, idx.size-start), lineIndexBufferSize)
+	pos := start
+	for skip > 0 {
+		chunk, err := reader.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if err == nil {
+			skip--
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		return 0, err
+	}
+	return pos, nil
+}
+
+// lineNumber 返回 offset 所在行的行号（从 0 开始）
+// offset 超出文件大小时按文件末尾计算
+func (idx *lineIndex) lineNumber(r io.ReaderAt, offset int64) (int64, error) {
+	offset = min(max(offset, 0), idx.size)
+	k := sort.Search(len(idx.checkpoints), func(i int) bool { return idx.checkpoints[i] > offset }) - 1
+	line := int64(k) * lineIndexStride
+
+	// 统计检查点到 offset 之间的换行符
+	start := idx.checkpoints[k]
+	reader := bufio.NewReaderSize(io.NewSectionReader(r, start, offset-start), lineIndexBufferSize)
+	buf := make([]byte, lineIndexBufferSize)
+	for {
+		n, err := reader.Read(buf)
+		line += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if errors.Is(err, io.EOF) {
+			return line, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// lineIndexCache 按路径缓存行索引，超出容量时淘汰最久未使用的条目
+type lineIndexCache struct {
+	lru *lruCache[string, *lineIndex] // 缓存键（绝对路径）-> 行索引
+}
+
+// newLineIndexCache 创建行索引缓存
+func newLineIndexCache(max int) *lineIndexCache {
+	return &lineIndexCache{lru: newLRUCache[string, *lineIndex](int64(max), nil)}
+}
+
+// get 返回 key 对应的行索引，并确保其与文件当前状态一致
+// 返回的索引已加锁，调用方使用完毕后需调用 index.mu.Unlock()
+func (c *lineIndexCache) get(key string, r io.ReaderAt, info os.FileInfo) (*lineIndex, error) {
+	idx := c.lru.getOrAdd(key, func() *lineIndex {
+		idx := &lineIndex{}
+		idx.reset()
+		return idx
+	})
+
+	idx.mu.Lock()
+	if err := idx.update(r, info); err != nil {
+		idx.mu.Unlock()
+		return nil, err
+	}
+	return idx, nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLines 写入 n 行形如 "line-<i>\n" 的测试文件
+func writeLines(t *testing.T, path string, from, n int) {
+	t.Helper()
+	var b strings.Builder
+	for i := from; i < from+n; i++ {
+		fmt.Fprintf(&b, "line-%d\n", i)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(b.String())
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// lookupLine 通过缓存获取索引并返回第 line 行（从 0 开始）的偏移
+func lookupLine(t *testing.T, cache *lineIndexCache, path string, line int64) (int64, int64) {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	require.NoError(t, err)
+
+	idx, err := cache.get(path, file, info)
+	require.NoError(t, err)
+	defer idx.mu.Unlock()
+
+	offset, err := idx.lineOffset(file, line)
+	require.NoError(t, err)
+	return offset, idx.totalLines()
+}
+
+func TestLineIndex_Offsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLines(t, path, 0, 5000)
+	cache := newLineIndexCache(4)
+
+	tests := []struct {
+		name string
+		line int64
+	}{
+		{"first line", 0},
+		{"before checkpoint", lineIndexStride - 1},
+		{"on checkpoint", lineIndexStride},
+		{"between checkpoints", 3*lineIndexStride + 17},
+		{"last line", 4999},
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, total := lookupLine(t, cache, path, tt.line)
+			assert.Equal(t, int64(5000), total)
+			assert.True(t, strings.HasPrefix(string(data[offset:]), fmt.Sprintf("line-%d\n", tt.line)))
+		})
+	}
+
+	offset, _ := lookupLine(t, cache, path, 9999)
+	assert.Equal(t, int64(len(data)), offset)
+}
+
+func TestLineIndex_AppendAndTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLines(t, path, 0, 10)
+	cache := newLineIndexCache(4)
+
+	_, total := lookupLine(t, cache, path, 0)
+	assert.Equal(t, int64(10), total)
+
+	// 追加写入后增量扩展索引
+	writeLines(t, path, 10, 2000)
+	offset, total := lookupLine(t, cache, path, 1500)
+	assert.Equal(t, int64(2010), total)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data[offset:]), "line-1500\n"))
+
+	// 截断后重建索引
+	require.NoError(t, os.WriteFile(path, []byte("a\nb"), 0644))
+	offset, total = lookupLine(t, cache, path, 1)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, int64(2), offset)
+}
+
+func TestLineIndexCache_Evicts(t *testing.T) {
+	dir := t.TempDir()
+	cache := newLineIndexCache(2)
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.log", i))
+		writeLines(t, path, 0, 1)
+		lookupLine(t, cache, path, 0)
+	}
+
+	assert.Len(t, cache.lru.entries, 2)
+	assert.NotContains(t, cache.lru.entries, filepath.Join(dir, "0.log"))
+}
+
+func TestTrimIncompleteRune(t *testing.T) {
+	full := []byte("ab中")
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"ascii", []byte("abc"), []byte("abc")},
+		{"complete multibyte", full, full},
+		{"cut after first byte", full[:3], []byte("ab")},
+		{"cut after second byte", full[:4], []byte("ab")},
+		{"invalid byte kept", []byte{'a', 0xff}, []byte{'a', 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, trimIncompleteRune(tt.input))
+		})
+	}
+}
+
+func TestAlignRuneStart(t *testing.T) {
+	data := strings.NewReader("ab中文")
+
+	assert.Equal(t, int64(0), alignRuneStart(data, 0))
+	assert.Equal(t, int64(2), alignRuneStart(data, 2))
+	assert.Equal(t, int64(2), alignRuneStart(data, 3))
+	assert.Equal(t, int64(2), alignRuneStart(data, 4))
+	assert.Equal(t, int64(5), alignRuneStart(data, 5))
+}
+
+func TestLineIndex_LineNumber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLines(t, path, 0, 3000)
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	info, err := file.Stat()
+	require.NoError(t, err)
+
+	idx, err := newLineIndexCache(1).get(path, file, info)
+	require.NoError(t, err)
+	defer idx.mu.Unlock()
+
+	for _, line := range []int64{0, 1, lineIndexStride - 1, lineIndexStride, 2*lineIndexStride + 5, 2999} {
+		offset, err := idx.lineOffset(file, line)
+		require.NoError(t, err)
+		got, err := idx.lineNumber(file, offset)
+		require.NoError(t, err)
+		assert.Equal(t, line, got, "line start %d", line)
+		// 行中间的偏移属于同一行
+		got, err = idx.lineNumber(file, offset+3)
+		require.NoError(t, err)
+		assert.Equal(t, line, got, "inside line %d", line)
+	}
+	got, err := idx.lineNumber(file, info.Size()+10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3000), got)
+}
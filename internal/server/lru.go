@@ -0,0 +1,150 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache 并发安全的 LRU 缓存，总权重超过上限时淘汰最久未使用的条目
+// 默认每个条目权重为 1（即按条目数限制），weight 不为空时按其返回值计算（如按字节数）
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	max     int64
+	total   int64               // 当前总权重
+	weight  func(V) int64       // 条目权重，为空时为 1
+	onEvict func(key K, val V)  // 条目被淘汰后调用（不持有锁），可为空
+	order   *list.List          // 最近使用的在前
+	entries map[K]*list.Element // 缓存键 -> order 中的元素
+}
+
+// lruEntry order 链表中的元素值
+type lruEntry[K comparable, V any] struct {
+	key    K
+	val    V
+	weight int64
+}
+
+// newLRUCache 创建 LRU 缓存，weight 为空时按条目数限制
+func newLRUCache[K comparable, V any](max int64, weight func(V) int64) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		max:     max,
+		weight:  weight,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// get 返回 key 对应的值，命中时标记为最近使用
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry[K, V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// getOrAdd 返回 key 对应的值，不存在时调用 create 创建并加入缓存
+// create 在持有锁时调用，应只做轻量的初始化
+func (c *lruCache[K, V]) getOrAdd(key K, create func() V) V {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*lruEntry[K, V]).val
+	}
+	val := create()
+	c.insert(key, val)
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return val
+}
+
+// add 加入或替换 key 对应的值，并标记为最近使用
+func (c *lruCache[K, V]) add(key K, val V) {
+	c.notify(c.push(key, val))
+}
+
+// push 加入或替换 key 对应的值，返回被淘汰的条目，由调用方在合适的时机调用 notify
+func (c *lruCache[K, V]) push(key K, val V) []*lruEntry[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(key, val)
+	return c.evict()
+}
+
+// contains 返回 key 是否在缓存中，不改变使用顺序
+func (c *lruCache[K, V]) contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+// remove 删除 key 对应的条目，不调用 onEvict
+func (c *lruCache[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.total -= elem.Value.(*lruEntry[K, V]).weight
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// purge 淘汰所有条目
+func (c *lruCache[K, V]) purge() {
+	c.mu.Lock()
+	max := c.max
+	c.max = -1
+	evicted := c.evict()
+	c.max = max
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// insert 加入或替换条目并移到最前，调用方需持有锁
+func (c *lruCache[K, V]) insert(key K, val V) {
+	w := int64(1)
+	if c.weight != nil {
+		w = c.weight(val)
+	}
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		c.total += w - entry.weight
+		entry.val, entry.weight = val, w
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, val: val, weight: w})
+	c.total += w
+}
+
+// evict 淘汰最久未使用的条目直到总权重不超过上限，返回被淘汰的条目，调用方需持有锁
+func (c *lruCache[K, V]) evict() []*lruEntry[K, V] {
+	var evicted []*lruEntry[K, V]
+	for c.total > c.max && c.order.Len() > 0 {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry[K, V])
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.total -= entry.weight
+		evicted = append(evicted, entry)
+	}
+	return evicted
+}
+
+// notify 对被淘汰的条目调用 onEvict，调用方不能持有锁
+func (c *lruCache[K, V]) notify(evicted []*lruEntry[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, entry := range evicted {
+		c.onEvict(entry.key, entry.val)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache_EvictsByCount(t *testing.T) {
+	cache := newLRUCache[string, int](2, nil)
+	var evicted []string
+	cache.onEvict = func(key string, _ int) { evicted = append(evicted, key) }
+
+	cache.add("a", 1)
+	cache.add("b", 2)
+	_, ok := cache.get("a") // a 变为最近使用
+	assert.True(t, ok)
+	assert.Equal(t, 3, cache.getOrAdd("c", func() int { return 3 }))
+	assert.Equal(t, []string{"b"}, evicted)
+
+	// 已存在时不调用 create
+	assert.Equal(t, 1, cache.getOrAdd("a", func() int { panic("unexpected create") }))
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+
+	// remove 不触发 onEvict
+	cache.remove("a")
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, int64(1), cache.total)
+}
+
+func TestLRUCache_EvictsByWeight(t *testing.T) {
+	cache := newLRUCache[string, int64](10, func(size int64) int64 { return size })
+	cache.add("a", 4)
+	cache.add("b", 4)
+	cache.add("a", 6) // 替换时按新的权重计算
+	assert.Equal(t, int64(10), cache.total)
+
+	cache.add("c", 3)
+	_, ok := cache.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, int64(9), cache.total)
+	assert.Len(t, cache.entries, 2)
+}
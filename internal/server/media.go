@@ -0,0 +1,800 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxMediaBoxes   = 10000 // 每层最多遍历的 MP4 box / EBML 元素数
+	maxMediaDepth   = 8     // MP4 box 最大嵌套深度
+	maxMediaTagSize = 4096  // 单个标签值最多读取的字节数
+)
+
+var (
+	// errUnsupportedMedia 不是受支持的音视频格式
+	errUnsupportedMedia = errors.New("unsupported media format")
+	// errInvalidMedia 音视频文件结构损坏
+	errInvalidMedia = errors.New("invalid media file")
+)
+
+// mediaExtensions 列表中附带音视频元数据时识别的扩展名
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".m4a": true, ".mov": true,
+	".mkv": true, ".mka": true, ".webm": true,
+	".mp3": true, ".flac": true, ".wav": true,
+}
+
+// mediaMeta 音视频元数据
+type mediaMeta struct {
+	Format   string            `json:"format"`             // 容器格式：mp4、m4a、mov、mkv、webm、mp3、flac、wav
+	Duration float64           `json:"duration,omitempty"` // 时长（秒）
+	Bitrate  int64             `json:"bitrate,omitempty"`  // 平均码率（bit/s）
+	Video    *videoStream      `json:"video,omitempty"`    // 第一路视频流
+	Audio    *audioStream      `json:"audio,omitempty"`    // 第一路音频流
+	Tags     map[string]string `json:"tags,omitempty"`     // 标签：title、artist、album、albumArtist、date、genre、track、comment
+}
+
+// videoStream 视频流信息
+type videoStream struct {
+	Codec     string  `json:"codec"`               // 编码，例如 h264、hevc、vp9、av1
+	Width     int     `json:"width,omitempty"`     // 宽度
+	Height    int     `json:"height,omitempty"`    // 高度
+	FrameRate float64 `json:"frameRate,omitempty"` // 平均帧率
+}
+
+// audioStream 音频流信息
+type audioStream struct {
+	Codec         string `json:"codec"`                   // 编码，例如 aac、mp3、opus、flac、pcm
+	SampleRate    int    `json:"sampleRate,omitempty"`    // 采样率（Hz）
+	Channels      int    `json:"channels,omitempty"`      // 声道数
+	BitsPerSample int    `json:"bitsPerSample,omitempty"` // 位深
+}
+
+// readMediaMeta 按文件头识别格式并读取音视频元数据，只读取头部和索引结构，不解码音视频数据
+func readMediaMeta(r io.ReaderAt, size int64) (*mediaMeta, error) {
+	head := make([]byte, 12)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	var meta *mediaMeta
+	var err error
+	switch {
+	case len(head) >= 8 && isMP4Box(string(head[4:8])):
+		meta, err = parseMP4(r, size)
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		meta, err = parseMatroska(r, size)
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		meta, err = parseFLAC(r, 0, size)
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		meta, err = parseWAV(r, size)
+	case bytes.HasPrefix(head, []byte("ID3")) || len(head) >= 4 && parseMPEGHeader(head) != nil:
+		meta, err = parseMP3(r, size)
+	default:
+		return nil, errUnsupportedMedia
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Duration = math.Round(meta.Duration*1000) / 1000
+	if meta.Bitrate == 0 && meta.Duration > 0 {
+		meta.Bitrate = int64(float64(size) * 8 / meta.Duration)
+	}
+	if len(meta.Tags) == 0 {
+		meta.Tags = nil
+	}
+	return meta, nil
+}
+
+// readAtFull 从 off 处读取 n 字节
+func readAtFull(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if read, err := r.ReadAt(buf, off); read < n {
+		if err == nil || errors.Is(err, io.EOF) {
+			return nil, errInvalidMedia
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// mediaTagKeys 将各格式的标签名（大写）映射为统一的键
+var mediaTagKeys = map[string]string{
+	"TITLE": "title", "NAME": "title",
+	"ARTIST": "artist", "PERFORMER": "artist",
+	"ALBUM": "album", "PRODUCT": "album",
+	"ALBUMARTIST": "albumArtist", "ALBUM_ARTIST": "albumArtist", "ALBUM ARTIST": "albumArtist",
+	"DATE": "date", "YEAR": "date", "DATE_RELEASED": "date", "DATE_RECORDED": "date",
+	"GENRE":       "genre",
+	"TRACKNUMBER": "track", "PART_NUMBER": "track", "TRACK": "track",
+	"COMMENT": "comment", "DESCRIPTION": "comment",
+}
+
+// setMediaTag 记录标签，同一个键只保留第一次出现的非空值
+func setMediaTag(tags map[string]string, key, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if key == "" || value == "" || tags[key] != "" {
+		return
+	}
+	if len(value) > maxMediaTagSize {
+		value = string(trimIncompleteRune([]byte(value[:maxMediaTagSize])))
+	}
+	tags[key] = value
+}
+
+// ---------------------------------------------------------------------------
+// MP4 / MOV（ISO BMFF）
+// ---------------------------------------------------------------------------
+
+// mp4Box box 的类型及其内容（不含头部）在文件中的范围
+type mp4Box struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+// isMP4Box 判断文件开头的 box 类型是否像 MP4/MOV
+func isMP4Box(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip":
+		return true
+	}
+	return false
+}
+
+// mp4Codecs 将 sample entry 的 fourcc 映射为通用编码名
+var mp4Codecs = map[string]string{
+	"avc1": "h264", "avc3": "h264",
+	"hvc1": "hevc", "hev1": "hevc",
+	"av01": "av1", "vp08": "vp8", "vp09": "vp9",
+	"mp4v": "mpeg4", "jpeg": "mjpeg",
+	"apch": "prores", "apcn": "prores", "apcs": "prores", "apco": "prores", "ap4h": "prores",
+	"mp4a": "aac", ".mp3": "mp3", "ac-3": "ac3", "ec-3": "eac3",
+	"Opus": "opus", "fLaC": "flac", "alac": "alac",
+	"sowt": "pcm", "twos": "pcm", "lpcm": "pcm", "ipcm": "pcm",
+}
+
+// mp4Tags 将 iTunes 风格的 ilst 条目映射为统一的键
+var mp4Tags = map[string]string{
+	"\xa9nam": "title", "\xa9ART": "artist", "\xa9alb": "album", "aART": "albumArtist",
+	"\xa9day": "date", "\xa9gen": "genre", "\xa9cmt": "comment", "trkn": "track",
+}
+
+// readMP4Boxes 遍历 [start, end) 范围内的 box
+func readMP4Boxes(r io.ReaderAt, start, end int64, fn func(box mp4Box) error) error {
+	off := start
+	for i := 0; off+8 <= end; i++ {
+		if i >= maxMediaBoxes {
+			return errInvalidMedia
+		}
+		hdr, err := readAtFull(r, off, 8)
+		if err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0: // 延伸到文件末尾
+			size = end - off
+		case 1: // 64 位长度
+			ext, err := readAtFull(r, off+8, 8)
+			if err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(ext))
+			headerLen = 16
+		}
+		if size < headerLen {
+			return errInvalidMedia
+		}
+		// 截断的文件：最后一个 box 只处理实际存在的部分
+		size = min(size, end-off)
+		if err := fn(mp4Box{typ: string(hdr[4:8]), offset: off + headerLen, size: size - headerLen}); err != nil {
+			return err
+		}
+		off += size
+	}
+	return nil
+}
+
+// readMP4Payload 读取 box 内容，最多 limit 字节
+func readMP4Payload(r io.ReaderAt, box mp4Box, limit int64) ([]byte, error) {
+	return readAtFull(r, box.offset, int(min(box.size, limit)))
+}
+
+// mp4Duration 解析 mvhd/mdhd 中的时间刻度和时长
+func mp4Duration(data []byte) (timescale uint32, duration uint64, ok bool) {
+	if len(data) < 4 {
+		return 0, 0, false
+	}
+	if data[0] == 1 { // version 1：64 位时间
+		if len(data) < 32 {
+			return 0, 0, false
+		}
+		return binary.BigEndian.Uint32(data[20:24]), binary.BigEndian.Uint64(data[24:32]), true
+	}
+	if len(data) < 20 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(data[12:16]), uint64(binary.BigEndian.Uint32(data[16:20])), true
+}
+
+// parseMP4 解析 MP4/MOV 的 moov box：总时长、各轨道编码和分辨率、iTunes 标签
+func parseMP4(r io.ReaderAt, size int64) (*mediaMeta, error) {
+	meta := &mediaMeta{Format: "mp4", Tags: map[string]string{}}
+	foundMoov := false
+	err := readMP4Boxes(r, 0, size, func(box mp4Box) error {
+		switch box.typ {
+		case "ftyp":
+			brand, err := readMP4Payload(r, box, 4)
+			if err != nil || len(brand) < 4 {
+				return nil
+			}
+			switch string(brand) {
+			case "qt  ":
+				meta.Format = "mov"
+			case "M4A ", "M4B ", "M4P ":
+				meta.Format = "m4a"
+			}
+		case "moov":
+			foundMoov = true
+			return parseMP4Moov(r, box, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !foundMoov {
+		return nil, errInvalidMedia
+	}
+	return meta, nil
+}
+
+// parseMP4Moov 解析 moov 的子 box
+func parseMP4Moov(r io.ReaderAt, moov mp4Box, meta *mediaMeta) error {
+	var trackDuration float64
+	err := readMP4Boxes(r, moov.offset, moov.offset+moov.size, func(box mp4Box) error {
+		switch box.typ {
+		case "mvhd":
+			data, err := readMP4Payload(r, box, 32)
+			if err != nil {
+				return err
+			}
+			if timescale, duration, ok := mp4Duration(data); ok && timescale > 0 {
+				meta.Duration = float64(duration) / float64(timescale)
+			}
+		case "trak":
+			d, err := parseMP4Track(r, box, meta, 1)
+			if err != nil {
+				return err
+			}
+			trackDuration = max(trackDuration, d)
+		case "udta":
+			return readMP4Boxes(r, box.offset, box.offset+box.size, func(child mp4Box) error {
+				if child.typ == "meta" {
+					return parseMP4Ilst(r, child, meta.Tags)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if meta.Duration == 0 {
+		meta.Duration = trackDuration
+	}
+	return err
+}
+
+// parseMP4Track 解析 trak：handler 类型、时长和第一个 sample entry，返回轨道时长（秒）
+func parseMP4Track(r io.ReaderAt, trak mp4Box, meta *mediaMeta, depth int) (float64, error) {
+	var handler string
+	var timescale uint32
+	var duration uint64
+	var entry []byte
+	var samples uint64
+
+	var walk func(box mp4Box, depth int) error
+	walk = func(box mp4Box, depth int) error {
+		if depth > maxMediaDepth {
+			return errInvalidMedia
+		}
+		return readMP4Boxes(r, box.offset, box.offset+box.size, func(child mp4Box) error {
+			switch child.typ {
+			case "mdia", "minf", "stbl":
+				return walk(child, depth+1)
+			case "mdhd":
+				data, err := readMP4Payload(r, child, 32)
+				if err != nil {
+					return err
+				}
+				timescale, duration, _ = mp4Duration(data)
+			case "hdlr":
+				data, err := readMP4Payload(r, child, 12)
+				if err == nil && len(data) >= 12 {
+					handler = string(data[8:12])
+				}
+			case "stsd":
+				// version/flags(4) + 条目数(4) + 第一个条目
+				data, err := readMP4Payload(r, child, 8+36)
+				if err == nil && len(data) >= 8+36 {
+					entry = data[8:]
+				}
+			case "stts":
+				samples = mp4SampleCount(r, child)
+			}
+			return nil
+		})
+	}
+	if err := walk(trak, depth); err != nil {
+		return 0, err
+	}
+
+	var seconds float64
+	if timescale > 0 {
+		seconds = float64(duration) / float64(timescale)
+	}
+	if entry == nil {
+		return seconds, nil
+	}
+	fourcc := string(entry[4:8])
+	codec := mp4Codecs[fourcc]
+	if codec == "" {
+		codec = strings.TrimSpace(fourcc)
+	}
+	switch {
+	case handler == "vide" && meta.Video == nil:
+		// sample entry(16) + pre_defined/reserved(16) + 宽(2) + 高(2)
+		meta.Video = &videoStream{
+			Codec:  codec,
+			Width:  int(binary.BigEndian.Uint16(entry[32:34])),
+			Height: int(binary.BigEndian.Uint16(entry[34:36])),
+		}
+		if seconds > 0 && samples > 0 {
+			meta.Video.FrameRate = math.Round(float64(samples)/seconds*1000) / 1000
+		}
+	case handler == "soun" && meta.Audio == nil:
+		// sample entry(16) + reserved(8) + 声道数(2) + 位深(2) + reserved(4) + 采样率(16.16 定点)
+		meta.Audio = &audioStream{
+			Codec:         codec,
+			Channels:      int(binary.BigEndian.Uint16(entry[24:26])),
+			BitsPerSample: int(binary.BigEndian.Uint16(entry[26:28])),
+			SampleRate:    int(binary.BigEndian.Uint32(entry[32:36]) >> 16),
+		}
+		if codec != "pcm" && codec != "alac" && codec != "flac" {
+			meta.Audio.BitsPerSample = 0 // 有损编码的位深字段没有意义
+		}
+	}
+	return seconds, nil
+}
+
+// mp4SampleCount 从 stts（解码时间表）统计样本数，用于计算平均帧率
+func mp4SampleCount(r io.ReaderAt, stts mp4Box) uint64 {
+	data, err := readMP4Payload(r, stts, maxMetaChunkSize)
+	if err != nil || len(data) < 8 {
+		return 0
+	}
+	count := binary.BigEndian.Uint32(data[4:8])
+	var total uint64
+	for i := uint32(0); i < count && 8+int(i)*8+8 <= len(data); i++ {
+		total += uint64(binary.BigEndian.Uint32(data[8+i*8:]))
+	}
+	return total
+}
+
+// parseMP4Ilst 解析 udta/meta/ilst 中的 iTunes 风格标签
+func parseMP4Ilst(r io.ReaderAt, metaBox mp4Box, tags map[string]string) error {
+	// ISO 的 meta 是 full box（多 4 字节 version/flags），QuickTime 的不是
+	start := metaBox.offset
+	if head, err := readMP4Payload(r, metaBox, 8); err == nil && len(head) == 8 && string(head[4:8]) != "hdlr" {
+		start += 4
+	}
+	return readMP4Boxes(r, start, metaBox.offset+metaBox.size, func(box mp4Box) error {
+		if box.typ != "ilst" {
+			return nil
+		}
+		return readMP4Boxes(r, box.offset, box.offset+box.size, func(item mp4Box) error {
+			key := mp4Tags[item.typ]
+			if key == "" {
+				return nil
+			}
+			return readMP4Boxes(r, item.offset, item.offset+item.size, func(data mp4Box) error {
+				if data.typ != "data" || data.size < 8 {
+					return nil
+				}
+				value, err := readMP4Payload(r, data, 8+maxMediaTagSize)
+				if err != nil {
+					return nil
+				}
+				// 类型标识(4) + 语言(4) + 值
+				if key == "track" {
+					if len(value) >= 14 {
+						setMediaTag(tags, key, strconv.Itoa(int(binary.BigEndian.Uint16(value[10:12]))))
+					}
+				} else if binary.BigEndian.Uint32(value[:4])&0xFFFFFF == 1 { // UTF-8 文本
+					setMediaTag(tags, key, string(value[8:]))
+				}
+				return nil
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Matroska / WebM（EBML）
+// ---------------------------------------------------------------------------
+
+// EBML 元素 ID
+const (
+	ebmlHeaderID      = 0x1A45DFA3
+	ebmlDocTypeID     = 0x4282
+	mkvSegmentID      = 0x18538067
+	mkvSeekHeadID     = 0x114D9B74
+	mkvSeekID         = 0x4DBB
+	mkvSeekIDID       = 0x53AB
+	mkvSeekPositionID = 0x53AC
+	mkvInfoID         = 0x1549A966
+	mkvTimescaleID    = 0x2AD7B1
+	mkvDurationID     = 0x4489
+	mkvTitleID        = 0x7BA9
+	mkvTracksID       = 0x1654AE6B
+	mkvTrackEntryID   = 0xAE
+	mkvTrackTypeID    = 0x83
+	mkvCodecID        = 0x86
+	mkvDefaultDurID   = 0x23E383
+	mkvVideoID        = 0xE0
+	mkvPixelWidthID   = 0xB0
+	mkvPixelHeightID  = 0xBA
+	mkvAudioID        = 0xE1
+	mkvSampleRateID   = 0xB5
+	mkvChannelsID     = 0x9F
+	mkvBitDepthID     = 0x6264
+	mkvTagsID         = 0x1254C367
+	mkvTagID          = 0x7373
+	mkvSimpleTagID    = 0x67C8
+	mkvTagNameID      = 0x45A3
+	mkvTagStringID    = 0x4487
+	mkvClusterID      = 0x1F43B675
+)
+
+// mkvCodecs 将 Matroska CodecID 映射为通用编码名
+var mkvCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC": "h264", "V_MPEGH/ISO/HEVC": "hevc", "V_AV1": "av1",
+	"V_VP8": "vp8", "V_VP9": "vp9", "V_MPEG4/ISO/ASP": "mpeg4", "V_MJPEG": "mjpeg",
+	"A_AAC": "aac", "A_OPUS": "opus", "A_VORBIS": "vorbis", "A_FLAC": "flac",
+	"A_AC3": "ac3", "A_EAC3": "eac3", "A_DTS": "dts", "A_MPEG/L3": "mp3", "A_MPEG/L2": "mp2",
+}
+
+// ebmlElement EBML 元素，size 为 -1 表示未知长度
+type ebmlElement struct {
+	id     uint32
+	offset int64
+	size   int64
+}
+
+// errStopEBML 用于提前结束遍历
+var errStopEBML = errors.New("stop")
+
+// readEBMLVint 读取变长整数，返回值（去掉长度标记位）、原始值（保留标记位）和字节数
+func readEBMLVint(r io.ReaderAt, off int64) (value, raw uint64, length int, err error) {
+	first, err := readAtFull(r, off, 1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	length = 1
+	for mask := byte(0x80); length <= 8 && first[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, 0, errInvalidMedia
+	}
+	data := first
+	if length > 1 {
+		if data, err = readAtFull(r, off, length); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	for _, b := range data {
+		raw = raw<<8 | uint64(b)
+	}
+	value = raw &^ (1 << (7 * length))
+	return value, raw, length, nil
+}
+
+// readEBMLElements 遍历 [start, end) 范围内的元素
+func readEBMLElements(r io.ReaderAt, start, end int64, fn func(el ebmlElement) error) error {
+	off := start
+	for i := 0; off < end; i++ {
+		if i >= maxMediaBoxes {
+			return nil
+		}
+		_, id, idLen, err := readEBMLVint(r, off)
+		if err != nil {
+			return err
+		}
+		size, _, sizeLen, err := readEBMLVint(r, off+int64(idLen))
+		if err != nil {
+			return err
+		}
+		el := ebmlElement{id: uint32(id), offset: off + int64(idLen+sizeLen), size: int64(size)}
+		if size == 1<<(7*sizeLen)-1 { // 全 1 表示未知长度
+			el.size = -1
+		} else if el.size > end-el.offset {
+			el.size = end - el.offset
+		}
+		if err := fn(el); err != nil {
+			if errors.Is(err, errStopEBML) {
+				return nil
+			}
+			return err
+		}
+		if el.size < 0 {
+			return nil // 未知长度的元素之后无法继续定位
+		}
+		off = el.offset + el.size
+	}
+	return nil
+}
+
+// ebmlData 读取元素内容
+func ebmlData(r io.ReaderAt, el ebmlElement, limit int64) []byte {
+	if el.size <= 0 {
+		return nil
+	}
+	data, _ := readAtFull(r, el.offset, int(min(el.size, limit)))
+	return data
+}
+
+// ebmlUint 读取无符号整数元素
+func ebmlUint(r io.ReaderAt, el ebmlElement) uint64 {
+	var v uint64
+	for _, b := range ebmlData(r, el, 8) {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// ebmlFloat 读取浮点数元素（4 或 8 字节）
+func ebmlFloat(r io.ReaderAt, el ebmlElement) float64 {
+	data := ebmlData(r, el, 8)
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+// ebmlString 读取字符串元素
+func ebmlString(r io.ReaderAt, el ebmlElement) string {
+	return strings.TrimRight(string(ebmlData(r, el, maxMediaTagSize)), "\x00")
+}
+
+// parseMatroska 解析 Matroska/WebM：Info（时长、标题）、Tracks（编码、分辨率、采样率）、Tags
+// 顺序扫描到第一个 Cluster 为止，之后通过 SeekHead 定位写在文件末尾的元素
+func parseMatroska(r io.ReaderAt, size int64) (*mediaMeta, error) {
+	meta := &mediaMeta{Format: "mkv", Tags: map[string]string{}}
+	var segment *ebmlElement
+	err := readEBMLElements(r, 0, size, func(el ebmlElement) error {
+		switch el.id {
+		case ebmlHeaderID:
+			return readEBMLElements(r, el.offset, el.offset+max(el.size, 0), func(child ebmlElement) error {
+				if child.id == ebmlDocTypeID && ebmlString(r, child) == "webm" {
+					meta.Format = "webm"
+				}
+				return nil
+			})
+		case mkvSegmentID:
+			segment = &el
+			return errStopEBML
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if segment == nil {
+		return nil, errInvalidMedia
+	}
+	end := size
+	if segment.size >= 0 {
+		end = segment.offset + segment.size
+	}
+
+	parsed := map[uint32]bool{}
+	seeks := map[uint32]int64{}
+	var parse func(el ebmlElement) error
+	parse = func(el ebmlElement) error {
+		if parsed[el.id] && el.id != mkvSeekHeadID {
+			return nil
+		}
+		parsed[el.id] = true
+		elEnd := end
+		if el.size >= 0 {
+			elEnd = el.offset + el.size
+		}
+		switch el.id {
+		case mkvSeekHeadID:
+			return readEBMLElements(r, el.offset, elEnd, func(seek ebmlElement) error {
+				if seek.id != mkvSeekID {
+					return nil
+				}
+				var id uint32
+				var pos int64 = -1
+				err := readEBMLElements(r, seek.offset, seek.offset+max(seek.size, 0), func(child ebmlElement) error {
+					switch child.id {
+					case mkvSeekIDID:
+						id = uint32(ebmlUint(r, child))
+					case mkvSeekPositionID:
+						pos = int64(ebmlUint(r, child))
+					}
+					return nil
+				})
+				if id != 0 && pos >= 0 {
+					seeks[id] = pos
+				}
+				return err
+			})
+		case mkvInfoID:
+			return parseMatroskaInfo(r, el, elEnd, meta)
+		case mkvTracksID:
+			return readEBMLElements(r, el.offset, elEnd, func(track ebmlElement) error {
+				if track.id == mkvTrackEntryID && track.size > 0 {
+					return parseMatroskaTrack(r, track, meta)
+				}
+				return nil
+			})
+		case mkvTagsID:
+			return parseMatroskaTags(r, el, elEnd, meta.Tags)
+		case mkvClusterID:
+			return errStopEBML
+		}
+		return nil
+	}
+	if err := readEBMLElements(r, segment.offset, end, parse); err != nil {
+		return nil, err
+	}
+
+	// 通过 SeekHead 补充解析位于 Cluster 之后的元素
+	for _, id := range []uint32{mkvInfoID, mkvTracksID, mkvTagsID} {
+		pos, ok := seeks[id]
+		if !ok || parsed[id] || segment.offset+pos >= end {
+			continue
+		}
+		err := readEBMLElements(r, segment.offset+pos, end, func(el ebmlElement) error {
+			if el.id == id {
+				if err := parse(el); err != nil && !errors.Is(err, errStopEBML) {
+					return err
+				}
+			}
+			return errStopEBML
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
+
+// parseMatroskaInfo 解析 Info：时长 = Duration × TimestampScale（纳秒）
+func parseMatroskaInfo(r io.ReaderAt, info ebmlElement, end int64, meta *mediaMeta) error {
+	scale := uint64(1000000)
+	var duration float64
+	err := readEBMLElements(r, info.offset, end, func(el ebmlElement) error {
+		switch el.id {
+		case mkvTimescaleID:
+			if v := ebmlUint(r, el); v > 0 {
+				scale = v
+			}
+		case mkvDurationID:
+			duration = ebmlFloat(r, el)
+		case mkvTitleID:
+			setMediaTag(meta.Tags, "title", ebmlString(r, el))
+		}
+		return nil
+	})
+	meta.Duration = duration * float64(scale) / 1e9
+	return err
+}
+
+// parseMatroskaTrack 解析 TrackEntry，只记录第一路视频和第一路音频
+func parseMatroskaTrack(r io.ReaderAt, track ebmlElement, meta *mediaMeta) error {
+	var trackType uint64
+	var codecID string
+	var defaultDuration uint64
+	video := &videoStream{}
+	audio := &audioStream{}
+	err := readEBMLElements(r, track.offset, track.offset+track.size, func(el ebmlElement) error {
+		switch el.id {
+		case mkvTrackTypeID:
+			trackType = ebmlUint(r, el)
+		case mkvCodecID:
+			codecID = ebmlString(r, el)
+		case mkvDefaultDurID:
+			defaultDuration = ebmlUint(r, el)
+		case mkvVideoID:
+			return readEBMLElements(r, el.offset, el.offset+max(el.size, 0), func(child ebmlElement) error {
+				switch child.id {
+				case mkvPixelWidthID:
+					video.Width = int(ebmlUint(r, child))
+				case mkvPixelHeightID:
+					video.Height = int(ebmlUint(r, child))
+				}
+				return nil
+			})
+		case mkvAudioID:
+			return readEBMLElements(r, el.offset, el.offset+max(el.size, 0), func(child ebmlElement) error {
+				switch child.id {
+				case mkvSampleRateID:
+					audio.SampleRate = int(ebmlFloat(r, child))
+				case mkvChannelsID:
+					audio.Channels = int(ebmlUint(r, child))
+				case mkvBitDepthID:
+					audio.BitsPerSample = int(ebmlUint(r, child))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+
+	codec := mkvCodecs[codecID]
+	if codec == "" {
+		if strings.HasPrefix(codecID, "A_PCM/") {
+			codec = "pcm"
+		} else if i := strings.IndexByte(codecID, '_'); i >= 0 {
+			codec = strings.ToLower(codecID[i+1:])
+		}
+	}
+	switch {
+	case trackType == 1 && meta.Video == nil:
+		video.Codec = codec
+		if defaultDuration > 0 {
+			video.FrameRate = math.Round(1e9/float64(defaultDuration)*1000) / 1000
+		}
+		meta.Video = video
+	case trackType == 2 && meta.Audio == nil:
+		audio.Codec = codec
+		if audio.SampleRate == 0 {
+			audio.SampleRate = 8000 // Matroska 规范中的默认值
+		}
+		if audio.Channels == 0 {
+			audio.Channels = 1
+		}
+		meta.Audio = audio
+	}
+	return err
+}
+
+// parseMatroskaTags 解析 Tags 中的 SimpleTag
+func parseMatroskaTags(r io.ReaderAt, tagsEl ebmlElement, end int64, tags map[string]string) error {
+	return readEBMLElements(r, tagsEl.offset, end, func(tag ebmlElement) error {
+		if tag.id != mkvTagID || tag.size <= 0 {
+			return nil
+		}
+		return readEBMLElements(r, tag.offset, tag.offset+tag.size, func(simple ebmlElement) error {
+			if simple.id != mkvSimpleTagID || simple.size <= 0 {
+				return nil
+			}
+			var name, value string
+			err := readEBMLElements(r, simple.offset, simple.offset+simple.size, func(el ebmlElement) error {
+				switch el.id {
+				case mkvTagNameID:
+					name = ebmlString(r, el)
+				case mkvTagStringID:
+					value = ebmlString(r, el)
+				}
+				return nil
+			})
+			setMediaTag(tags, mediaTagKeys[strings.ToUpper(name)], value)
+			return err
+		})
+	})
+}
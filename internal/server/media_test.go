@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mp4Atom 构建一个 MP4 box
+func mp4Atom(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, typ...), body...)
+}
+
+// be 按大端序拼接整数
+func be(values ...any) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// buildMP4 构建带一路 1920x1080 H.264 视频、一路 AAC 音频和 iTunes 标签的 MP4（10 秒）
+func buildMP4() []byte {
+	mdhd := func(timescale, duration uint32) []byte {
+		return mp4Atom("mdhd", be(uint32(0), uint32(0), uint32(0), timescale, duration, uint32(0)))
+	}
+	hdlr := func(handler string) []byte {
+		return mp4Atom("hdlr", be(uint32(0), uint32(0)), []byte(handler), make([]byte, 12))
+	}
+	// 视频 sample entry：reserved(6) + dref(2) + pre_defined/reserved(16) + 宽高
+	avc1 := mp4Atom("avc1", make([]byte, 8), make([]byte, 16), be(uint16(1920), uint16(1080)), make([]byte, 50))
+	// 音频 sample entry：reserved(6) + dref(2) + reserved(8) + 声道数 + 位深 + reserved(4) + 采样率
+	mp4a := mp4Atom("mp4a", make([]byte, 8), make([]byte, 8), be(uint16(2), uint16(16), uint32(0), uint32(48000<<16)))
+	video := mp4Atom("trak", mp4Atom("mdia", mdhd(90000, 900000), hdlr("vide"),
+		mp4Atom("minf", mp4Atom("stbl",
+			mp4Atom("stsd", be(uint32(0), uint32(1)), avc1),
+			mp4Atom("stts", be(uint32(0), uint32(1), uint32(250), uint32(3600))),
+		))))
+	audio := mp4Atom("trak", mp4Atom("mdia", mdhd(48000, 480000), hdlr("soun"),
+		mp4Atom("minf", mp4Atom("stbl", mp4Atom("stsd", be(uint32(0), uint32(1)), mp4a)))))
+	data := func(value []byte, typ uint32) []byte {
+		return mp4Atom("data", be(typ, uint32(0)), value)
+	}
+	ilst := mp4Atom("ilst",
+		mp4Atom("\xa9nam", data([]byte("Demo Clip"), 1)),
+		mp4Atom("\xa9ART", data([]byte("Someone"), 1)),
+		mp4Atom("trkn", data(be(uint16(0), uint16(3), uint16(12), uint16(0)), 0)),
+	)
+	udta := mp4Atom("udta", mp4Atom("meta", be(uint32(0)), hdlr("mdir"), ilst))
+	mvhd := mp4Atom("mvhd", be(uint32(0), uint32(0), uint32(0), uint32(1000), uint32(10000)), make([]byte, 80))
+
+	return bytes.Join([][]byte{
+		mp4Atom("ftyp", []byte("isom"), be(uint32(512)), []byte("isomavc1")),
+		mp4Atom("mdat", make([]byte, 1000)),
+		mp4Atom("moov", mvhd, video, audio, udta),
+	}, nil)
+}
+
+// ebml 构建一个 EBML 元素（ID 原样写入，长度使用 8 字节变长整数）
+func ebml(id uint32, payload ...[]byte) []byte {
+	idBytes := be(id)
+	for len(idBytes) > 1 && idBytes[0] == 0 {
+		idBytes = idBytes[1:]
+	}
+	body := bytes.Join(payload, nil)
+	return append(append(idBytes, be(uint64(len(body))|1<<56)...), body...)
+}
+
+// buildWebM 构建带一路 VP9 视频、一路 Opus 音频的 WebM，Tags 位于 Cluster 之后并通过 SeekHead 定位
+func buildWebM() []byte {
+	header := ebml(ebmlHeaderID, ebml(ebmlDocTypeID, []byte("webm")))
+	info := ebml(mkvInfoID, ebml(mkvTimescaleID, be(uint32(1000000))), ebml(mkvDurationID, be(float64(61500))),
+		ebml(mkvTitleID, []byte("Meeting")))
+	tracks := ebml(mkvTracksID,
+		ebml(mkvTrackEntryID, ebml(mkvTrackTypeID, []byte{1}), ebml(mkvCodecID, []byte("V_VP9")),
+			ebml(mkvDefaultDurID, be(uint32(40000000))),
+			ebml(mkvVideoID, ebml(mkvPixelWidthID, be(uint16(1280))), ebml(mkvPixelHeightID, be(uint16(720))))),
+		ebml(mkvTrackEntryID, ebml(mkvTrackTypeID, []byte{2}), ebml(mkvCodecID, []byte("A_OPUS")),
+			ebml(mkvAudioID, ebml(mkvSampleRateID, be(float32(48000))), ebml(mkvChannelsID, []byte{2}))),
+	)
+	cluster := ebml(mkvClusterID, make([]byte, 500))
+	tags := ebml(mkvTagsID, ebml(mkvTagID, ebml(mkvSimpleTagID,
+		ebml(mkvTagNameID, []byte("ARTIST")), ebml(mkvTagStringID, []byte("Team")))))
+
+	seek := func(id uint32, pos int) []byte {
+		return ebml(mkvSeekID, ebml(mkvSeekIDID, be(id)), ebml(mkvSeekPositionID, be(uint64(pos))))
+	}
+	// SeekHead 自身长度固定，先用占位位置计算长度
+	seekLen := len(ebml(mkvSeekHeadID, seek(mkvTagsID, 0)))
+	tagsPos := seekLen + len(info) + len(tracks) + len(cluster)
+	segment := bytes.Join([][]byte{ebml(mkvSeekHeadID, seek(mkvTagsID, tagsPos)), info, tracks, cluster, tags}, nil)
+	return append(header, ebml(mkvSegmentID, segment)...)
+}
+
+// buildMP3 构建带 ID3v2.3 标签和 Xing 头的 MPEG-1 Layer III 文件（1000 帧，44.1kHz 立体声）
+func buildMP3() []byte {
+	frame := func(id, text string) []byte {
+		body := append([]byte{3}, text...)
+		return append(append([]byte(id), be(uint32(len(body)), uint16(0))...), body...)
+	}
+	utf16Frame := append([]byte("TPE1"), be(uint32(7), uint16(0))...)
+	utf16Frame = append(utf16Frame, 1, 0xFF, 0xFE, 'A', 0, 'B', 0)
+	frames := bytes.Join([][]byte{frame("TIT2", "曲目"), utf16Frame, frame("TRCK", "5/10")}, nil)
+	size := len(frames)
+	id3 := append([]byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}, frames...)
+
+	// 帧头：MPEG-1 Layer III，128kbps，44.1kHz，立体声；Xing 头位于 4+32 字节处
+	first := make([]byte, 417)
+	copy(first, []byte{0xFF, 0xFB, 0x90, 0x00})
+	copy(first[36:], append([]byte("Xing"), be(uint32(1), uint32(1000))...))
+	return append(append(id3, first...), bytes.Repeat([]byte{0xFF, 0xFB, 0x90, 0x00}, 100)...)
+}
+
+// buildFLAC 构建 STREAMINFO（44.1kHz，2 声道，16 位，441000 个样本）和 Vorbis 注释
+func buildFLAC() []byte {
+	info := make([]byte, 34)
+	// 采样率(20) 声道数-1(3) 位深-1(5) 总样本数(36)
+	packed := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36 | 441000
+	binary.BigEndian.PutUint64(info[10:18], packed)
+	comment := func(s string) []byte {
+		return append(binary.LittleEndian.AppendUint32(nil, uint32(len(s))), s...)
+	}
+	vorbis := bytes.Join([][]byte{comment("test"), binary.LittleEndian.AppendUint32(nil, 2),
+		comment("TITLE=Song"), comment("album=Record")}, nil)
+
+	out := []byte("fLaC")
+	out = append(out, 0x00, 0, 0, 34)
+	out = append(out, info...)
+	out = append(out, 0x84, 0, byte(len(vorbis)>>8), byte(len(vorbis)))
+	out = append(out, vorbis...)
+	return append(out, make([]byte, 2000)...)
+}
+
+// buildWAV 构建 16 位立体声 PCM（44.1kHz，0.5 秒）和 LIST/INFO 标签
+func buildWAV() []byte {
+	chunk := func(id string, body []byte) []byte {
+		out := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+		out = append(out, body...)
+		if len(body)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:], 1)
+	binary.LittleEndian.PutUint16(fmtChunk[2:], 2)
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 44100)
+	binary.LittleEndian.PutUint32(fmtChunk[8:], 44100*4)
+	binary.LittleEndian.PutUint16(fmtChunk[12:], 4)
+	binary.LittleEndian.PutUint16(fmtChunk[14:], 16)
+	list := append([]byte("INFO"), chunk("INAM", []byte("Take 1\x00"))...)
+	body := bytes.Join([][]byte{[]byte("WAVE"), chunk("fmt ", fmtChunk), chunk("LIST", list), chunk("data", make([]byte, 44100*2))}, nil)
+	return chunk("RIFF", body)
+}
+
+func TestReadMediaMeta(t *testing.T) {
+	parse := func(data []byte) *mediaMeta {
+		meta, err := readMediaMeta(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		return meta
+	}
+
+	t.Run("mp4", func(t *testing.T) {
+		meta := parse(buildMP4())
+		assert.Equal(t, "mp4", meta.Format)
+		assert.Equal(t, 10.0, meta.Duration)
+		assert.Equal(t, &videoStream{Codec: "h264", Width: 1920, Height: 1080, FrameRate: 25}, meta.Video)
+		assert.Equal(t, &audioStream{Codec: "aac", SampleRate: 48000, Channels: 2}, meta.Audio)
+		assert.Equal(t, map[string]string{"title": "Demo Clip", "artist": "Someone", "track": "3"}, meta.Tags)
+		assert.Positive(t, meta.Bitrate)
+	})
+
+	t.Run("webm", func(t *testing.T) {
+		meta := parse(buildWebM())
+		assert.Equal(t, "webm", meta.Format)
+		assert.Equal(t, 61.5, meta.Duration)
+		assert.Equal(t, &videoStream{Codec: "vp9", Width: 1280, Height: 720, FrameRate: 25}, meta.Video)
+		assert.Equal(t, &audioStream{Codec: "opus", SampleRate: 48000, Channels: 2}, meta.Audio)
+		assert.Equal(t, map[string]string{"title": "Meeting", "artist": "Team"}, meta.Tags)
+	})
+
+	t.Run("mp3", func(t *testing.T) {
+		meta := parse(buildMP3())
+		assert.Equal(t, "mp3", meta.Format)
+		assert.Equal(t, math.Round(1000*1152/44100.0*1000)/1000, meta.Duration)
+		assert.Equal(t, &audioStream{Codec: "mp3", SampleRate: 44100, Channels: 2}, meta.Audio)
+		assert.Equal(t, map[string]string{"title": "曲目", "artist": "AB", "track": "5/10"}, meta.Tags)
+	})
+
+	t.Run("flac", func(t *testing.T) {
+		meta := parse(buildFLAC())
+		assert.Equal(t, "flac", meta.Format)
+		assert.Equal(t, 10.0, meta.Duration)
+		assert.Equal(t, &audioStream{Codec: "flac", SampleRate: 44100, Channels: 2, BitsPerSample: 16}, meta.Audio)
+		assert.Equal(t, map[string]string{"title": "Song", "album": "Record"}, meta.Tags)
+	})
+
+	t.Run("wav", func(t *testing.T) {
+		meta := parse(buildWAV())
+		assert.Equal(t, "wav", meta.Format)
+		assert.Equal(t, 0.5, meta.Duration)
+		assert.Equal(t, int64(44100*4*8), meta.Bitrate)
+		assert.Equal(t, &audioStream{Codec: "pcm", SampleRate: 44100, Channels: 2, BitsPerSample: 16}, meta.Audio)
+		assert.Equal(t, map[string]string{"title": "Take 1"}, meta.Tags)
+	})
+
+	for _, data := range [][]byte{[]byte("plain text file"), {0x1A, 0x45, 0xDF, 0xA3, 0x00}, mp4Atom("ftyp", []byte("isom"))} {
+		_, err := readMediaMeta(bytes.NewReader(data), int64(len(data)))
+		assert.Error(t, err)
+	}
+}
+
+func TestHandleMeta_Media(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "clip.mp4"), buildMP4(), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "song.flac"), buildFLAC(), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/meta?path=/clip.mp4", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp metaResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Image)
+	require.NotNil(t, resp.Media)
+	assert.Equal(t, "h264", resp.Media.Video.Codec)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files?path=/&withMeta=image,media", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var items []fileEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	byName := map[string]fileEntry{}
+	for _, item := range items {
+		byName[item.Name] = item
+	}
+	assert.Equal(t, 10.0, byName["clip.mp4"].Duration)
+	assert.Equal(t, "h264", byName["clip.mp4"].Codec)
+	assert.Equal(t, 1920, byName["clip.mp4"].Width)
+	assert.Equal(t, "flac", byName["song.flac"].Codec)
+	assert.Zero(t, byName["notes.txt"].Duration)
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxICCProfileSize = 4 * 1024 * 1024 // ICC 配置文件最大读取大小
+	maxMetaChunkSize  = 4 * 1024 * 1024 // PNG/WebP 元数据块最大读取大小
+)
+
+// imageExtensions 列表中附带图片尺寸时识别的扩展名
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true,
+}
+
+// metaResponse 文件元数据响应
+type metaResponse struct {
+	Path     string     `json:"path"`            // 相对路径
+	Name     string     `json:"name"`            // 文件名
+	Size     int64      `json:"size"`            // 文件大小
+	Modified string     `json:"modified"`        // 修改时间
+	Image    *imageMeta `json:"image,omitempty"` // 图片元数据
+	Media    *mediaMeta `json:"media,omitempty"` // 音视频元数据
+}
+
+// imageMeta 图片元数据
+type imageMeta struct {
+	Format      string    `json:"format"`                // 格式：jpeg、png、gif、webp
+	Width       int       `json:"width"`                 // 显示宽度（已按 EXIF 方向换算）
+	Height      int       `json:"height"`                // 显示高度（已按 EXIF 方向换算）
+	ColorModel  string    `json:"colorModel"`            // 颜色模型，例如 RGBA、YCbCr、Gray、Paletted
+	Orientation int       `json:"orientation,omitempty"` // EXIF 方向（1-8）
+	ICCProfile  string    `json:"iccProfile,omitempty"`  // ICC 配置文件描述，例如 Display P3
+	Exif        *exifInfo `json:"exif,omitempty"`        // EXIF 信息
+}
+
+// handleMeta 返回文件元数据
+// GET /api/meta?path=/photos/a.jpg
+// 图片返回尺寸、颜色模型、EXIF（相机、拍摄时间、GPS、方向）和 ICC 配置文件名称；
+// 音视频返回时长、码率、编码、分辨率、采样率和标签
+func (s *Server) handleMeta(c *gin.Context) {
+	absPath, relPath, err := s.resolvePath(c.Query("path"))
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+
+	file, info, err := openRegularFile(absPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "OPEN_FAILED", err.Error())
+		return
+	}
+	defer file.Close()
+
+	resp := metaResponse{
+		Path:     "/" + relPath,
+		Name:     info.Name(),
+		Size:     info.Size(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+	}
+	if meta, err := readImageMeta(file); err == nil {
+		resp.Image = meta
+	} else if meta, err := readMediaMeta(file, info.Size()); err == nil {
+		resp.Media = meta
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// readImageMeta 读取图片元数据，不解码像素数据
+// 非图片或格式不受支持时返回 errUnsupportedImage
+func readImageMeta(r io.ReadSeeker) (*imageMeta, error) {
+	cfg, format, err := image.DecodeConfig(bufio.NewReader(r))
+	if err != nil {
+		return nil, errUnsupportedImage
+	}
+	meta := &imageMeta{
+		Format:     format,
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		ColorModel: colorModelName(cfg.ColorModel),
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return meta, nil
+	}
+	var exifData, iccData []byte
+	switch format {
+	case "jpeg":
+		if segments, err := readJPEGSegments(r); err == nil {
+			exifData = segments.exif
+			iccData = bytes.Join(segments.icc, nil)
+		}
+	case "png":
+		exifData, iccData = readPNGMeta(r)
+	case "webp":
+		exifData, iccData = readWebPMeta(r)
+	}
+
+	if exif, err := parseExif(exifData); err == nil {
+		meta.Exif = exif
+		meta.Orientation = exif.Orientation
+	}
+	if meta.Orientation >= 5 {
+		meta.Width, meta.Height = meta.Height, meta.Width
+	}
+	meta.ICCProfile = iccDescription(iccData)
+	return meta, nil
+}
+
+// colorModelName 返回颜色模型的名称
+func colorModelName(m color.Model) string {
+	switch m {
+	case color.RGBAModel:
+		return "RGBA"
+	case color.RGBA64Model:
+		return "RGBA64"
+	case color.NRGBAModel:
+		return "NRGBA"
+	case color.NRGBA64Model:
+		return "NRGBA64"
+	case color.AlphaModel, color.Alpha16Model:
+		return "Alpha"
+	case color.GrayModel:
+		return "Gray"
+	case color.Gray16Model:
+		return "Gray16"
+	case color.YCbCrModel:
+		return "YCbCr"
+	case color.NYCbCrAModel:
+		return "NYCbCrA"
+	case color.CMYKModel:
+		return "CMYK"
+	}
+	if _, ok := m.(color.Palette); ok {
+		return "Paletted"
+	}
+	return "unknown"
+}
+
+// readPNGMeta 读取 PNG 的 eXIf 和 iCCP 块，遇到图像数据（IDAT）即停止
+func readPNGMeta(r io.Reader) (exifData, iccData []byte) {
+	br := bufio.NewReader(r)
+	if _, err := br.Discard(8); err != nil { // PNG 签名
+		return nil, nil
+	}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		typ := string(header[4:8])
+		if typ == "IDAT" || typ == "IEND" || length > maxMetaChunkSize {
+			return
+		}
+		if typ != "eXIf" && typ != "iCCP" {
+			if _, err := br.Discard(int(length) + 4); err != nil { // 数据 + CRC
+				return
+			}
+			continue
+		}
+
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return
+		}
+		data = data[:length]
+		switch typ {
+		case "eXIf":
+			exifData = data
+		case "iCCP":
+			iccData = decodeICCP(data)
+		}
+	}
+}
+
+// decodeICCP 解码 PNG iCCP 块：配置文件名 + NUL + 压缩方式 + zlib 压缩的配置文件
+func decodeICCP(data []byte) []byte {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 || i+2 > len(data) {
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[i+2:]))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	profile, err := io.ReadAll(io.LimitReader(zr, maxICCProfileSize))
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// readWebPMeta 读取 WebP（RIFF 容器）中的 EXIF 和 ICCP 块
+func readWebPMeta(r io.Reader) (exifData, iccData []byte) {
+	br := bufio.NewReader(r)
+	var header [12]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil ||
+		string(header[:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return nil, nil
+	}
+	var chunk [8]byte
+	for {
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			return
+		}
+		length := binary.LittleEndian.Uint32(chunk[4:8])
+		padded := int(length + length&1) // 块按偶数字节对齐
+		typ := string(chunk[:4])
+		if (typ != "EXIF" && typ != "ICCP") || length > maxMetaChunkSize {
+			if _, err := br.Discard(padded); err != nil {
+				return
+			}
+			continue
+		}
+
+		data := make([]byte, padded)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return
+		}
+		data = data[:length]
+		switch typ {
+		case "EXIF":
+			// 部分编码器会保留 JPEG 风格的 Exif\0\0 前缀
+			exifData = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+		case "ICCP":
+			iccData = data
+		}
+	}
+}
+
+// iccDescription 从 ICC 配置文件的 desc 标签中读取描述
+// 支持 v2 的 textDescriptionType 和 v4 的 multiLocalizedUnicodeType
+func iccDescription(profile []byte) string {
+	if len(profile) < 132 {
+		return ""
+	}
+	count := binary.BigEndian.Uint32(profile[128:132])
+	for i := uint32(0); i < count; i++ {
+		entry := 132 + uint64(i)*12
+		if entry+12 > uint64(len(profile)) {
+			return ""
+		}
+		if string(profile[entry:entry+4]) != "desc" {
+			continue
+		}
+		offset := uint64(binary.BigEndian.Uint32(profile[entry+4:]))
+		size := uint64(binary.BigEndian.Uint32(profile[entry+8:]))
+		if offset+size > uint64(len(profile)) || size < 12 {
+			return ""
+		}
+		return decodeICCText(profile[offset : offset+size])
+	}
+	return ""
+}
+
+// decodeICCText 解码 desc 标签的内容
+func decodeICCText(tag []byte) string {
+	switch string(tag[:4]) {
+	case "desc":
+		// 类型(4) + 保留(4) + ASCII 长度(4) + ASCII 字符串
+		n := uint64(binary.BigEndian.Uint32(tag[8:12]))
+		if 12+n > uint64(len(tag)) {
+			return ""
+		}
+		return strings.TrimSpace(string(bytes.TrimRight(tag[12:12+n], "\x00")))
+	case "mluc":
+		// 类型(4) + 保留(4) + 记录数(4) + 记录大小(4) + 记录（语言(2) 国家(2) 长度(4) 偏移(4)）
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:12]) == 0 {
+			return ""
+		}
+		n := uint64(binary.BigEndian.Uint32(tag[20:24]))
+		offset := uint64(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+n > uint64(len(tag)) {
+			return ""
+		}
+		units := make([]uint16, n/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+uint64(i)*2:])
+		}
+		return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+	}
+	return ""
+}
+
+// addImageSizes 为列表中的图片文件填充显示尺寸
+func addImageSizes(absDir string, items []fileEntry) {
+	annotateEntries(absDir, items, imageExtensions, func(absPath string, item *fileEntry) {
+		item.Width, item.Height = imageSize(absPath)
+	})
+}
+
+// addMediaMeta 为列表中的音视频文件填充时长、编码和分辨率
+func addMediaMeta(absDir string, items []fileEntry) {
+	annotateEntries(absDir, items, mediaExtensions, func(absPath string, item *fileEntry) {
+		file, info, err := openRegularFile(absPath)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		meta, err := readMediaMeta(file, info.Size())
+		if err != nil {
+			return
+		}
+		item.Duration = meta.Duration
+		switch {
+		case meta.Video != nil:
+			item.Codec = meta.Video.Codec
+			item.Width, item.Height = meta.Video.Width, meta.Video.Height
+		case meta.Audio != nil:
+			item.Codec = meta.Audio.Codec
+		}
+	})
+}
+
+// annotateEntries 对扩展名在 exts 中的文件调用 fill 补充元数据
+// 只读取文件头，按 CPU 数并发处理；读取失败的文件保持字段为空
+func annotateEntries(absDir string, items []fileEntry, exts map[string]bool, fill func(absPath string, item *fileEntry)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fill(filepath.Join(absDir, items[i].Name), &items[i])
+			}
+		}()
+	}
+	for i, item := range items {
+		if item.Type == "file" && exts[strings.ToLower(path.Ext(item.Name))] {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// imageSize 返回图片的显示尺寸，无法读取时返回 0, 0
+func imageSize(absPath string) (int, int) {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+
+	meta, err := readImageMeta(file)
+	if err != nil {
+		return 0, 0
+	}
+	return meta.Width, meta.Height
+}
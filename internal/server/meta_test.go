@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTag 构造 TIFF 数据用的 IFD 条目
+type testTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiTag(tag uint16, s string) testTag {
+	return testTag{tag, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func shortTag(tag uint16, v uint16) testTag {
+	return testTag{tag, 3, 1, binary.BigEndian.AppendUint16(nil, v)}
+}
+
+func rationalTag(tag uint16, pairs ...uint32) testTag {
+	var value []byte
+	for _, v := range pairs {
+		value = binary.BigEndian.AppendUint32(value, v)
+	}
+	return testTag{tag, 5, uint32(len(pairs) / 2), value}
+}
+
+// buildTIFF 构造大端 TIFF 数据：IFD0 + Exif 子 IFD + GPS 子 IFD，子 IFD 指针自动加入 IFD0
+func buildTIFF(ifd0, exifIFD, gpsIFD []testTag) []byte {
+	ifdSize := func(tags []testTag) uint32 { return uint32(2 + 12*len(tags) + 4) }
+	ifd0 = append(append([]testTag(nil), ifd0...), testTag{exifTagExifIFD, 4, 1, nil}, testTag{exifTagGPSIFD, 4, 1, nil})
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	dataOffset := gpsOffset + ifdSize(gpsIFD)
+	ifd0[len(ifd0)-2].value = binary.BigEndian.AppendUint32(nil, exifOffset)
+	ifd0[len(ifd0)-1].value = binary.BigEndian.AppendUint32(nil, gpsOffset)
+
+	head := []byte{'M', 'M', 0, 42, 0, 0, 0, 8}
+	var data []byte
+	for _, tags := range [][]testTag{ifd0, exifIFD, gpsIFD} {
+		head = binary.BigEndian.AppendUint16(head, uint16(len(tags)))
+		for _, tag := range tags {
+			head = binary.BigEndian.AppendUint16(head, tag.tag)
+			head = binary.BigEndian.AppendUint16(head, tag.typ)
+			head = binary.BigEndian.AppendUint32(head, tag.count)
+			if len(tag.value) <= 4 {
+				head = append(head, append(tag.value, make([]byte, 4-len(tag.value))...)...)
+			} else {
+				head = binary.BigEndian.AppendUint32(head, dataOffset+uint32(len(data)))
+				data = append(data, tag.value...)
+			}
+		}
+		head = binary.BigEndian.AppendUint32(head, 0)
+	}
+	return append(head, data...)
+}
+
+// buildICCProfile 构造只包含 desc 标签的 ICC 配置文件，tagType 为 desc（v2）或 mluc（v4）
+func buildICCProfile(tagType, desc string) []byte {
+	var tag []byte
+	switch tagType {
+	case "desc":
+		tag = append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(desc)+1))...)
+		tag = append(append(tag, desc...), 0)
+	case "mluc":
+		text := utf16.Encode([]rune(desc))
+		tag = []byte("mluc\x00\x00\x00\x00")
+		tag = binary.BigEndian.AppendUint32(tag, 1)
+		tag = binary.BigEndian.AppendUint32(tag, 12)
+		tag = append(tag, "enUS"...)
+		tag = binary.BigEndian.AppendUint32(tag, uint32(len(text)*2))
+		tag = binary.BigEndian.AppendUint32(tag, 28)
+		for _, u := range text {
+			tag = binary.BigEndian.AppendUint16(tag, u)
+		}
+	}
+	profile := make([]byte, 128)
+	profile = binary.BigEndian.AppendUint32(profile, 1)
+	profile = append(profile, "desc"...)
+	profile = binary.BigEndian.AppendUint32(profile, 144)
+	profile = binary.BigEndian.AppendUint32(profile, uint32(len(tag)))
+	return append(profile, tag...)
+}
+
+// buildJPEG 编码 w×h 的 JPEG，并在 SOI 之后插入给定的 APP 段（marker + payload）
+func buildJPEG(t *testing.T, w, h int, segments ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil))
+	encoded := buf.Bytes()
+
+	data := []byte{0xff, 0xd8}
+	for _, seg := range segments {
+		data = append(data, 0xff, seg[0])
+		data = binary.BigEndian.AppendUint16(data, uint16(len(seg)+1))
+		data = append(data, seg[1:]...)
+	}
+	return append(data, encoded[2:]...)
+}
+
+// iccChunk 构造第 seq 个（共 total 个）APP2 ICC_PROFILE 段
+func iccChunk(seq, total int, data []byte) []byte {
+	seg := append([]byte{0xe2}, "ICC_PROFILE\x00"...)
+	return append(append(seg, byte(seq), byte(total)), data...)
+}
+
+// testExif 测试用 EXIF：相机信息、拍摄参数、GPS 和方向 6
+func testExif() []byte {
+	return buildTIFF(
+		[]testTag{
+			asciiTag(exifTagMake, "Canon"),
+			asciiTag(exifTagModel, "EOS R5"),
+			shortTag(exifTagOrientation, 6),
+		},
+		[]testTag{
+			rationalTag(exifTagExposureTime, 1, 250),
+			rationalTag(exifTagFNumber, 28, 10),
+			shortTag(exifTagISO, 400),
+			asciiTag(exifTagDateTimeOriginal, "2024:05:01 14:30:00"),
+			asciiTag(exifTagOffsetTimeOrig, "+08:00"),
+			rationalTag(exifTagFocalLength, 50, 1),
+		},
+		[]testTag{
+			asciiTag(gpsTagLatitudeRef, "N"),
+			rationalTag(gpsTagLatitude, 31, 1, 14, 1, 2412, 100),
+			asciiTag(gpsTagLongitudeRef, "E"),
+			rationalTag(gpsTagLongitude, 121, 1, 28, 1, 3600, 100),
+			rationalTag(gpsTagAltitude, 45, 1),
+		},
+	)
+}
+
+func TestParseExif(t *testing.T) {
+	info, err := parseExif(testExif())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Canon", info.Make)
+	assert.Equal(t, "EOS R5", info.Model)
+	assert.Equal(t, 6, info.Orientation)
+	assert.Equal(t, "1/250", info.ExposureTime)
+	assert.Equal(t, 2.8, info.FNumber)
+	assert.Equal(t, 400, info.ISO)
+	assert.Equal(t, 50.0, info.FocalLength)
+	assert.Equal(t, "2024-05-01T14:30:00+08:00", info.DateTime)
+	require.NotNil(t, info.GPS)
+	assert.InDelta(t, 31.2400333, info.GPS.Latitude, 1e-6)
+	assert.InDelta(t, 121.4766667, info.GPS.Longitude, 1e-6)
+	require.NotNil(t, info.GPS.Altitude)
+	assert.Equal(t, 45.0, *info.GPS.Altitude)
+
+	_, err = parseExif([]byte("garbage"))
+	assert.ErrorIs(t, err, errNoExif)
+}
+
+func TestICCDescription(t *testing.T) {
+	assert.Equal(t, "sRGB IEC61966-2.1", iccDescription(buildICCProfile("desc", "sRGB IEC61966-2.1")))
+	assert.Equal(t, "Display P3", iccDescription(buildICCProfile("mluc", "Display P3")))
+	assert.Equal(t, "", iccDescription([]byte("short")))
+}
+
+func TestReadPNGMeta(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 2))))
+	pngData := buf.Bytes()
+
+	// 在 IHDR（8 字节签名 + 25 字节块）之后插入 iCCP 块
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(buildICCProfile("desc", "Adobe RGB (1998)"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	chunk := append([]byte("iCCP"), append([]byte("ICC\x00\x00"), compressed.Bytes()...)...)
+	var iccp []byte
+	iccp = binary.BigEndian.AppendUint32(iccp, uint32(len(chunk)-4))
+	iccp = append(iccp, chunk...)
+	iccp = binary.BigEndian.AppendUint32(iccp, crc32.ChecksumIEEE(chunk))
+	data := append(append(append([]byte(nil), pngData[:33]...), iccp...), pngData[33:]...)
+
+	meta, err := readImageMeta(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "png", meta.Format)
+	assert.Equal(t, 4, meta.Width)
+	assert.Equal(t, 2, meta.Height)
+	assert.Equal(t, "Gray", meta.ColorModel)
+	assert.Equal(t, "Adobe RGB (1998)", meta.ICCProfile)
+}
+
+func TestHandleMeta(t *testing.T) {
+	root := t.TempDir()
+	profile := buildICCProfile("desc", "Display P3")
+	photo := buildJPEG(t, 40, 30,
+		append(append([]byte{0xe1}, "Exif\x00\x00"...), testExif()...),
+		iccChunk(1, 2, profile[:100]),
+		iccChunk(2, 2, profile[100:]),
+	)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "photo.jpg"), photo, 0644))
+	writePNG(t, filepath.Join(root, "plain.png"), 8, 6)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "note.txt"), []byte("hello"), 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get("/api/meta?path=/photo.jpg")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp metaResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Image)
+	assert.Equal(t, "jpeg", resp.Image.Format)
+	assert.Equal(t, 30, resp.Image.Width) // 方向 6 交换宽高
+	assert.Equal(t, 40, resp.Image.Height)
+	assert.Equal(t, "YCbCr", resp.Image.ColorModel)
+	assert.Equal(t, 6, resp.Image.Orientation)
+	assert.Equal(t, "Display P3", resp.Image.ICCProfile)
+	require.NotNil(t, resp.Image.Exif)
+	assert.Equal(t, "EOS R5", resp.Image.Exif.Model)
+
+	// 非图片只返回基本信息
+	w = get("/api/meta?path=/note.txt")
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = metaResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Image)
+	assert.Equal(t, int64(5), resp.Size)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/meta?path=/missing.jpg").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/meta?path=/").Code)
+
+	// 列表附带图片尺寸
+	w = get("/api/files?path=/&withMeta=image")
+	require.Equal(t, http.StatusOK, w.Code)
+	var items []fileEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	sizes := map[string][2]int{}
+	for _, item := range items {
+		sizes[item.Name] = [2]int{item.Width, item.Height}
+	}
+	assert.Equal(t, [2]int{30, 40}, sizes["photo.jpg"])
+	assert.Equal(t, [2]int{8, 6}, sizes["plain.png"])
+	assert.Equal(t, [2]int{0, 0}, sizes["note.txt"])
+
+	// 未指定 withMeta 时不读取尺寸
+	w = get("/api/files?path=/")
+	assert.NotContains(t, w.Body.String(), `"width"`)
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 模糊匹配的评分（参考 fzf）：每个匹配字符得分，跳过的字符扣分，
+// 匹配在路径段开头、单词边界、驼峰处或连续匹配时加分，落在文件名（最后一段）中的字符额外加分
+const (
+	fuzzyScoreMatch        = 16
+	fuzzyScoreGapStart     = -3
+	fuzzyScoreGapExtension = -1
+	fuzzyBonusSegment      = 10 // 路径段开头（/ 之后或开头）
+	fuzzyBonusBoundary     = 8  // 单词边界（_、-、.、空格等之后）
+	fuzzyBonusCamel        = 7  // 驼峰（小写之后的大写）或字母之后的数字
+	fuzzyBonusConsecutive  = 4  // 连续匹配
+	fuzzyBonusBasename     = 2  // 匹配字符位于文件名中
+	fuzzyFirstCharFactor   = 2  // 模式第一个字符的位置加分倍数
+
+	maxFuzzyPatternLen = 128 // 模糊匹配模式的最大字符数
+)
+
+// fuzzyNoScore 动态规划中不可达的状态
+const fuzzyNoScore = math.MinInt32 / 2
+
+// fuzzyPattern 编译后的模糊匹配模式：模式的字符按顺序出现在路径中即匹配，中间可以间隔任意字符
+// 评分时复用内部缓冲区，不能并发使用
+type fuzzyPattern struct {
+	caseSensitive bool
+	pattern       []rune
+
+	// 评分使用的缓冲区
+	text, folded []rune
+	bonus        []int32
+	score, chunk []int32 // score[i*n+j]：模式前 i+1 个字符匹配且第 i 个字符位于 j 时的最高得分；chunk 为所在连续段开头的加分
+	from         []int32 // 取得最高得分时模式前一个字符的位置
+}
+
+// fuzzyResult 模糊搜索结果：文件信息、得分和匹配字符的位置
+type fuzzyResult struct {
+	fileEntry
+	Score     int   `json:"score"`     // 得分，越高越相关
+	Positions []int `json:"positions"` // path 中匹配字符的下标（按字符计，从 0 开始），用于高亮
+}
+
+// fuzzySearchResponse 模糊搜索响应
+type fuzzySearchResponse struct {
+	Results []fuzzyResult `json:"results"`
+	searchPage
+}
+
+// newFuzzyPattern 编译模糊匹配模式
+func newFuzzyPattern(query string, caseSensitive bool) (*fuzzyPattern, error) {
+	p := &fuzzyPattern{caseSensitive: caseSensitive, pattern: []rune(query)}
+	if len(p.pattern) > maxFuzzyPatternLen {
+		return nil, fmt.Errorf("%w: fuzzy pattern longer than %d characters", errInvalidQuery, maxFuzzyPatternLen)
+	}
+	if !caseSensitive {
+		for i, r := range p.pattern {
+			p.pattern[i] = unicode.ToLower(r)
+		}
+	}
+	return p, nil
+}
+
+// contains 快速判断模式是否是 text 的子序列，用于在评分前筛选
+func (p *fuzzyPattern) contains(text string) bool {
+	i := 0
+	for _, r := range text {
+		if i == len(p.pattern) {
+			break
+		}
+		if !p.caseSensitive {
+			r = unicode.ToLower(r)
+		}
+		if r == p.pattern[i] {
+			i++
+		}
+	}
+	return i == len(p.pattern)
+}
+
+// match 对相对路径评分，返回最高得分和匹配字符在 text 中的位置（按字符计），不匹配时返回 false
+func (p *fuzzyPattern) match(text string) (int, []int, bool) {
+	if !p.contains(text) {
+		return 0, nil, false
+	}
+	m := len(p.pattern)
+	if m == 0 {
+		return 0, []int{}, true
+	}
+
+	p.text = append(p.text[:0], []rune(text)...)
+	n := len(p.text)
+	p.folded = append(p.folded[:0], p.text...)
+	if !p.caseSensitive {
+		for j, r := range p.folded {
+			p.folded[j] = unicode.ToLower(r)
+		}
+	}
+	base := strings.LastIndexByte(text, '/')
+	base = utf8.RuneCountInString(text[:base+1]) // 文件名第一个字符的位置
+	p.bonus = p.bonus[:0]
+	prev := '/'
+	for _, r := range p.text {
+		p.bonus = append(p.bonus, int32(fuzzyCharBonus(prev, r)))
+		prev = r
+	}
+	p.score = resizeInt32(p.score, m*n)
+	p.chunk = resizeInt32(p.chunk, m*n)
+	p.from = resizeInt32(p.from, m*n)
+
+	for i := 0; i < m; i++ {
+		// gap：模式前一个字符位于 k <= j-2（中间至少跳过一个字符）时的最高得分（已扣除跳过的字符）
+		gap, gapFrom := int32(fuzzyNoScore), int32(-1)
+		for j := 0; j < n; j++ {
+			cell := i*n + j
+			if i > 0 && j >= 2 {
+				if gap != fuzzyNoScore {
+					gap += fuzzyScoreGapExtension
+				}
+				if s := p.score[cell-n-2]; s != fuzzyNoScore && s+fuzzyScoreGapStart > gap {
+					gap, gapFrom = s+fuzzyScoreGapStart, int32(j-2)
+				}
+			}
+
+			p.score[cell] = fuzzyNoScore
+			if j < i || p.folded[j] != p.pattern[i] {
+				continue
+			}
+			b := p.bonus[j]
+			best, from, chunk := int32(fuzzyNoScore), int32(-1), b
+			if i == 0 {
+				best = fuzzyScoreMatch + b*fuzzyFirstCharFactor
+			} else {
+				// 连续匹配：沿用所在连续段开头的加分
+				if s := p.score[cell-n-1]; j > 0 && s != fuzzyNoScore {
+					c := p.chunk[cell-n-1]
+					if b >= fuzzyBonusBoundary && b > c {
+						c = b
+					}
+					best, from, chunk = s+fuzzyScoreMatch+max(c, b, fuzzyBonusConsecutive), int32(j-1), c
+				}
+				if gap != fuzzyNoScore && gap+fuzzyScoreMatch+b > best {
+					best, from, chunk = gap+fuzzyScoreMatch+b, gapFrom, b
+				}
+				if best == fuzzyNoScore {
+					continue
+				}
+			}
+			if j >= base {
+				best += fuzzyBonusBasename
+			}
+			p.score[cell], p.from[cell], p.chunk[cell] = best, from, chunk
+		}
+	}
+
+	// 取最后一个模式字符得分最高的位置，回溯得到全部位置
+	last := int32(-1)
+	for j := m - 1; j < n; j++ {
+		if s := p.score[(m-1)*n+j]; s != fuzzyNoScore && (last < 0 || s > p.score[(m-1)*n+int(last)]) {
+			last = int32(j)
+		}
+	}
+	if last < 0 {
+		return 0, nil, false
+	}
+	score := int(p.score[(m-1)*n+int(last)])
+	positions := make([]int, m)
+	for i, j := m-1, last; i >= 0; i-- {
+		positions[i] = int(j)
+		j = p.from[i*n+int(j)]
+	}
+	return score, positions, true
+}
+
+// fuzzyCharBonus 根据前一个字符计算位置加分
+func fuzzyCharBonus(prev, cur rune) int {
+	if !isFuzzyWordRune(cur) {
+		return 0
+	}
+	switch {
+	case prev == '/':
+		return fuzzyBonusSegment
+	case !isFuzzyWordRune(prev):
+		return fuzzyBonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur), !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return fuzzyBonusCamel
+	}
+	return 0
+}
+
+// isFuzzyWordRune 字母和数字属于单词，下划线等符号作为单词边界
+func isFuzzyWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// resizeInt32 返回长度为 n 的切片，尽量复用 buf
+func resizeInt32(buf []int32, n int) []int32 {
+	if cap(buf) < n {
+		return make([]int32, n)
+	}
+	return buf[:n]
+}
+
+// fuzzyRank 结果的排序键：得分高的在前，得分相同时路径短的在前，再按路径排序
+type fuzzyRank struct {
+	score int
+	rel   string // 相对搜索目录的路径
+}
+
+// before 判断 r 是否排在 o 之前
+func (r fuzzyRank) before(o fuzzyRank) bool {
+	if r.score != o.score {
+		return r.score > o.score
+	}
+	if len(r.rel) != len(o.rel) {
+		return len(r.rel) < len(o.rel)
+	}
+	return r.rel < o.rel
+}
+
+// parseFuzzyCursor 解析模糊搜索的游标（上一页最后一个结果的得分和相对路径），空字符串返回 nil
+func parseFuzzyCursor(after string) (*fuzzyRank, error) {
+	if after == "" {
+		return nil, nil
+	}
+	score, rel, ok := strings.Cut(after, ":")
+	n, err := strconv.Atoi(score)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("%w: not a fuzzy search cursor", errInvalidCursor)
+	}
+	return &fuzzyRank{score: n, rel: rel}, nil
+}
+
+// fuzzyCollector 对候选条目评分，保留排在游标之后的前 limit+1 个结果
+type fuzzyCollector struct {
+	pattern *fuzzyPattern
+	pinyin  bool // 路径不匹配时按拼音评分
+	after   *fuzzyRank
+	limit   int
+	found   fuzzyHeap
+	origin  []int // 拼音中每个字符对应的原字符下标，复用的缓冲区
+}
+
+// add 对相对路径为 rel 的条目评分，进入前 limit+1 名时调用 entry 获取文件信息
+func (fc *fuzzyCollector) add(rel string, entry func() fileEntry) {
+	score, positions, matchedBy := fc.score(rel)
+	if matchedBy == "" {
+		return
+	}
+	rank := fuzzyRank{score: score, rel: rel}
+	if fc.after != nil && !fc.after.before(rank) {
+		return
+	}
+	if len(fc.found) > fc.limit && !rank.before(fc.found[0].rank) {
+		return
+	}
+	item := entry()
+	item.MatchedBy = matchedBy
+	// 位置从相对路径换算为 path 中的位置
+	offset := utf8.RuneCountInString(item.Path) - utf8.RuneCountInString(rel)
+	for i := range positions {
+		positions[i] += offset
+	}
+	result := fuzzyRankedResult{rank: rank, result: fuzzyResult{fileEntry: item, Score: score, Positions: positions}}
+	if len(fc.found) <= fc.limit {
+		heap.Push(&fc.found, result)
+	} else {
+		fc.found[0] = result
+		heap.Fix(&fc.found, 0)
+	}
+}
+
+// score 对相对路径评分，返回得分、匹配字符的位置和匹配方式，不匹配时匹配方式为空
+// 路径本身不匹配时分别按全拼和拼音首字母评分，取得分高的一种，位置换算为原路径中的字符
+func (fc *fuzzyCollector) score(rel string) (int, []int, string) {
+	if score, positions, ok := fc.pattern.match(rel); ok {
+		return score, positions, matchByName
+	}
+	if !fc.pinyin {
+		return 0, nil, ""
+	}
+	best, bestPositions, matchedBy := 0, []int(nil), ""
+	for _, kind := range []string{matchByPinyin, matchByInitials} {
+		fc.origin = fc.origin[:0]
+		text, ok := transliteratePinyin(rel, kind == matchByInitials, &fc.origin)
+		if !ok {
+			break // 不含汉字
+		}
+		score, positions, ok := fc.pattern.match(text)
+		if !ok || (matchedBy != "" && score <= best) {
+			continue
+		}
+		// 一个汉字的拼音有多个字母，只保留一次
+		mapped := positions[:0]
+		for _, p := range positions {
+			if o := fc.origin[p]; len(mapped) == 0 || mapped[len(mapped)-1] != o {
+				mapped = append(mapped, o)
+			}
+		}
+		best, bestPositions, matchedBy = score, mapped, kind
+	}
+	return best, bestPositions, matchedBy
+}
+
+// results 按排名返回结果
+func (fc *fuzzyCollector) results() []fuzzyResult {
+	sort.Slice(fc.found, func(i, j int) bool { return fc.found[i].rank.before(fc.found[j].rank) })
+	results := make([]fuzzyResult, len(fc.found))
+	for i, r := range fc.found {
+		results[i] = r.result
+	}
+	return results
+}
+
+// fuzzyRankedResult 带排序键的结果
+type fuzzyRankedResult struct {
+	rank   fuzzyRank
+	result fuzzyResult
+}
+
+// fuzzyHeap 按排名的最大堆，堆顶是排名最靠后的结果
+type fuzzyHeap []fuzzyRankedResult
+
+func (h fuzzyHeap) Len() int           { return len(h) }
+func (h fuzzyHeap) Less(i, j int) bool { return h[j].rank.before(h[i].rank) }
+func (h fuzzyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *fuzzyHeap) Push(x any)        { *h = append(*h, x.(fuzzyRankedResult)) }
+func (h *fuzzyHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// handleFuzzySearch 模糊搜索文件名
+// GET /api/search?path=/&q=hndlrtst&mode=fuzzy[&recursive=true&caseSensitive=true&limit=100&cursor=]
+// 按相对搜索目录的路径做 fzf 风格的模糊匹配，需要检查全部条目，结果按得分从高到低排列（得分相同时路径短的在前），
+// 并返回 path 中匹配字符的位置
+func (s *Server) handleFuzzySearch(c *gin.Context, q *nameSearch, recursive bool) {
+	after, err := parseFuzzyCursor(q.after)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_CURSOR", err.Error())
+		return
+	}
+	fc := &fuzzyCollector{pattern: q.match.fuzzy, pinyin: q.match.pinyin != nil, after: after, limit: q.limit}
+
+	// 排名与遍历顺序无关，遍历时不按游标跳过，也不提前停止
+	walk := *q
+	walk.after = ""
+	var scanned int
+	ok := false
+	if recursive {
+		scanned, ok = s.names.scan(&walk, func(e *nameEntry, rel, _ string) {
+			fc.add(rel, e.fileEntry)
+		})
+	}
+	if !ok {
+		collect := func(item fileEntry) bool {
+			fc.add(walk.relOf(item), func() fileEntry { return item })
+			return true
+		}
+		if recursive {
+			scanned = s.walkSearch(c.Request.Context(), &walk, collect, nil)
+		} else {
+			scanned = s.readDirSearch(&walk, collect)
+		}
+	}
+	if c.Request.Context().Err() != nil {
+		c.Abort() // 客户端已断开
+		return
+	}
+
+	resp := fuzzySearchResponse{}
+	resp.Results, resp.searchPage = newSearchPage(fc.results(), q.limit, scanned, func(r fuzzyResult) string {
+		return strconv.Itoa(r.Score) + ":" + q.relOf(r.fileEntry)
+	})
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyPattern_Match(t *testing.T) {
+	p, err := newFuzzyPattern("hndlrtst", false)
+	require.NoError(t, err)
+	score, positions, ok := p.match("handlers_test.go")
+	require.True(t, ok)
+	assert.Positive(t, score)
+	assert.Equal(t, []int{0, 2, 3, 4, 6, 9, 11, 12}, positions)
+
+	_, _, ok = p.match("handlers.go")
+	assert.False(t, ok)
+
+	// 位置按字符计
+	p, err = newFuzzyPattern("报告", false)
+	require.NoError(t, err)
+	_, positions, ok = p.match("文档/年度报告.md")
+	require.True(t, ok)
+	assert.Equal(t, []int{5, 6}, positions)
+
+	// 区分大小写
+	p, err = newFuzzyPattern("RM", true)
+	require.NoError(t, err)
+	_, _, ok = p.match("readme.md")
+	assert.False(t, ok)
+	_, positions, ok = p.match("README.md")
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 4}, positions)
+
+	_, err = newFuzzyPattern(string(make([]rune, maxFuzzyPatternLen+1)), false)
+	assert.ErrorIs(t, err, errInvalidQuery)
+}
+
+func TestFuzzyPattern_Ranking(t *testing.T) {
+	score := func(pattern, text string) int {
+		p, err := newFuzzyPattern(pattern, false)
+		require.NoError(t, err)
+		s, _, ok := p.match(text)
+		require.True(t, ok, text)
+		return s
+	}
+
+	tests := []struct {
+		name          string
+		pattern       string
+		better, worse string
+	}{
+		{"consecutive", "abc", "abc.txt", "axbxc.txt"},
+		{"word boundary", "ft", "file_test.go", "fileset.go"},
+		{"camel case", "fb", "FooBar.go", "foobar.go"},
+		{"path segment", "sh", "src/handlers.go", "fresh.go"},
+		{"basename", "test", "foo/test.go", "test/foo.go"},
+		{"first char boundary", "cfg", "cfg.yaml", "xcfg.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Greater(t, score(tt.pattern, tt.better), score(tt.pattern, tt.worse))
+		})
+	}
+
+	// 得分相同时路径短的在前
+	assert.True(t, fuzzyRank{score: 10, rel: "a/b.go"}.before(fuzzyRank{score: 10, rel: "a/bb.go"}))
+	assert.True(t, fuzzyRank{score: 11, rel: "a/bb.go"}.before(fuzzyRank{score: 10, rel: "a/b.go"}))
+	assert.True(t, fuzzyRank{score: 10, rel: "a/b.go"}.before(fuzzyRank{score: 10, rel: "a/c.go"}))
+}
+
+func TestHandleSearch_Fuzzy(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"internal/server/handlers_test.go",
+		"internal/server/handlers.go",
+		"internal/server/tail_test.go",
+		"handlers_test.go",
+		"docs/hxnxdxlxrxtxsxt.txt",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte("x"), 0644))
+	}
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string) (*httptest.ResponseRecorder, fuzzySearchResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp fuzzySearchResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+	paths := func(results []fuzzyResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Path)
+		}
+		return out
+	}
+
+	// 按得分排序，得分相同时路径短的在前
+	w, resp := get("/api/search?path=/&q=hndlrtst&mode=fuzzy&recursive=true")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, []string{"/handlers_test.go", "/internal/server/handlers_test.go", "/docs/hxnxdxlxrxtxsxt.txt"}, paths(resp.Results))
+	assert.GreaterOrEqual(t, resp.Results[0].Score, resp.Results[1].Score)
+	assert.Greater(t, resp.Results[1].Score, resp.Results[2].Score)
+	assert.Equal(t, []int{1, 3, 4, 5, 7, 10, 12, 13}, resp.Results[0].Positions)
+	assert.Equal(t, []int{17, 19, 20, 21, 23, 26, 28, 29}, resp.Results[1].Positions) // path 中的位置
+	assert.Equal(t, 8, resp.Scanned)
+	assert.False(t, resp.Truncated)
+
+	// 在子目录下搜索时按相对路径匹配，位置仍对应 path
+	_, resp = get("/api/search?path=/internal&q=srvtail&mode=fuzzy&recursive=true")
+	require.Equal(t, []string{"/internal/server/tail_test.go"}, paths(resp.Results))
+	assert.Equal(t, []int{10, 12, 13, 17, 18, 19, 20}, resp.Results[0].Positions)
+
+	// 单层目录
+	_, resp = get("/api/search?path=/internal/server&q=hndl&mode=fuzzy")
+	assert.Equal(t, []string{"/internal/server/handlers.go", "/internal/server/handlers_test.go"}, paths(resp.Results))
+
+	// 按排名分页
+	var all []string
+	cursor := ""
+	for range 4 {
+		_, resp = get("/api/search?path=/&q=hndlrtst&mode=fuzzy&recursive=true&limit=1&cursor=" + url.QueryEscape(cursor))
+		all = append(all, paths(resp.Results)...)
+		if !resp.Truncated {
+			break
+		}
+		cursor = resp.Cursor
+	}
+	assert.Equal(t, []string{"/handlers_test.go", "/internal/server/handlers_test.go", "/docs/hxnxdxlxrxtxsxt.txt"}, all)
+
+	// 其他模式的游标和流式输出
+	w, _ = get("/api/search?path=/&q=x&mode=fuzzy&cursor=" + encodeSearchCursor("a/b"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_CURSOR")
+	w, _ = get("/api/search?path=/&q=x&mode=fuzzy&stream=ndjson")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
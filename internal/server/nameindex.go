@@ -0,0 +1,585 @@
+package server
+
+import (
+	"container/heap"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	nameIndexVersion         = 2                // 持久化格式版本，格式变化时递增
+	nameIndexPersistInterval = time.Minute      // 索引有变化时写入磁盘的间隔
+	nameIndexRescanInterval  = 10 * time.Minute // 无法监听全部目录时定期重新构建的间隔
+)
+
+// nameEntry 文件名索引中的一个文件或目录
+// 字段导出以便使用 gob 持久化
+type nameEntry struct {
+	Path     string // 相对根目录的路径（/ 分隔，不含前导 /）
+	Lower    string // 小写的文件名，用于不区分大小写匹配
+	Pinyin   string // 文件名的全拼（见 toPinyin），不含汉字时为空
+	Initials string // 文件名的拼音首字母，不含汉字时为空
+	Size     int64  // 文件大小（目录为 0）
+	ModTime  int64  // 修改时间（Unix 秒）
+	Dir      bool   // 是否为目录
+}
+
+// fileEntry 转换为搜索结果
+func (e *nameEntry) fileEntry() fileEntry {
+	name := path.Base(e.Path)
+	item := fileEntry{
+		Name:     name,
+		Path:     "/" + e.Path,
+		Modified: time.Unix(e.ModTime, 0).UTC().Format(time.RFC3339),
+	}
+	if e.Dir {
+		item.Type = "dir"
+	} else {
+		item.Type = "file"
+		item.Size = e.Size
+		_, item.Archive = archiveFormatOf(name)
+	}
+	return item
+}
+
+// newNameEntry 根据 Lstat 的结果创建索引条目
+func newNameEntry(rel string, info os.FileInfo) *nameEntry {
+	py := toPinyin(info.Name())
+	e := &nameEntry{
+		Path:     rel,
+		Lower:    strings.ToLower(info.Name()),
+		Pinyin:   py.full,
+		Initials: py.initials,
+		ModTime:  info.ModTime().Unix(),
+		Dir:      info.IsDir(),
+	}
+	if !e.Dir {
+		e.Size = info.Size()
+	}
+	return e
+}
+
+// nameIndexFile 持久化的索引文件内容
+type nameIndexFile struct {
+	Version int
+	Root    string
+	Built   time.Time
+	Entries []*nameEntry
+}
+
+// nameIndexStatus 索引状态
+type nameIndexStatus struct {
+	Enabled    bool   `json:"enabled"`              // 是否启用文件名索引
+	Ready      bool   `json:"ready"`                // 索引是否可用于搜索（从磁盘加载或首次构建完成后）
+	Building   bool   `json:"building"`             // 是否正在后台构建
+	Files      int    `json:"files"`                // 索引中的文件数
+	Dirs       int    `json:"dirs"`                 // 索引中的目录数
+	LastBuild  string `json:"lastBuild,omitempty"`  // 最近一次完整构建完成的时间
+	BuildMs    int64  `json:"buildMs,omitempty"`    // 最近一次完整构建的耗时（毫秒）
+	LastUpdate string `json:"lastUpdate,omitempty"` // 索引最近一次变化的时间
+	Watching   bool   `json:"watching"`             // 是否监听到了全部目录的变化
+	WatchError string `json:"watchError,omitempty"` // 监听失败的原因（例如超出 inotify 监听数量上限），此时定期重新构建
+	Persisted  bool   `json:"persisted"`            // 是否持久化到缓存目录
+}
+
+// nameIndex 根目录下所有路径的内存索引
+// 启动时后台遍历构建（有持久化的索引时先加载，构建期间即可搜索），之后通过 fsnotify 事件增量更新
+type nameIndex struct {
+	root      string
+	cachePath string // 持久化文件路径，为空时不持久化
+	watcher   *fsnotify.Watcher
+
+	mu            sync.RWMutex
+	entries       []*nameEntry
+	byPath        map[string]int // 路径 -> entries 下标
+	dirs          int
+	ready         bool
+	building      bool
+	dirty         bool // 有尚未持久化的变化
+	lastBuild     time.Time
+	buildDuration time.Duration
+	lastUpdate    time.Time
+	watchErr      error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newNameIndex 创建索引并在后台开始构建，cacheDir 为空时不持久化
+func newNameIndex(root, cacheDir string) *nameIndex {
+	idx := &nameIndex{
+		root:   root,
+		byPath: make(map[string]int),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(root))
+		idx.cachePath = filepath.Join(cacheDir, "index", hex.EncodeToString(sum[:8])+".gob")
+		idx.load()
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		idx.watchErr = err
+	} else {
+		idx.watcher = watcher
+	}
+	go idx.run()
+	return idx
+}
+
+// close 停止监听并持久化尚未写入的变化
+func (idx *nameIndex) close() error {
+	close(idx.stop)
+	<-idx.done
+	if idx.watcher != nil {
+		idx.watcher.Close()
+	}
+	return idx.save()
+}
+
+// run 处理构建结果、文件系统事件和定时任务，是唯一修改索引内容的 goroutine
+func (idx *nameIndex) run() {
+	defer close(idx.done)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if idx.watcher != nil {
+		events, errs = idx.watcher.Events, idx.watcher.Errors
+	}
+
+	// 构建期间收到的事件只记录路径，构建完成后重新检查，避免被新的索引覆盖
+	built := make(chan []*nameEntry, 1)
+	var pending map[string]struct{}
+	startBuild := func() {
+		if pending != nil {
+			return
+		}
+		pending = make(map[string]struct{})
+		idx.mu.Lock()
+		idx.building = true
+		idx.mu.Unlock()
+		go func() {
+			start := time.Now()
+			entries := idx.walk("")
+			idx.mu.Lock()
+			idx.buildDuration = time.Since(start)
+			idx.mu.Unlock()
+			built <- entries
+		}()
+	}
+	startBuild()
+
+	persist := time.NewTicker(nameIndexPersistInterval)
+	defer persist.Stop()
+	rescan := time.NewTicker(nameIndexRescanInterval)
+	defer rescan.Stop()
+
+	for {
+		select {
+		case <-idx.stop:
+			return
+		case entries := <-built:
+			idx.replace(entries)
+			for rel := range pending {
+				idx.refresh(rel)
+			}
+			pending = nil
+			idx.save()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			rel, ok := idx.relPath(event.Name)
+			if !ok {
+				continue
+			}
+			if pending != nil {
+				pending[rel] = struct{}{}
+			} else {
+				idx.refresh(rel)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				startBuild() // 事件队列溢出，部分变化已丢失
+				continue
+			}
+			idx.mu.Lock()
+			idx.watchErr = err
+			idx.mu.Unlock()
+		case <-persist.C:
+			idx.save()
+		case <-rescan.C:
+			idx.mu.RLock()
+			limited := idx.watchErr != nil
+			idx.mu.RUnlock()
+			if limited {
+				startBuild()
+			}
+		}
+	}
+}
+
+// walk 遍历 rel 对应的子树并监听其中的目录，返回子树中的条目（rel 为空时不含根目录本身）
+// 跳过符号链接和无法读取的目录
+func (idx *nameIndex) walk(rel string) []*nameEntry {
+	var entries []*nameEntry
+	start := filepath.Join(idx.root, filepath.FromSlash(rel))
+	filepath.WalkDir(start, func(walkPath string, d fs.DirEntry, err error) error {
+		select {
+		case <-idx.stop:
+			return filepath.SkipAll
+		default:
+		}
+		if err != nil || d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+		if d.IsDir() {
+			// 先监听再读取目录内容，读取期间新建的文件也能收到事件
+			idx.watch(walkPath)
+		}
+		itemRel, ok := idx.relPath(walkPath)
+		if !ok || itemRel == "" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, newNameEntry(itemRel, info))
+		return nil
+	})
+	return entries
+}
+
+// watch 监听目录，失败时记录原因（此后依靠定期重新构建保持索引更新）
+func (idx *nameIndex) watch(dir string) {
+	if idx.watcher == nil {
+		return
+	}
+	if err := idx.watcher.Add(dir); err != nil {
+		idx.mu.Lock()
+		if idx.watchErr == nil {
+			idx.watchErr = err
+		}
+		idx.mu.Unlock()
+	}
+}
+
+// relPath 把绝对路径转换为相对根目录的 / 分隔路径
+func (idx *nameIndex) relPath(absPath string) (string, bool) {
+	rel, err := filepath.Rel(idx.root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// replace 用完整构建的结果替换索引
+func (idx *nameIndex) replace(entries []*nameEntry) {
+	byPath := make(map[string]int, len(entries))
+	dirs := 0
+	for i, e := range entries {
+		byPath[e.Path] = i
+		if e.Dir {
+			dirs++
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries, idx.byPath, idx.dirs = entries, byPath, dirs
+	idx.ready = true
+	idx.building = false
+	idx.dirty = true
+	idx.lastBuild = time.Now()
+	idx.lastUpdate = idx.lastBuild
+}
+
+// refresh 按磁盘上的当前状态更新一个路径：不存在时删除（包括子树），新目录遍历整个子树
+func (idx *nameIndex) refresh(rel string) {
+	if rel == "" {
+		return
+	}
+	info, err := os.Lstat(filepath.Join(idx.root, filepath.FromSlash(rel)))
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		idx.mu.Lock()
+		idx.remove(rel)
+		idx.mu.Unlock()
+		return
+	}
+
+	idx.mu.RLock()
+	i, exists := idx.byPath[rel]
+	wasDir := exists && idx.entries[i].Dir
+	idx.mu.RUnlock()
+
+	entries := []*nameEntry{newNameEntry(rel, info)}
+	if info.IsDir() && !wasDir {
+		// 新建或移入的目录：其中的内容不会再有单独的事件
+		entries = idx.walk(rel)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if exists && wasDir != info.IsDir() {
+		idx.remove(rel)
+	}
+	for _, e := range entries {
+		idx.put(e)
+	}
+}
+
+// put 插入或更新条目，调用方需持有写锁
+func (idx *nameIndex) put(e *nameEntry) {
+	if i, ok := idx.byPath[e.Path]; ok {
+		if idx.entries[i].Dir {
+			idx.dirs--
+		}
+		idx.entries[i] = e
+	} else {
+		idx.byPath[e.Path] = len(idx.entries)
+		idx.entries = append(idx.entries, e)
+	}
+	if e.Dir {
+		idx.dirs++
+	}
+	idx.dirty = true
+	idx.lastUpdate = time.Now()
+}
+
+// remove 删除条目，目录连同其子树一起删除，调用方需持有写锁
+func (idx *nameIndex) remove(rel string) {
+	i, ok := idx.byPath[rel]
+	if !ok {
+		return
+	}
+	idx.dirty = true
+	idx.lastUpdate = time.Now()
+
+	if !idx.entries[i].Dir {
+		// 单个文件：用最后一个条目填补空位
+		last := len(idx.entries) - 1
+		if i != last {
+			idx.entries[i] = idx.entries[last]
+			idx.byPath[idx.entries[i].Path] = i
+		}
+		idx.entries[last] = nil
+		idx.entries = idx.entries[:last]
+		delete(idx.byPath, rel)
+		return
+	}
+
+	prefix := rel + "/"
+	kept := idx.entries[:0]
+	for _, e := range idx.entries {
+		if e.Path == rel || strings.HasPrefix(e.Path, prefix) {
+			delete(idx.byPath, e.Path)
+			if e.Dir {
+				idx.dirs--
+			}
+			continue
+		}
+		idx.byPath[e.Path] = len(kept)
+		kept = append(kept, e)
+	}
+	clear(idx.entries[len(kept):])
+	idx.entries = kept
+}
+
+// search 在 q.relPath 下按文件名搜索，返回按遍历顺序排列的结果和检查的条目数，索引不可用时返回 false
+// 索引中的条目没有固定顺序，需要检查全部条目，用大小为 limit+1 的堆保留遍历顺序最靠前的结果
+func (idx *nameIndex) search(q *nameSearch) ([]fileEntry, int, bool) {
+	found := &walkOrderHeap{}
+	scanned, ok := idx.scan(q, func(e *nameEntry, _, matchedBy string) {
+		if found.Len() <= q.limit {
+			heap.Push(found, indexMatch{e, matchedBy})
+		} else if walkOrderLess(e.Path, (*found)[0].entry.Path) {
+			(*found)[0] = indexMatch{e, matchedBy}
+			heap.Fix(found, 0)
+		}
+	})
+	if !ok {
+		return nil, 0, false
+	}
+
+	results := make([]fileEntry, found.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		m := heap.Pop(found).(indexMatch)
+		results[i] = m.entry.fileEntry()
+		results[i].MatchedBy = m.matchedBy
+	}
+	return results, scanned, true
+}
+
+// scan 检查 q.relPath 下游标之后的全部条目，对匹配查询和过滤条件的条目调用 fn
+// （rel 为相对搜索目录的路径，matchedBy 为匹配方式）；返回检查的条目数，索引不可用时返回 false。fn 在持有读锁时调用
+func (idx *nameIndex) scan(q *nameSearch, fn func(e *nameEntry, rel, matchedBy string)) (int, bool) {
+	if idx == nil {
+		return 0, false
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if !idx.ready {
+		return 0, false
+	}
+
+	prefix := ""
+	if q.relPath != "" {
+		prefix = q.relPath + "/"
+	}
+	scanned := 0
+	for _, e := range idx.entries {
+		if !strings.HasPrefix(e.Path, prefix) {
+			continue
+		}
+		rel := strings.TrimPrefix(e.Path, prefix)
+		if !q.filter.withinDepth(strings.Count(rel, "/")+1) || (q.after != "" && !walkOrderLess(q.after, rel)) ||
+			q.ignore.isIgnoredPath(e.Path, e.Dir, q.relPath) {
+			continue
+		}
+		scanned++
+		// 不区分大小写时直接使用预先转换的小写文件名，避免重复转换；拼音同样使用预先转换的结果
+		name := e.Lower
+		if q.match.caseSensitive {
+			name = path.Base(e.Path)
+		}
+		matchedBy := q.match.matchedBy(name, rel, &pinyinName{full: e.Pinyin, initials: e.Initials})
+		if matchedBy == "" || !q.filter.matches(name, e.Dir, e.Size, time.Unix(e.ModTime, 0)) {
+			continue
+		}
+		fn(e, rel, matchedBy)
+	}
+	return scanned, true
+}
+
+// indexMatch 索引中匹配的条目和匹配方式
+type indexMatch struct {
+	entry     *nameEntry
+	matchedBy string
+}
+
+// walkOrderHeap 按遍历顺序的最大堆，堆顶是遍历顺序最靠后的条目
+type walkOrderHeap []indexMatch
+
+func (h walkOrderHeap) Len() int           { return len(h) }
+func (h walkOrderHeap) Less(i, j int) bool { return walkOrderLess(h[j].entry.Path, h[i].entry.Path) }
+func (h walkOrderHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *walkOrderHeap) Push(x any)        { *h = append(*h, x.(indexMatch)) }
+func (h *walkOrderHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// status 返回索引状态
+func (idx *nameIndex) status() nameIndexStatus {
+	if idx == nil {
+		return nameIndexStatus{}
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	st := nameIndexStatus{
+		Enabled:   true,
+		Ready:     idx.ready,
+		Building:  idx.building,
+		Files:     len(idx.entries) - idx.dirs,
+		Dirs:      idx.dirs,
+		Watching:  idx.watchErr == nil,
+		Persisted: idx.cachePath != "",
+	}
+	if idx.watchErr != nil {
+		st.WatchError = idx.watchErr.Error()
+	}
+	if !idx.lastBuild.IsZero() {
+		st.LastBuild = idx.lastBuild.UTC().Format(time.RFC3339)
+		st.BuildMs = idx.buildDuration.Milliseconds()
+	}
+	if !idx.lastUpdate.IsZero() {
+		st.LastUpdate = idx.lastUpdate.UTC().Format(time.RFC3339)
+	}
+	return st
+}
+
+// load 加载持久化的索引，文件不存在或格式不匹配时忽略
+func (idx *nameIndex) load() {
+	file, err := os.Open(idx.cachePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var data nameIndexFile
+	if err := gob.NewDecoder(file).Decode(&data); err != nil || data.Version != nameIndexVersion || data.Root != idx.root {
+		return
+	}
+	idx.replace(data.Entries)
+	idx.dirty = false
+	idx.lastBuild = data.Built
+	idx.lastUpdate = data.Built
+}
+
+// save 有变化时把索引写入缓存目录，先写临时文件再重命名
+func (idx *nameIndex) save() error {
+	if idx.cachePath == "" {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if !idx.dirty || !idx.ready {
+		return nil
+	}
+
+	dir := filepath.Dir(idx.cachePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	data := nameIndexFile{Version: nameIndexVersion, Root: idx.root, Built: idx.lastUpdate, Entries: idx.entries}
+	if err := gob.NewEncoder(tmp).Encode(&data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), idx.cachePath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// 持有读锁期间只有 run 会修改索引，而 save 只在 run 中或 run 退出后调用
+	idx.dirty = false
+	return nil
+}
+
+// handleIndexStatus 返回文件名索引的状态
+// GET /api/index/status
+func (s *Server) handleIndexStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.names.status())
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameIndex(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs", "api"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "api", "Report.md"), []byte("# report"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "readme.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(root, "readme.txt"), filepath.Join(root, "report-link")))
+
+	server, err := New(Config{Root: root, CacheDir: cacheDir, NameIndex: true})
+	require.NoError(t, err)
+	router := server.Handler()
+	search := func(target string) []string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp searchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var paths []string
+		for _, r := range resp.Results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+	status := func() nameIndexStatus {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/index/status", nil))
+		var st nameIndexStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &st))
+		return st
+	}
+
+	require.Eventually(t, func() bool { return status().Ready }, 5*time.Second, 10*time.Millisecond)
+	st := status()
+	assert.True(t, st.Enabled)
+	assert.False(t, st.Building)
+	assert.Equal(t, 2, st.Files)
+	assert.Equal(t, 2, st.Dirs)
+	assert.True(t, st.Watching)
+	assert.True(t, st.Persisted)
+	assert.NotEmpty(t, st.LastBuild)
+
+	// 不区分大小写，跳过符号链接，可限定目录
+	assert.Equal(t, []string{"/docs/api/Report.md"}, search("/api/search?q=REPORT&recursive=true"))
+	assert.Equal(t, []string{"/docs/api"}, search("/api/search?path=/docs&q=api&recursive=true"))
+	assert.Empty(t, search("/api/search?path=/docs&q=readme&recursive=true"))
+	assert.Equal(t, []string{"/docs/api/Report.md"}, search("/api/search?path=/docs&q=api/*.MD&mode=glob&recursive=true"))
+	assert.Empty(t, search("/api/search?q=report.md&mode=exact&caseSensitive=true&recursive=true"))
+	assert.Equal(t, []string{"/docs", "/readme.txt"}, search("/api/search?maxDepth=1&recursive=true"))
+	assert.Equal(t, []string{"/docs/api/Report.md"}, search("/api/search?ext=md&minSize=5&recursive=true"))
+	assert.Equal(t, []string{"/docs/api", "/docs/api/Report.md"}, search("/api/search?q=dapi&mode=fuzzy&recursive=true"))
+
+	// 索引中的条目没有固定顺序，分页结果仍按遍历顺序排列
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search?type=dir&limit=1&recursive=true", nil))
+	var page searchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, "/docs", page.Results[0].Path)
+	assert.True(t, page.Truncated)
+	assert.Equal(t, []string{"/docs/api"}, search("/api/search?type=dir&limit=1&recursive=true&cursor="+page.Cursor))
+
+	// 新建文件和目录、删除和重命名通过事件更新索引
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "new-report.txt"), nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "logs", "2026"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "logs", "2026", "app-report.log"), nil, 0644))
+	require.Eventually(t, func() bool {
+		return len(search("/api/search?q=report&recursive=true")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Rename(filepath.Join(root, "logs"), filepath.Join(root, "archive")))
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "docs")))
+	require.Eventually(t, func() bool {
+		paths := search("/api/search?q=report&recursive=true")
+		return len(paths) == 1 && paths[0] == "/archive/2026/app-report.log"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		st := status()
+		return st.Files == 2 && st.Dirs == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 关闭时持久化，重新启动后在构建完成前即可使用
+	require.NoError(t, server.Close())
+	idx := &nameIndex{root: root, byPath: map[string]int{}}
+	idx.cachePath = server.names.cachePath
+	idx.load()
+	assert.True(t, idx.ready)
+	match, err := newNameQuery("app-report", "", false, false, true)
+	require.NoError(t, err)
+	results, scanned, ok := idx.search(&nameSearch{match: match, limit: 10})
+	assert.Equal(t, 4, scanned)
+	require.True(t, ok)
+	require.Len(t, results, 1)
+	assert.Equal(t, "/archive/2026/app-report.log", results[0].Path)
+}
+
+func TestNameIndex_Disabled(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), nil, 0644))
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/index/status", nil))
+	assert.JSONEq(t, `{"enabled":false,"ready":false,"building":false,"files":0,"dirs":0,"watching":false,"persisted":false}`, w.Body.String())
+	assert.NoError(t, server.Close())
+}
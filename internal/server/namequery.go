@@ -0,0 +1,238 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 文件名搜索模式
+const (
+	nameModeSubstring = "substring" // 子串匹配（默认）
+	nameModeExact     = "exact"     // 完整文件名匹配
+	nameModeGlob      = "glob"      // glob 模式，例如 *.log、**/*.yaml
+	nameModeRegex     = "regex"     // RE2 正则表达式
+	nameModeFuzzy     = "fuzzy"     // fzf 风格的模糊匹配，结果按得分排序
+)
+
+// nameQuery 编译后的文件名查询，在一次搜索中复用
+type nameQuery struct {
+	caseSensitive bool
+	matchPath     bool              // glob 含 / 时按相对搜索目录的路径匹配，否则按文件名匹配
+	match         func(string) bool // 对文件名（或相对路径）判断是否匹配
+	fuzzy         *fuzzyPattern     // fuzzy 模式的匹配模式，用于评分
+	pinyin        func(string) bool // 对文件名（或相对路径）的拼音形式判断是否匹配，为 nil 时不按拼音匹配
+}
+
+// newNameQuery 按模式编译查询
+// wholeWord 只对 substring 和 regex 模式有效：匹配的前后必须不是字母、数字或下划线；
+// fuzzy 模式按相对路径匹配，这里只做子序列筛选，评分和排序见 handleFuzzySearch；
+// pinyin 为 true 且查询不含汉字时，substring（非整词）和 fuzzy 模式同时按汉字的全拼和首字母匹配；
+// 查询为空（只按过滤条件搜索）时匹配所有条目，忽略 wholeWord
+func newNameQuery(query, mode string, caseSensitive, wholeWord, pinyin bool) (*nameQuery, error) {
+	q := &nameQuery{caseSensitive: caseSensitive}
+	if query == "" {
+		// 空字符串在每个位置都能匹配，按整词查找时无法前进
+		wholeWord = false
+	}
+	switch mode {
+	case "", nameModeSubstring:
+		needle := query
+		if !caseSensitive {
+			needle = strings.ToLower(query)
+		}
+		q.match = func(s string) bool {
+			if !caseSensitive {
+				s = strings.ToLower(s)
+			}
+			if !wholeWord {
+				return strings.Contains(s, needle)
+			}
+			for start := 0; ; {
+				i := strings.Index(s[start:], needle)
+				if i < 0 {
+					return false
+				}
+				if isWordBounded(s, start+i, start+i+len(needle)) {
+					return true
+				}
+				// 从下一个字符继续查找，允许与上一次查找重叠
+				_, size := utf8.DecodeRuneInString(s[start+i:])
+				start += i + size
+			}
+		}
+		if pinyin && !wholeWord && isPinyinQuery(query) {
+			lower := strings.ToLower(query)
+			q.pinyin = func(s string) bool { return strings.Contains(s, lower) }
+		}
+
+	case nameModeExact:
+		q.match = func(s string) bool {
+			if caseSensitive {
+				return s == query
+			}
+			return strings.EqualFold(s, query)
+		}
+
+	case nameModeGlob:
+		expr, err := globToRegexp(query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidQuery, err)
+		}
+		re, err := compileNameRegexp(expr, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		q.matchPath = strings.Contains(query, "/")
+		q.match = re.MatchString
+
+	case nameModeRegex:
+		re, err := compileNameRegexp(query, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		q.match = re.MatchString
+		if wholeWord {
+			q.match = func(s string) bool {
+				for _, loc := range re.FindAllStringIndex(s, -1) {
+					if isWordBounded(s, loc[0], loc[1]) {
+						return true
+					}
+				}
+				return false
+			}
+		}
+
+	case nameModeFuzzy:
+		pattern, err := newFuzzyPattern(query, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		q.matchPath = true
+		q.match = pattern.contains
+		q.fuzzy = pattern
+		if pinyin && isPinyinQuery(query) {
+			q.pinyin = pattern.contains
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", errInvalidQuery, mode)
+	}
+	return q, nil
+}
+
+// matches 判断条目是否匹配，rel 为相对搜索目录的路径（/ 分隔）
+func (q *nameQuery) matches(name, rel string) bool {
+	if q.matchPath {
+		return q.match(rel)
+	}
+	return q.match(name)
+}
+
+// matchedBy 判断条目是否匹配并返回匹配方式（matchByName、matchByPinyin 或 matchByInitials），不匹配时返回空字符串
+// 文件名不匹配时再按拼音匹配；py 为预先计算的文件名拼音（索引中），为 nil 时按需转换
+func (q *nameQuery) matchedBy(name, rel string, py *pinyinName) string {
+	if q.matches(name, rel) {
+		return matchByName
+	}
+	if q.pinyin == nil {
+		return ""
+	}
+	if q.matchPath || py == nil {
+		target := name
+		if q.matchPath {
+			target = rel
+		}
+		converted := toPinyin(target)
+		py = &converted
+	}
+	switch {
+	case py.full == "":
+		return "" // 不含汉字
+	case q.pinyin(py.full):
+		return matchByPinyin
+	case q.pinyin(py.initials):
+		return matchByInitials
+	}
+	return ""
+}
+
+// compileNameRegexp 编译正则表达式，不区分大小写时添加 (?i)
+func compileNameRegexp(expr string, caseSensitive bool) (*regexp.Regexp, error) {
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidQuery, err)
+	}
+	return re, nil
+}
+
+// isWordBounded 判断 s[start:end] 前后是否为单词边界
+func isWordBounded(s string, start, end int) bool {
+	isWord := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(s[:start]); isWord(r) {
+			return false
+		}
+	}
+	if end < len(s) {
+		if r, _ := utf8.DecodeRuneInString(s[end:]); isWord(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// globToRegexp 把 glob 模式转换为锚定的正则表达式
+// 支持 *（不跨目录）、?、[abc]/[!abc]、**（跨任意层目录，**/ 可匹配零层）和 \ 转义
+func globToRegexp(glob string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == 0 {
+				// []] 形式：] 作为第一个字符时是普通字符
+				end = strings.IndexByte(glob[i+2:], ']') + 1
+			}
+			if end <= 0 {
+				return "", fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 == len(glob) {
+				return "", fmt.Errorf("trailing backslash in %q", glob)
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameQuery(t *testing.T) {
+	cases := []struct {
+		query, mode   string
+		caseSensitive bool
+		wholeWord     bool
+		name, rel     string
+		want          bool
+	}{
+		{"Report", "", false, false, "annual-report.pdf", "docs/annual-report.pdf", true},
+		{"Report", "", true, false, "annual-report.pdf", "docs/annual-report.pdf", false},
+		{"log", "", false, true, "app.log", "app.log", true},
+		{"log", "", false, true, "catalog.txt", "catalog.txt", false},
+		{"log", "", false, true, "catalog-log.txt", "catalog-log.txt", true}, // 第一次出现不是整词，继续查找
+		{"报告", "", false, true, "年度报告.pdf", "年度报告.pdf", false},
+		{"报告", "", false, true, "2026_报告.pdf", "2026_报告.pdf", false}, // 下划线视为单词字符
+		{"报告", "", false, true, "2026-报告.pdf", "2026-报告.pdf", true},
+		{"readme.md", "exact", false, false, "README.md", "README.md", true},
+		{"readme.md", "exact", true, false, "README.md", "README.md", false},
+		{"readme", "exact", false, false, "readme.md", "readme.md", false},
+		{"*.yaml", "glob", false, false, "app.YAML", "config/app.YAML", true},
+		{"*.yaml", "glob", true, false, "app.YAML", "config/app.YAML", false},
+		{"**/*.yaml", "glob", false, false, "app.yaml", "app.yaml", true},
+		{"**/*.yaml", "glob", false, false, "app.yaml", "a/b/app.yaml", true},
+		{"config/*.yaml", "glob", false, false, "app.yaml", "config/sub/app.yaml", false},
+		{"config/**", "glob", false, false, "app.yaml", "config/sub/app.yaml", true},
+		{"app-?.[!b]*", "glob", false, false, "app-1.log", "app-1.log", true},
+		{"app-?.[!b]*", "glob", false, false, "app-1.bak", "app-1.bak", false},
+		{`\*.txt`, "glob", false, false, "*.txt", "*.txt", true},
+		{`\*.txt`, "glob", false, false, "a.txt", "a.txt", false},
+		{`^app-\d+\.log$`, "regex", false, false, "APP-12.log", "APP-12.log", true},
+		{`^app-\d+\.log$`, "regex", true, false, "APP-12.log", "APP-12.log", false},
+		{`v\d`, "regex", false, true, "release-v2.zip", "release-v2.zip", true},
+		{`v\d`, "regex", false, true, "rev2.zip", "rev2.zip", false},
+		{"srvhndl", "fuzzy", false, false, "handlers.go", "server/handlers.go", true},
+		{"", "", false, true, "abc", "abc", true}, // 空查询匹配所有条目，不会因整词查找而卡住
+		{"", "", false, true, "", "", true},
+		{"", "regex", false, true, "abc", "abc", true},
+		{"hndlrs", "fuzzy", false, false, "handlers.go", "handler/a.go", false},
+	}
+	for _, tc := range cases {
+		q, err := newNameQuery(tc.query, tc.mode, tc.caseSensitive, tc.wholeWord, false)
+		require.NoError(t, err, tc.query)
+		assert.Equal(t, tc.want, q.matches(tc.name, tc.rel), "%s %s %s", tc.mode, tc.query, tc.rel)
+	}
+
+	for _, invalid := range []struct{ query, mode string }{
+		{"(", "regex"},
+		{"[abc", "glob"},
+		{`abc\`, "glob"},
+		{"x", "phonetic"},
+	} {
+		_, err := newNameQuery(invalid.query, invalid.mode, false, false, true)
+		assert.ErrorIs(t, err, errInvalidQuery, invalid.query)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 搜索结果的匹配方式
+const (
+	matchByName     = "name"     // 按文件名（或相对路径）匹配
+	matchByPinyin   = "pinyin"   // 按汉字的全拼匹配，例如 baogao 匹配 报告.docx
+	matchByInitials = "initials" // 按汉字拼音的首字母匹配，例如 bg 匹配 报告.docx
+)
+
+// pinyinData 嵌入的汉字拼音表，格式见文件开头的注释
+//
+//go:embed pinyin.txt
+var pinyinData string
+
+var (
+	pinyinOnce  sync.Once
+	pinyinTable map[rune]string // 汉字 -> 不带声调的拼音
+)
+
+// pinyinOf 返回汉字的拼音，不在拼音表中时返回 false
+// 首次调用时解析拼音表
+func pinyinOf(r rune) (string, bool) {
+	pinyinOnce.Do(func() {
+		pinyinTable = make(map[rune]string, 27000)
+		for _, line := range strings.Split(pinyinData, "\n") {
+			syllable, chars, ok := strings.Cut(line, "\t")
+			if !ok || strings.HasPrefix(line, "#") {
+				continue
+			}
+			for _, c := range chars {
+				pinyinTable[c] = syllable
+			}
+		}
+	})
+	py, ok := pinyinTable[r]
+	return py, ok
+}
+
+// pinyinName 名称的拼音形式：汉字替换为拼音，其余字符转为小写
+type pinyinName struct {
+	full     string // 全拼，例如 报告.docx -> baogao.docx；不含汉字时为空
+	initials string // 首字母，例如 报告.docx -> bg.docx；不含汉字时为空
+}
+
+// toPinyin 转换名称中的汉字，不含汉字时返回零值
+func toPinyin(name string) pinyinName {
+	full, ok := transliteratePinyin(name, false, nil)
+	if !ok {
+		return pinyinName{}
+	}
+	initials, _ := transliteratePinyin(name, true, nil)
+	return pinyinName{full: full, initials: initials}
+}
+
+// transliteratePinyin 把 s 中的汉字替换为全拼（initials 为 true 时为拼音首字母），其余字符转为小写
+// origin 不为 nil 时追加结果中每个字符对应的原字符下标（按字符计）；s 不含汉字时返回 false
+func transliteratePinyin(s string, initials bool, origin *[]int) (string, bool) {
+	if !hasHan(s) {
+		return "", false
+	}
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+	i := 0
+	for _, r := range s {
+		py, ok := pinyinOf(r)
+		if !ok {
+			py = string(unicode.ToLower(r))
+		} else if initials {
+			py = py[:1]
+		}
+		b.WriteString(py)
+		if origin != nil {
+			for range utf8.RuneCountInString(py) {
+				*origin = append(*origin, i)
+			}
+		}
+		i++
+	}
+	return b.String(), true
+}
+
+// hasHan 判断 s 是否包含汉字
+func hasHan(s string) bool {
+	for _, r := range s {
+		if r >= 0x3400 && unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPinyinQuery 查询可能是拼音：不含汉字且至少包含一个字母
+func isPinyinQuery(query string) bool {
+	letter := false
+	for _, r := range query {
+		if r >= 0x3400 && unicode.Is(unicode.Han, r) {
+			return false
+		}
+		if r < utf8.RuneSelf && unicode.IsLetter(r) {
+			letter = true
+		}
+	}
+	return letter
+}
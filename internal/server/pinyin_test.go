@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToPinyin(t *testing.T) {
+	assert.Equal(t, pinyinName{full: "baogao.docx", initials: "bg.docx"}, toPinyin("报告.docx"))
+	assert.Equal(t, pinyinName{full: "2026niandu-zongjie.md", initials: "2026nd-zj.md"}, toPinyin("2026年度-总结.md"))
+	assert.Equal(t, pinyinName{full: "lvse", initials: "ls"}, toPinyin("绿色"))
+	assert.Equal(t, pinyinName{}, toPinyin("Report.docx"))
+
+	// 结果中每个字符对应的原字符下标
+	var origin []int
+	text, ok := transliteratePinyin("A报告", false, &origin)
+	require.True(t, ok)
+	assert.Equal(t, "abaogao", text)
+	assert.Equal(t, []int{0, 1, 1, 1, 2, 2, 2}, origin)
+
+	assert.True(t, isPinyinQuery("bg"))
+	assert.True(t, isPinyinQuery("2026nd"))
+	assert.False(t, isPinyinQuery("2026"))
+	assert.False(t, isPinyinQuery("报告"))
+}
+
+func TestNameQuery_Pinyin(t *testing.T) {
+	cases := []struct {
+		query, mode string
+		wholeWord   bool
+		name        string
+		want        string
+	}{
+		{"bg", "", false, "报告.docx", matchByInitials},
+		{"baogao", "", false, "报告.docx", matchByPinyin},
+		{"BaoGao", "", false, "年度报告.docx", matchByPinyin},
+		{"gaob", "", false, "报告.docx", ""},
+		{"docx", "", false, "报告.docx", matchByName},
+		{"报", "", false, "报告.docx", matchByName},
+		{"bg", "", true, "报告.docx", ""}, // 整词匹配不按拼音
+		{"bg", "exact", false, "bg", matchByName},
+		{"bg", "exact", false, "报告", ""},
+		// fuzzy 模式在这里只做筛选（全拼包含首字母的子序列），实际的匹配方式由评分决定
+		{"ndbg", "fuzzy", false, "年度报告.docx", matchByPinyin},
+		{"bgdx", "fuzzy", false, "报告.docx", matchByPinyin},
+		{"bgz", "fuzzy", false, "报告.docx", ""},
+	}
+	for _, tc := range cases {
+		q, err := newNameQuery(tc.query, tc.mode, false, tc.wholeWord, true)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, q.matchedBy(tc.name, tc.name, nil), "%s %s %s", tc.mode, tc.query, tc.name)
+	}
+
+	// 关闭拼音匹配
+	q, err := newNameQuery("bg", "", false, false, false)
+	require.NoError(t, err)
+	assert.Empty(t, q.matchedBy("报告.docx", "报告.docx", nil))
+}
+
+func TestHandleSearch_Pinyin(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"文档/年度报告.docx", "文档/报告.docx", "readme.md"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte("x"), 0644))
+	}
+
+	for _, index := range []bool{false, true} {
+		server, err := New(Config{Root: root, NameIndex: index})
+		require.NoError(t, err)
+		defer server.Close()
+		if index {
+			require.Eventually(t, func() bool { return server.names.status().Ready }, 5*time.Second, 10*time.Millisecond)
+		}
+		router := server.Handler()
+		get := func(target string) fuzzySearchResponse {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var resp fuzzySearchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			return resp
+		}
+		matched := func(resp fuzzySearchResponse) map[string]string {
+			out := map[string]string{}
+			for _, r := range resp.Results {
+				out[r.Path] = r.MatchedBy
+			}
+			return out
+		}
+
+		// 首字母和全拼
+		assert.Equal(t, map[string]string{
+			"/文档/年度报告.docx": matchByInitials,
+			"/文档/报告.docx":   matchByInitials,
+		}, matched(get("/api/search?path=/&q=bg&recursive=true")), "index=%v", index)
+		assert.Equal(t, map[string]string{
+			"/文档/年度报告.docx": matchByPinyin,
+			"/文档/报告.docx":   matchByPinyin,
+		}, matched(get("/api/search?path="+url.QueryEscape("/文档")+"&q=baogao.&recursive=true")), "index=%v", index)
+		assert.Equal(t, map[string]string{
+			"/文档": matchByPinyin,
+		}, matched(get("/api/search?path=/&q=wendang")), "index=%v", index)
+		assert.Equal(t, map[string]string{
+			"/readme.md": matchByName,
+		}, matched(get("/api/search?path=/&q=readme&recursive=true")), "index=%v", index)
+		assert.Empty(t, get("/api/search?path=/&q=bg&recursive=true&pinyin=false").Results)
+
+		// 模糊匹配：取全拼和首字母中得分高的一种，位置对应 path 中的汉字
+		resp := get("/api/search?path=/&q=ndbg&mode=fuzzy&recursive=true")
+		require.Len(t, resp.Results, 2)
+		assert.Equal(t, "/文档/年度报告.docx", resp.Results[0].Path)
+		assert.Equal(t, matchByInitials, resp.Results[0].MatchedBy)
+		assert.Equal(t, []int{4, 5, 6, 7}, resp.Results[0].Positions)
+		assert.Equal(t, "/文档/报告.docx", resp.Results[1].Path) // wen dang bao gao
+		assert.Equal(t, matchByPinyin, resp.Results[1].MatchedBy)
+		assert.Equal(t, []int{1, 2, 4, 5}, resp.Results[1].Positions)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRawCSP /raw 默认的内容安全策略：页面运行在不透明源中，
+// 可以执行脚本，但无法以本站身份调用 API 或读取前端的本地存储
+const defaultRawCSP = "sandbox allow-scripts allow-forms allow-popups allow-downloads"
+
+// handleRaw 按路径返回文件的原始内容，供 HTML 报告、文档等静态站点通过相对链接加载 CSS/JS/图片
+// GET /raw/reports/coverage/index.html
+// GET /raw/reports/coverage/   目录返回其中的 index.html，没有时跳转到 /browse 目录页
+// GET /raw/reports/coverage    目录重定向到以 / 结尾的地址，保证相对链接以目录为基准
+// GET /raw/site.zip!/index.html 压缩包内的文件同样适用
+func (s *Server) handleRaw(c *gin.Context) {
+	reqPath := c.Param("path")
+
+	// 配置了独立源时，只在该源上提供原始内容
+	if origin, err := url.Parse(s.cfg.RawOrigin); err == nil && origin.Host != "" && !strings.EqualFold(c.Request.Host, origin.Host) {
+		c.Redirect(http.StatusFound, s.rawURL(reqPath))
+		return
+	}
+	if s.cfg.RawCSP != "" {
+		c.Header("Content-Security-Policy", s.cfg.RawCSP)
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	isDir, err := s.statRaw(reqPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+	if !isDir {
+		s.serveFile(c, reqPath, false)
+		return
+	}
+
+	if !strings.HasSuffix(reqPath, "/") {
+		// 使用相对地址重定向，无需关心反向代理的基础路径
+		// 直接写 Location 头：http.Redirect 会把相对地址改写为基于请求路径的绝对地址
+		target := "./" + (&url.URL{Path: path.Base(reqPath) + "/"}).EscapedPath()
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Header("Location", target)
+		c.Status(http.StatusMovedPermanently)
+		return
+	}
+	index := path.Join(reqPath, "index.html")
+	if indexIsDir, err := s.statRaw(index); err == nil && !indexIsDir {
+		s.serveFile(c, index, false)
+		return
+	}
+	c.Redirect(http.StatusFound, s.browseURL(reqPath))
+}
+
+// statRaw 判断路径（可以是压缩包内路径）是否为目录，不存在时返回 os.ErrNotExist
+func (s *Server) statRaw(reqPath string) (bool, error) {
+	target, inArchive, err := s.resolveArchivePath(reqPath)
+	if err != nil {
+		return false, err
+	}
+	if inArchive {
+		inner := strings.Trim(target.inner, "/")
+		if inner == "" {
+			return true, nil
+		}
+		entries, err := s.listArchive(target)
+		if err != nil {
+			return false, err
+		}
+		for _, entry := range entries {
+			if entry.name == inner {
+				return entry.isDir, nil
+			}
+			if strings.HasPrefix(entry.name, inner+"/") {
+				return true, nil // 没有显式目录条目的虚拟目录
+			}
+		}
+		return false, entryNotFound(target)
+	}
+
+	absPath, _, err := s.resolvePath(reqPath)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// rawURL 返回文件的原始内容地址；配置了 RawOrigin 时使用独立源，否则使用基础路径
+func (s *Server) rawURL(filePath string) string {
+	escaped := (&url.URL{Path: path.Join("/raw", filePath)}).EscapedPath()
+	if strings.HasSuffix(filePath, "/") {
+		escaped += "/"
+	}
+	if s.cfg.RawOrigin != "" {
+		return strings.TrimSuffix(s.cfg.RawOrigin, "/") + escaped
+	}
+	return s.cfg.BasePath + escaped
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRaw(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "site", "css"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "index.html"), []byte(`<link rel="stylesheet" href="css/app.css">`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "css", "app.css"), []byte("body{color:red}"), 0644))
+
+	f, err := os.Create(filepath.Join(root, "report.zip"))
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{"index.html": "<h1>report</h1>", "docs/guide.html": "guide"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	server, err := New(Config{Root: root, RawCSP: defaultRawCSP})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(url string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/raw/site/index.html")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Equal(t, defaultRawCSP, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	// 相对链接引用的资源按扩展名返回 MIME 类型，并支持 Range
+	w = get("/raw/site/css/app.css", "Range", "bytes=0-3")
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+	assert.Equal(t, "body", w.Body.String())
+
+	// 目录：先补全结尾的 /，再返回 index.html
+	w = get("/raw/site?v=1")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "./site/?v=1", w.Header().Get("Location"))
+	w = get("/raw/site/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "css/app.css")
+
+	// 没有 index.html 的目录跳转到目录浏览页
+	w = get("/raw/empty/")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/browse/empty/", w.Header().Get("Location"))
+
+	// 压缩包内的静态站点
+	w = get("/raw/report.zip!/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>report</h1>", w.Body.String())
+	w = get("/raw/report.zip!/docs")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "./docs/", w.Header().Get("Location"))
+	w = get("/raw/report.zip!/docs/guide.html")
+	assert.Equal(t, "guide", w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/raw/missing.html").Code)
+	assert.Equal(t, http.StatusNotFound, get("/raw/report.zip!/missing.html").Code)
+	assert.NotEqual(t, http.StatusOK, get("/raw/../../etc/passwd").Code)
+}
+
+func TestHandleRaw_Origin(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a b.html"), []byte("hi"), 0644))
+
+	server, err := New(Config{Root: root, RawOrigin: "https://raw.example.com"})
+	require.NoError(t, err)
+	router := server.Handler()
+
+	// 主站上的请求跳转到独立源
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://files.example.com/raw/a%20b.html", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://raw.example.com/raw/a%20b.html", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://raw.example.com/raw/a%20b.html", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hi", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+}
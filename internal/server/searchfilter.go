@@ -0,0 +1,135 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchFilter 搜索结果的过滤条件，零值表示不过滤
+type searchFilter struct {
+	entryType      string    // file 或 dir，为空时不限
+	exts           []string  // 小写的扩展名（不含开头的点，例如 zip、tar.gz），文件名以其中之一结尾
+	minSize        int64     // 最小文件大小，0 表示不限
+	maxSize        int64     // 最大文件大小，-1 表示不限
+	modifiedAfter  time.Time // 修改时间不早于该时间
+	modifiedBefore time.Time // 修改时间早于该时间
+	maxDepth       int       // 相对搜索目录的最大深度（直接子项为 1），0 表示不限
+}
+
+// parseSearchFilter 解析搜索的过滤参数
+// type=file|dir、ext=zip,tar.gz、minSize=1GB、maxSize=10MB、
+// modifiedAfter/modifiedBefore（RFC3339 或 2006-01-02，日期按 UTC 零点）、maxDepth=2
+func parseSearchFilter(c *gin.Context) (*searchFilter, error) {
+	f := &searchFilter{maxSize: -1}
+
+	switch t := c.Query("type"); t {
+	case "", "file", "dir":
+		f.entryType = t
+	default:
+		return nil, fmt.Errorf("type must be file or dir, got %q", t)
+	}
+
+	for _, ext := range strings.Split(c.Query("ext"), ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			f.exts = append(f.exts, ext)
+		}
+	}
+
+	var err error
+	if v := c.Query("minSize"); v != "" {
+		if f.minSize, err = parseBytes(v); err != nil {
+			return nil, fmt.Errorf("invalid minSize: %w", err)
+		}
+	}
+	if v := c.Query("maxSize"); v != "" {
+		if f.maxSize, err = parseBytes(v); err != nil {
+			return nil, fmt.Errorf("invalid maxSize: %w", err)
+		}
+	}
+	if f.maxSize >= 0 && f.minSize > f.maxSize {
+		return nil, errors.New("minSize must not exceed maxSize")
+	}
+
+	if f.modifiedAfter, err = parseSearchTime(c.Query("modifiedAfter")); err != nil {
+		return nil, fmt.Errorf("invalid modifiedAfter: %w", err)
+	}
+	if f.modifiedBefore, err = parseSearchTime(c.Query("modifiedBefore")); err != nil {
+		return nil, fmt.Errorf("invalid modifiedBefore: %w", err)
+	}
+
+	if v := c.Query("maxDepth"); v != "" {
+		if f.maxDepth, err = strconv.Atoi(v); err != nil || f.maxDepth < 1 {
+			return nil, errors.New("maxDepth must be >= 1")
+		}
+	}
+	return f, nil
+}
+
+// parseSearchTime 解析 RFC3339 时间或日期，空字符串返回零值
+func parseSearchTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+// active 是否设置了任何过滤条件（设置后允许空的搜索关键词）
+func (f *searchFilter) active() bool {
+	return f != nil && (f.entryType != "" || len(f.exts) > 0 || f.minSize > 0 || f.maxSize >= 0 ||
+		!f.modifiedAfter.IsZero() || !f.modifiedBefore.IsZero() || f.maxDepth > 0)
+}
+
+// matches 判断条目是否满足过滤条件；扩展名和大小条件只对文件有效，设置后目录不会匹配
+func (f *searchFilter) matches(name string, isDir bool, size int64, modTime time.Time) bool {
+	if f == nil {
+		return true
+	}
+	if f.entryType == "file" && isDir || f.entryType == "dir" && !isDir {
+		return false
+	}
+	if isDir && (len(f.exts) > 0 || f.minSize > 0 || f.maxSize >= 0) {
+		return false
+	}
+	if len(f.exts) > 0 {
+		lower := strings.ToLower(name)
+		found := false
+		for _, ext := range f.exts {
+			if strings.HasSuffix(lower, "."+ext) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if size < f.minSize || f.maxSize >= 0 && size > f.maxSize {
+		return false
+	}
+	if !f.modifiedAfter.IsZero() && modTime.Before(f.modifiedAfter) {
+		return false
+	}
+	if !f.modifiedBefore.IsZero() && !modTime.Before(f.modifiedBefore) {
+		return false
+	}
+	return true
+}
+
+// withinDepth 深度为 depth 的条目是否在深度限制内
+func (f *searchFilter) withinDepth(depth int) bool {
+	return f == nil || f.maxDepth == 0 || depth <= f.maxDepth
+}
+
+// descend 深度为 depth 的目录是否需要继续向下搜索
+func (f *searchFilter) descend(depth int) bool {
+	return f == nil || f.maxDepth == 0 || depth < f.maxDepth
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 100  // 默认每页结果数
+	maxSearchLimit     = 1000 // 每页最多结果数
+	searchCursorPrefix = "1:" // 游标格式版本
+)
+
+// errInvalidCursor 游标无法解析
+var errInvalidCursor = errors.New("invalid cursor")
+
+// searchPage 搜索结果的分页信息
+type searchPage struct {
+	Truncated bool   `json:"truncated"`        // 结果是否不完整（还有更多结果，或超出时间预算），可使用 cursor 继续
+	Scanned   int    `json:"scanned"`          // 本次检查的条目数（内容搜索为扫描的文件数）
+	Cursor    string `json:"cursor,omitempty"` // 获取下一页的游标，结果完整时为空
+}
+
+// searchResponse 文件名搜索响应
+type searchResponse struct {
+	Results []fileEntry `json:"results"`
+	searchPage
+}
+
+// parseSearchPage 解析每页结果数 limit 和游标 cursor
+// 游标记录上一页最后一个条目相对搜索目录的路径，下一页从遍历顺序中它之后的条目开始
+func parseSearchPage(c *gin.Context) (string, int, error) {
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return "", 0, errors.New("limit must be >= 1")
+		}
+		limit = min(n, maxSearchLimit)
+	}
+	after, err := decodeSearchCursor(c.Query("cursor"))
+	if err != nil {
+		return "", 0, err
+	}
+	return after, limit, nil
+}
+
+// encodeSearchCursor 把相对路径编码为不透明的游标
+func encodeSearchCursor(rel string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(searchCursorPrefix + rel))
+}
+
+// decodeSearchCursor 解析游标，空字符串表示从头开始
+func decodeSearchCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), searchCursorPrefix) {
+		return "", fmt.Errorf("%w: %q", errInvalidCursor, cursor)
+	}
+	return strings.TrimPrefix(string(data), searchCursorPrefix), nil
+}
+
+// walkOrderLess 按目录遍历顺序比较两个相对路径（/ 分隔）：
+// 逐级按名称字节序比较，目录排在其中的内容之前，与 filepath.WalkDir 的访问顺序一致
+func walkOrderLess(a, b string) bool {
+	for {
+		ai, bi := strings.IndexByte(a, '/'), strings.IndexByte(b, '/')
+		ac, bc := a, b
+		if ai >= 0 {
+			ac = a[:ai]
+		}
+		if bi >= 0 {
+			bc = b[:bi]
+		}
+		if ac != bc {
+			return ac < bc
+		}
+		if ai < 0 {
+			return bi >= 0 // a 是 b 的上级目录
+		}
+		if bi < 0 {
+			return false
+		}
+		a, b = a[ai+1:], b[bi+1:]
+	}
+}
+
+// skipBeforeCursor 在遍历中跳过游标及其之前的条目
+// 返回 true 表示跳过该条目；err 为 filepath.SkipDir 时整个子树都在游标之前
+func skipBeforeCursor(rel string, isDir bool, after string) (bool, error) {
+	if after == "" || walkOrderLess(after, rel) {
+		return false, nil
+	}
+	if isDir && rel != after && !strings.HasPrefix(after, rel+"/") {
+		return true, filepath.SkipDir
+	}
+	return true, nil // 游标本身或其上级目录：跳过条目，但继续遍历其中的内容
+}
+
+// relToSearchDir 把结果的路径（以 / 开头，相对根目录）转换为相对搜索目录 relPath 的路径
+func relToSearchDir(relPath, itemPath string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(itemPath, path.Join("/", relPath)), "/")
+}
+
+// newSearchPage 根据最多 limit+1 个按遍历顺序排列的结果生成分页信息，返回当前页的结果
+// relOf 返回结果相对搜索目录的路径，用于生成游标
+func newSearchPage[T any](results []T, limit, scanned int, relOf func(T) string) ([]T, searchPage) {
+	page := searchPage{Scanned: scanned}
+	if len(results) > limit {
+		results = results[:limit]
+		page.Truncated = true
+		page.Cursor = encodeSearchCursor(relOf(results[limit-1]))
+	}
+	return results, page
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkOrderLess(t *testing.T) {
+	// 与 filepath.WalkDir 的访问顺序一致：a 目录中的内容排在 a-b 之前
+	ordered := []string{"a", "a/b", "a/b/c", "a/c", "a-b", "a.txt", "b"}
+	for i := range ordered {
+		for j := range ordered {
+			assert.Equal(t, i < j, walkOrderLess(ordered[i], ordered[j]), "%s < %s", ordered[i], ordered[j])
+		}
+	}
+}
+
+func TestSkipBeforeCursor(t *testing.T) {
+	for _, tc := range []struct {
+		rel   string
+		isDir bool
+		skip  bool
+		err   error
+	}{
+		{"a", true, true, filepath.SkipDir},
+		{"b", true, true, nil},   // 游标的上级目录，继续向下
+		{"b/c", true, true, nil}, // 游标本身，其中的内容在游标之后
+		{"b/c/d.txt", false, false, nil},
+		{"b/b.txt", false, true, nil},
+		{"b/d", true, false, nil},
+	} {
+		skip, err := skipBeforeCursor(tc.rel, tc.isDir, "b/c")
+		assert.Equal(t, tc.skip, skip, tc.rel)
+		assert.Equal(t, tc.err, err, tc.rel)
+	}
+
+	cursor := encodeSearchCursor("docs/报告 1.md")
+	after, err := decodeSearchCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "docs/报告 1.md", after)
+	_, err = decodeSearchCursor("ZG9jcw") // 缺少版本前缀
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
+
+func TestContentSearchPage(t *testing.T) {
+	result := func(p string) *contentResult { return &contentResult{fileEntry: fileEntry{Path: p}} }
+
+	// 超时：只返回连续完成的前缀中的结果，游标指向前缀中最后一个文件
+	cs := &contentSearch{relPath: "src", limit: 10, files: []contentFile{
+		{rel: "a.go", done: true, result: result("/src/a.go")},
+		{rel: "b.go", done: true},
+		{rel: "c.go"},
+		{rel: "d.go", done: true, result: result("/src/d.go")},
+	}}
+	results, page := cs.page()
+	require.Len(t, results, 1)
+	assert.Equal(t, "/src/a.go", results[0].Path)
+	assert.True(t, page.Truncated)
+	assert.Equal(t, 3, page.Scanned)
+	after, err := decodeSearchCursor(page.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "b.go", after)
+
+	// 找到超过 limit 个匹配的文件：游标指向本页最后一个结果
+	cs = &contentSearch{relPath: "src", limit: 1, files: []contentFile{
+		{rel: "a.go", done: true, result: result("/src/a.go")},
+		{rel: "b.go", done: true, result: result("/src/b.go")},
+	}}
+	results, page = cs.page()
+	require.Len(t, results, 1)
+	after, _ = decodeSearchCursor(page.Cursor)
+	assert.Equal(t, "a.go", after)
+
+	// 遍历完整结束
+	cs = &contentSearch{relPath: "src", limit: 1, walked: true, files: []contentFile{{rel: "a.go", done: true}}}
+	results, page = cs.page()
+	assert.Empty(t, results)
+	assert.False(t, page.Truncated)
+	assert.Empty(t, page.Cursor)
+}
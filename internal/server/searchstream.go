@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchProgressInterval 流式搜索发送进度事件的最小间隔（测试中可调小）
+var searchProgressInterval = 250 * time.Millisecond
+
+// searchProgress 流式搜索的进度
+type searchProgress struct {
+	Dirs    int `json:"dirs"`    // 已遍历的目录数
+	Scanned int `json:"scanned"` // 已检查的条目数
+	Found   int `json:"found"`   // 已发送的结果数
+}
+
+// searchStreamDone 流式搜索结束事件：分页信息和遍历的目录数
+type searchStreamDone struct {
+	searchPage
+	Found int `json:"found"` // 发送的结果数
+	Dirs  int `json:"dirs"`  // 遍历的目录数（查询索引或单层搜索时为 0）
+}
+
+// searchStream 流式搜索的输出，格式为 ndjson 或 sse
+// ndjson 每行一个 {"type": 事件名, "data": 数据}；sse 的事件名和数据与之相同
+type searchStream struct {
+	c      *gin.Context
+	format string
+}
+
+// parseSearchStream 解析 stream 参数，为空时不使用流式输出
+func parseSearchStream(c *gin.Context) (string, error) {
+	switch v := c.Query("stream"); v {
+	case "", "ndjson", "sse":
+		return v, nil
+	default:
+		return "", fmt.Errorf("stream must be ndjson or sse, got %q", v)
+	}
+}
+
+// newSearchStream 设置响应头并返回流式输出
+func newSearchStream(c *gin.Context, format string) *searchStream {
+	if format == "ndjson" {
+		c.Header("Content-Type", "application/x-ndjson")
+	} else {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Connection", "keep-alive")
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 禁止 Nginx 缓冲
+	c.Status(http.StatusOK)
+	return &searchStream{c: c, format: format}
+}
+
+// send 发送一个事件并立即刷新
+func (w *searchStream) send(event string, data any) {
+	if w.format == "ndjson" {
+		line, err := json.Marshal(struct {
+			Type string `json:"type"`
+			Data any    `json:"data"`
+		}{event, data})
+		if err != nil {
+			return
+		}
+		_, _ = w.c.Writer.Write(append(line, '\n'))
+	} else {
+		w.c.SSEvent(event, data)
+	}
+	w.c.Writer.Flush()
+}
+
+// streamSearch 流式返回文件名搜索结果
+// GET /api/search?path=/&q=report&recursive=true&stream=ndjson|sse
+// 每找到一个结果立即发送 result 事件（fileEntry），遍历目录时定期发送 progress 事件（searchProgress），
+// 最后发送 done 事件（searchStreamDone，含 truncated 和 cursor）；客户端断开时立即停止遍历且不再发送
+func (s *Server) streamSearch(c *gin.Context, q *nameSearch, recursive bool, format string) {
+	ctx := c.Request.Context()
+	w := newSearchStream(c, format)
+
+	// 索引查询和单层搜索很快，直接发送全部结果；递归搜索没有可用的索引时边遍历边发送
+	var results []fileEntry
+	var scanned int
+	ok := true
+	if recursive {
+		results, scanned, ok = s.names.search(q)
+	} else {
+		results, scanned = s.searchDir(q)
+	}
+	if ok {
+		results, page := newSearchPage(results, q.limit, scanned, q.relOf)
+		for i := range results {
+			w.send("result", results[i])
+		}
+		w.send("done", searchStreamDone{searchPage: page, Found: len(results)})
+		return
+	}
+
+	progress := searchProgress{}
+	last := ""
+	truncated := false
+	lastProgress := time.Now()
+	emit := func(item fileEntry) bool {
+		if progress.Found == q.limit {
+			truncated = true // 已多找到一个结果，还有下一页
+			return false
+		}
+		progress.Found++
+		last = q.relOf(item)
+		w.send("result", item)
+		return true
+	}
+	onDir := func(scanned int) {
+		progress.Dirs++
+		if time.Since(lastProgress) >= searchProgressInterval {
+			progress.Scanned = scanned
+			w.send("progress", progress)
+			lastProgress = time.Now()
+		}
+	}
+
+	scanned = s.walkSearch(ctx, q, emit, onDir)
+	if ctx.Err() != nil {
+		c.Abort() // 客户端已断开
+		return
+	}
+	done := searchStreamDone{searchPage: searchPage{Truncated: truncated, Scanned: scanned}, Found: progress.Found, Dirs: progress.Dirs}
+	if truncated {
+		done.Cursor = encodeSearchCursor(last)
+	}
+	w.send("done", done)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamLine 测试中解析出的 NDJSON 行
+type streamLine struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func TestHandleSearch_Stream(t *testing.T) {
+	interval := searchProgressInterval
+	searchProgressInterval = 0 // 每个目录都发送进度
+	defer func() { searchProgressInterval = interval }()
+
+	root := t.TempDir()
+	for _, name := range []string{"a/report.txt", "a/b/report.md", "c/notes.txt", "report.csv"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte("x"), 0644))
+	}
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	readLines := func(w *httptest.ResponseRecorder) ([]string, []searchProgress, searchStreamDone) {
+		var paths []string
+		var progress []searchProgress
+		var done searchStreamDone
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var line streamLine
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			switch line.Type {
+			case "result":
+				var entry fileEntry
+				require.NoError(t, json.Unmarshal(line.Data, &entry))
+				paths = append(paths, entry.Path)
+			case "progress":
+				var p searchProgress
+				require.NoError(t, json.Unmarshal(line.Data, &p))
+				progress = append(progress, p)
+			case "done":
+				require.NoError(t, json.Unmarshal(line.Data, &done))
+			}
+		}
+		return paths, progress, done
+	}
+
+	// NDJSON：结果按遍历顺序逐条发送，每个目录发送一次进度，最后是 done
+	w := get("/api/search?path=/&q=report&recursive=true&stream=ndjson")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	paths, progress, done := readLines(w)
+	assert.Equal(t, []string{"/a/b/report.md", "/a/report.txt", "/report.csv"}, paths)
+	assert.Len(t, progress, 4)            // /、a、a/b、c
+	assert.Equal(t, 2, progress[3].Found) // 进入 c 时已发送两个结果
+	assert.False(t, done.Truncated)
+	assert.Empty(t, done.Cursor)
+	assert.Equal(t, 3, done.Found)
+	assert.Equal(t, 4, done.Dirs)
+	assert.Equal(t, 7, done.Scanned)
+
+	// 达到 limit 后停止，游标可用于普通搜索和流式搜索
+	w = get("/api/search?path=/&q=report&recursive=true&stream=ndjson&limit=2")
+	paths, _, done = readLines(w)
+	assert.Equal(t, []string{"/a/b/report.md", "/a/report.txt"}, paths)
+	require.True(t, done.Truncated)
+	require.NotEmpty(t, done.Cursor)
+	w = get("/api/search?path=/&q=report&recursive=true&stream=ndjson&limit=2&cursor=" + done.Cursor)
+	paths, _, done = readLines(w)
+	assert.Equal(t, []string{"/report.csv"}, paths)
+	assert.False(t, done.Truncated)
+
+	// 单层搜索直接发送全部结果
+	w = get("/api/search?path=/&q=report&stream=ndjson")
+	paths, progress, done = readLines(w)
+	assert.Equal(t, []string{"/report.csv"}, paths)
+	assert.Empty(t, progress)
+	assert.Equal(t, 1, done.Found)
+
+	// SSE
+	w = get("/api/search?path=/a&q=report&recursive=true&stream=sse")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	body := w.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "event:result"))
+	assert.Contains(t, body, `"path":"/a/b/report.md"`)
+	assert.Contains(t, body, "event:progress")
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(body), `"found":2,"dirs":2}`), body)
+
+	// 空查询只发送 done
+	w = get("/api/search?path=/&stream=ndjson")
+	paths, _, done = readLines(w)
+	assert.Empty(t, paths)
+	assert.Zero(t, done.Found)
+
+	// 参数错误在开始输出前返回
+	assert.Equal(t, http.StatusBadRequest, get("/api/search?path=/&q=x&stream=xml").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/search?path=/&q=x&mode=content&stream=sse").Code)
+	w = get("/api/search?path=/missing&q=x&recursive=true&stream=ndjson")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_PATH")
+}
+
+func TestWalkSearch_Canceled(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "x.txt"), []byte("x"), 0644))
+
+	match, err := newNameQuery("x", "", false, false, true)
+	require.NoError(t, err)
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	q := &nameSearch{absPath: root, match: match, limit: 10}
+
+	// 在第一个子目录处取消，之后不再遍历
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dirs := 0
+	scanned := server.walkSearch(ctx, q, func(fileEntry) bool {
+		t.Fatal("unexpected result after cancel")
+		return true
+	}, func(int) {
+		dirs++
+		if dirs == 2 {
+			cancel()
+		}
+	})
+	assert.Equal(t, 2, dirs)
+	assert.Equal(t, 1, scanned)
+}
@@ -4,8 +4,11 @@ package server
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io/fs"
 	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +16,20 @@ import (
 
 // Server 文件浏览器 HTTP 服务器
 type Server struct {
-	cfg    Config  // 服务器配置
-	static fs.FS   // 嵌入的静态文件系统（前端资源）
-	index  []byte  // index.html 内容，用于 SPA 路由回退
+	cfg    Config           // 服务器配置
+	static fs.FS            // 嵌入的静态文件系统（前端资源）
+	index  []byte           // index.html 内容，用于 SPA 路由回退
+	lines  *lineIndexCache  // 文件行偏移索引缓存，用于按行预览
+	tables *tableIndexCache // 表格行偏移索引缓存，用于 CSV/TSV 分页
+	thumbs *thumbCache      // 缩略图磁盘缓存
+
+	decompress *decompressCache  // 压缩文件透明解压的检查点缓存
+	archives   *archiveListCache // 压缩包条目列表缓存
+	names      *nameIndex        // 文件名索引，未启用时为 nil
+	ignores    *ignoreCache      // 解析后的 .gitignore 规则缓存
+	dupes      *dupeJobs         // 后台运行的重复文件扫描任务
+
+	thumbSlots chan struct{} // 限制同时生成缩略图的数量
 }
 
 // New 创建一个新的 Server 实例
@@ -37,11 +51,49 @@ func New(cfg Config) (*Server, error) {
 		index = []byte("<!doctype html><html><body>file-browser</body></html>")
 	}
 
-	return &Server{
-		cfg:    cfg,
-		static: sub,
-		index:  index,
-	}, nil
+	// 缩略图缓存，未配置缓存目录时不落盘
+	thumbDir := ""
+	if cfg.CacheDir != "" {
+		thumbDir = filepath.Join(cfg.CacheDir, "thumbs")
+	}
+	thumbMax := cfg.ThumbCacheMax
+	if thumbMax <= 0 {
+		thumbMax = defaultThumbCacheMax
+	}
+	thumbs, err := newThumbCache(thumbDir, thumbMax)
+	if err != nil {
+		return nil, fmt.Errorf("create thumbnail cache: %w", err)
+	}
+
+	s := &Server{
+		cfg:        cfg,
+		static:     sub,
+		index:      index,
+		lines:      newLineIndexCache(lineIndexCacheSize),
+		tables:     newTableIndexCache(tableIndexCacheSize),
+		thumbs:     thumbs,
+		decompress: newDecompressCache(decompressCacheSize),
+		archives:   newArchiveListCache(archiveListCacheEntries),
+		ignores:    newIgnoreCache(ignoreCacheSize),
+		dupes:      newDupeJobs(),
+		thumbSlots: make(chan struct{}, runtime.NumCPU()),
+	}
+
+	// 文件名索引在后台构建，构建完成前递归搜索仍然遍历目录
+	if cfg.NameIndex {
+		s.names = newNameIndex(cfg.Root, cfg.CacheDir)
+	}
+	return s, nil
+}
+
+// Close 停止后台任务（重复文件扫描和文件名索引的监听），持久化尚未写入的索引，并删除解压检查点的临时文件
+func (s *Server) Close() error {
+	s.dupes.close()
+	s.decompress.close()
+	if s.names == nil {
+		return nil
+	}
+	return s.names.close()
 }
 
 // Handler 返回配置好的 Gin 引擎，包含所有路由
@@ -50,12 +102,33 @@ func (s *Server) Handler() *gin.Engine {
 	r.Use(gin.Recovery()) // 恢复中间件，防止 panic 导致服务崩溃
 
 	// API 路由
-	r.GET("/api/files", s.handleFiles)       // 获取目录内容
-	r.GET("/api/search", s.handleSearch)     // 搜索文件
-	r.GET("/api/preview", s.handlePreview)   // 预览文件内容
-	r.GET("/api/image", s.handleImage)       // 获取图片
-	r.GET("/api/download", s.handleDownload) // 下载文件
-	r.GET("/healthz", s.handleHealth)        // 健康检查
+	r.GET("/api/files", s.handleFiles)                // 获取目录内容
+	r.GET("/api/search", s.handleSearch)              // 搜索文件
+	r.GET("/api/preview", s.handlePreview)            // 预览文件内容
+	r.GET("/api/grep", s.handleGrep)                  // 在单个文件中搜索
+	r.GET("/api/table", s.handleTable)                // 以表格形式预览 CSV/TSV
+	r.GET("/api/structured", s.handleStructured)      // 以树形结构预览 JSON/YAML/TOML
+	r.GET("/api/sqlite/tables", s.handleSQLiteTables) // 列出 SQLite 数据库的表和视图
+	r.GET("/api/sqlite/rows", s.handleSQLiteRows)     // 分页浏览 SQLite 表数据
+	r.GET("/api/sqlite/query", s.handleSQLiteQuery)   // 执行只读 SELECT 查询
+	r.GET("/api/image", s.handleImage)                // 获取图片
+	r.GET("/api/thumb", s.handleThumb)                // 获取图片缩略图
+	r.GET("/api/meta", s.handleMeta)                  // 获取文件元数据（图片尺寸、EXIF 等）
+	r.GET("/api/download", s.handleDownload)          // 下载文件
+	r.GET("/api/tail", s.handleTail)                  // 追踪文件新增内容（SSE）
+	r.GET("/api/diff", s.handleDiff)                  // 比较两个文本文件
+	r.GET("/api/index/status", s.handleIndexStatus)   // 文件名索引状态
+	r.POST("/api/dupes", s.handleDupesStart)          // 启动重复文件扫描任务
+	r.GET("/api/dupes", s.handleDupesList)            // 列出重复文件扫描任务
+	r.GET("/api/dupes/:id", s.handleDupesStatus)      // 查询扫描进度和重复文件
+	r.DELETE("/api/dupes/:id", s.handleDupesCancel)   // 取消并删除扫描任务
+	r.GET("/healthz", s.handleHealth)                 // 健康检查
+
+	// 服务端渲染的目录浏览页面（无需 JavaScript）
+	r.GET("/browse/*path", s.handleBrowse)
+
+	// 按路径返回文件原始内容，HTML 报告中的相对链接可以正常加载
+	r.GET("/raw/*path", s.handleRaw)
 
 	// 静态文件和 SPA 回退（处理前端路由）
 	r.NoRoute(s.handleStatic)
@@ -129,3 +202,9 @@ func (s *Server) serveIndex(c *gin.Context) {
 
 // errAccessDenied 路径遍历攻击防护错误
 var errAccessDenied = errors.New("access denied")
+
+// errNotAFile 请求的路径是目录或其他非普通文件（FIFO、设备文件等）
+var errNotAFile = errors.New("not a regular file")
+
+// errNotADir 请求的路径是文件而非目录
+var errNotADir = errors.New("path is not a directory")
@@ -0,0 +1,454 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	_ "modernc.org/sqlite" // 注册纯 Go 实现的 sqlite 驱动
+)
+
+const (
+	sqliteHeader        = "SQLite format 3\x00" // SQLite 文件头
+	defaultSQLiteLimit  = 100                   // 默认每页行数
+	maxSQLiteLimit      = 1000                  // 每页/每次查询最多返回的行数
+	sqliteQueryTimeout  = 10 * time.Second      // 单次查询超时
+	sqliteCountTimeout  = 3 * time.Second       // 列出表时统计行数的总超时
+	maxSQLiteText       = 4096                  // 文本值最多返回的字节数
+	maxSQLiteBlobPrefix = 64                    // BLOB 值返回的十六进制前缀字节数
+)
+
+var (
+	// errNotADatabase 文件不是 SQLite 数据库
+	errNotADatabase = errors.New("not a sqlite database")
+	// errNotReadOnlySQL 查询语句不是单条 SELECT
+	errNotReadOnlySQL = errors.New("only a single SELECT statement is allowed")
+)
+
+// sqliteColumn 列信息
+type sqliteColumn struct {
+	Name       string  `json:"name"`                 // 列名
+	Type       string  `json:"type"`                 // 声明的类型
+	NotNull    bool    `json:"notNull,omitempty"`    // 是否 NOT NULL
+	PrimaryKey bool    `json:"primaryKey,omitempty"` // 是否为主键的一部分
+	Default    *string `json:"default,omitempty"`    // 默认值表达式
+}
+
+// sqliteTable 表或视图
+type sqliteTable struct {
+	Name     string         `json:"name"`               // 名称
+	Type     string         `json:"type"`               // table 或 view
+	SQL      string         `json:"sql"`                // 建表语句
+	Columns  []sqliteColumn `json:"columns"`            // 列信息
+	RowCount *int64         `json:"rowCount,omitempty"` // 行数，统计超时时不返回
+}
+
+// sqliteTablesResponse 表列表响应
+type sqliteTablesResponse struct {
+	Path     string        `json:"path"`     // 相对路径
+	Name     string        `json:"name"`     // 文件名
+	Size     int64         `json:"size"`     // 文件大小
+	Modified string        `json:"modified"` // 修改时间
+	Tables   []sqliteTable `json:"tables"`   // 表和视图
+}
+
+// sqliteBlob BLOB 值的摘要
+type sqliteBlob struct {
+	Type string `json:"type"` // 固定为 blob
+	Size int    `json:"size"` // 字节数
+	Hex  string `json:"hex"`  // 前 maxSQLiteBlobPrefix 字节的十六进制
+}
+
+// sqliteRowsResponse 行数据响应（分页浏览表或执行查询）
+type sqliteRowsResponse struct {
+	Path      string         `json:"path"`                // 相对路径
+	Table     string         `json:"table,omitempty"`     // 浏览的表
+	SQL       string         `json:"sql,omitempty"`       // 执行的查询
+	Columns   []sqliteColumn `json:"columns"`             // 结果列
+	Rows      [][]any        `json:"rows"`                // 行数据
+	Offset    int64          `json:"offset"`              // 起始行
+	Limit     int64          `json:"limit"`               // 每页行数
+	HasMore   bool           `json:"hasMore"`             // 是否还有更多行
+	TotalRows *int64         `json:"totalRows,omitempty"` // 表的总行数（仅浏览表时）
+}
+
+// openSQLite 以只读、不可变方式打开请求路径对应的 SQLite 数据库
+// immutable=1 使 SQLite 不加锁、不读写 -wal/-journal 文件，保证不会修改磁盘上的任何内容
+// 失败时已写入错误响应，返回 false
+func (s *Server) openSQLite(c *gin.Context) (*sql.DB, string, os.FileInfo, bool) {
+	absPath, relPath, err := s.resolvePath(c.Query("path"))
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return nil, "", nil, false
+	}
+	file, info, err := openRegularFile(absPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "OPEN_FAILED", err.Error())
+		return nil, "", nil, false
+	}
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(file, header)
+	file.Close()
+	if err != nil || !bytes.Equal(header, []byte(sqliteHeader)) {
+		abortWithError(c, http.StatusUnsupportedMediaType, "NOT_A_DATABASE", errNotADatabase.Error())
+		return nil, "", nil, false
+	}
+
+	dsn := url.URL{Scheme: "file", Path: absPath, RawQuery: "mode=ro&immutable=1&_pragma=query_only(1)"}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, "OPEN_FAILED", err.Error())
+		return nil, "", nil, false
+	}
+	db.SetMaxOpenConns(1)
+	return db, "/" + relPath, info, true
+}
+
+// handleSQLiteTables 列出 SQLite 数据库中的表和视图
+// GET /api/sqlite/tables?path=/app.db
+// 返回建表语句、列信息和行数（行数统计总共最多 sqliteCountTimeout，超时的表不返回行数）
+func (s *Server) handleSQLiteTables(c *gin.Context) {
+	db, displayPath, info, ok := s.openSQLite(c)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), sqliteQueryTimeout)
+	defer cancel()
+	tables, err := listSQLiteTables(ctx, db)
+	if err != nil {
+		abortSQLite(c, err)
+		return
+	}
+
+	countCtx, cancelCount := context.WithTimeout(ctx, sqliteCountTimeout)
+	defer cancelCount()
+	for i := range tables {
+		columns, err := sqliteTableColumns(ctx, db, tables[i].Name)
+		if err != nil {
+			abortSQLite(c, err)
+			return
+		}
+		tables[i].Columns = columns
+
+		var count int64
+		if err := db.QueryRowContext(countCtx, "SELECT COUNT(*) FROM "+quoteIdent(tables[i].Name)).Scan(&count); err == nil {
+			tables[i].RowCount = &count
+		}
+	}
+
+	c.JSON(http.StatusOK, sqliteTablesResponse{
+		Path:     displayPath,
+		Name:     info.Name(),
+		Size:     info.Size(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+		Tables:   tables,
+	})
+}
+
+// handleSQLiteRows 分页浏览表或视图的数据
+// GET /api/sqlite/rows?path=/app.db&table=users&offset=0&limit=100
+func (s *Server) handleSQLiteRows(c *gin.Context) {
+	offset, limit, ok := parseSQLitePaging(c)
+	if !ok {
+		return
+	}
+	table := c.Query("table")
+	if table == "" {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "table is required")
+		return
+	}
+
+	db, displayPath, _, ok := s.openSQLite(c)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), sqliteQueryTimeout)
+	defer cancel()
+
+	// 表名只能来自 sqlite_master，避免拼接任意 SQL
+	tables, err := listSQLiteTables(ctx, db)
+	if err != nil {
+		abortSQLite(c, err)
+		return
+	}
+	found := false
+	for _, t := range tables {
+		found = found || t.Name == table
+	}
+	if !found {
+		abortWithError(c, http.StatusNotFound, "TABLE_NOT_FOUND", fmt.Sprintf("table %q not found", table))
+		return
+	}
+
+	resp := sqliteRowsResponse{Path: displayPath, Table: table, Offset: offset, Limit: limit}
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT %d OFFSET %d", quoteIdent(table), limit+1, offset)
+	if err := querySQLiteRows(ctx, db, query, limit, &resp); err != nil {
+		abortSQLite(c, err)
+		return
+	}
+	var total int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteIdent(table)).Scan(&total); err == nil {
+		resp.TotalRows = &total
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleSQLiteQuery 执行用户提交的 SELECT 查询
+// GET /api/sqlite/query?path=/app.db&sql=SELECT...&limit=100
+// 只允许单条 SELECT/WITH/VALUES 语句，最多执行 sqliteQueryTimeout，最多返回 limit 行
+func (s *Server) handleSQLiteQuery(c *gin.Context) {
+	offset, limit, ok := parseSQLitePaging(c)
+	if !ok {
+		return
+	}
+	if offset != 0 {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", "offset is not supported for queries, use LIMIT/OFFSET in SQL")
+		return
+	}
+	query, err := checkReadOnlySQL(c.Query("sql"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_SQL", err.Error())
+		return
+	}
+
+	db, displayPath, _, ok := s.openSQLite(c)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), sqliteQueryTimeout)
+	defer cancel()
+
+	resp := sqliteRowsResponse{Path: displayPath, SQL: query, Limit: limit}
+	if err := querySQLiteRows(ctx, db, query, limit, &resp); err != nil {
+		abortSQLite(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// abortSQLite 根据错误类型返回 SQLite 相关的错误响应
+func abortSQLite(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		abortWithError(c, http.StatusRequestTimeout, "QUERY_TIMEOUT",
+			fmt.Sprintf("query exceeded %s", sqliteQueryTimeout))
+	case c.Request.Context().Err() != nil:
+		c.Abort() // 客户端已断开
+	default:
+		abortWithError(c, http.StatusUnprocessableEntity, "QUERY_FAILED", err.Error())
+	}
+}
+
+// parseSQLitePaging 解析 offset/limit，limit 默认 defaultSQLiteLimit
+func parseSQLitePaging(c *gin.Context) (int64, int64, bool) {
+	offset, limit, err := parseOffsetLimit(c, maxSQLiteLimit)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return 0, 0, false
+	}
+	if limit == 0 {
+		limit = defaultSQLiteLimit
+	}
+	return offset, limit, true
+}
+
+// listSQLiteTables 列出用户表和视图（不含 sqlite_ 开头的内部表）
+func listSQLiteTables(ctx context.Context, db *sql.DB) ([]sqliteTable, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT name, type, COALESCE(sql, '') FROM sqlite_master
+		 WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		 ORDER BY type, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []sqliteTable{}
+	for rows.Next() {
+		var t sqliteTable
+		if err := rows.Scan(&t.Name, &t.Type, &t.SQL); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// sqliteTableColumns 读取表或视图的列信息
+func sqliteTableColumns(ctx context.Context, db *sql.DB, table string) ([]sqliteColumn, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type, \"notnull\", dflt_value, pk FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []sqliteColumn{}
+	for rows.Next() {
+		var col sqliteColumn
+		var def sql.NullString
+		var pk int
+		if err := rows.Scan(&col.Name, &col.Type, &col.NotNull, &def, &pk); err != nil {
+			return nil, err
+		}
+		if def.Valid {
+			col.Default = &def.String
+		}
+		col.PrimaryKey = pk > 0
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// querySQLiteRows 执行查询并读取最多 limit 行，多读一行用于判断是否还有更多
+func querySQLiteRows(ctx context.Context, db *sql.DB, query string, limit int64, resp *sqliteRowsResponse) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	resp.Columns = make([]sqliteColumn, len(types))
+	for i, t := range types {
+		resp.Columns[i] = sqliteColumn{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+
+	resp.Rows = [][]any{}
+	values := make([]any, len(types))
+	ptrs := make([]any, len(types))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if int64(len(resp.Rows)) >= limit {
+			resp.HasMore = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		row := make([]any, len(values))
+		for i, v := range values {
+			row[i] = sqliteValue(v)
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+	return rows.Err()
+}
+
+// sqliteValue 将查询结果转换为可 JSON 序列化的值：过长文本截断，BLOB 只返回摘要
+func sqliteValue(v any) any {
+	switch x := v.(type) {
+	case string:
+		if len(x) > maxSQLiteText {
+			return string(trimIncompleteRune([]byte(x[:maxSQLiteText]))) + "…"
+		}
+		return x
+	case []byte:
+		if utf8.Valid(x) && !bytes.ContainsRune(x, 0) {
+			return sqliteValue(string(x))
+		}
+		return sqliteBlob{Type: "blob", Size: len(x), Hex: hex.EncodeToString(x[:min(len(x), maxSQLiteBlobPrefix)])}
+	}
+	return v
+}
+
+// quoteIdent 将标识符用双引号括起并转义
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// checkReadOnlySQL 检查查询是否为单条 SELECT/WITH/VALUES 语句，返回去掉末尾分号的语句
+// 数据库本身以只读方式打开，此检查额外阻止 ATTACH、PRAGMA 等可能访问其他文件的语句
+func checkReadOnlySQL(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", errNotReadOnlySQL
+	}
+
+	// 跳过开头的注释后检查第一个关键字
+	rest := skipSQLSpace(query)
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	switch strings.ToUpper(rest[:end]) {
+	case "SELECT", "WITH", "VALUES":
+	default:
+		return "", errNotReadOnlySQL
+	}
+
+	// 引号和注释之外的分号之后只能是空白或注释
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; ch {
+		case '\'', '"', '`':
+			j := strings.IndexByte(query[i+1:], ch)
+			if j < 0 {
+				return query, nil // 未闭合的引号交给 SQLite 报错
+			}
+			i += j + 1
+		case '[':
+			j := strings.IndexByte(query[i+1:], ']')
+			if j < 0 {
+				return query, nil
+			}
+			i += j + 1
+		case '-', '/':
+			if skipped := skipSQLSpace(query[i:]); len(skipped) < len(query[i:]) {
+				i = len(query) - len(skipped) - 1
+			}
+		case ';':
+			if skipSQLSpace(query[i+1:]) != "" {
+				return "", errNotReadOnlySQL
+			}
+			return strings.TrimSpace(query[:i]), nil
+		}
+	}
+	return query, nil
+}
+
+// skipSQLSpace 跳过开头的空白和注释（-- 行注释、/* */ 块注释）
+func skipSQLSpace(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		switch {
+		case strings.HasPrefix(s, "--"):
+			i := strings.IndexByte(s, '\n')
+			if i < 0 {
+				return ""
+			}
+			s = s[i+1:]
+		case strings.HasPrefix(s, "/*"):
+			i := strings.Index(s[2:], "*/")
+			if i < 0 {
+				return ""
+			}
+			s = s[i+4:]
+		default:
+			return s
+		}
+	}
+}
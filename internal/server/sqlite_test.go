@@ -0,0 +1,155 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		ok       bool
+	}{
+		{"SELECT 1", "SELECT 1", true},
+		{"  select * from t;  ", "select * from t", true},
+		{"-- comment\n/* block */ WITH x AS (SELECT 1) SELECT * FROM x", "-- comment\n/* block */ WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"SELECT ';' AS semi; -- trailing", "SELECT ';' AS semi", true},
+		{`SELECT "a;b" FROM [c;d]`, `SELECT "a;b" FROM [c;d]`, true},
+		{"VALUES (1), (2)", "VALUES (1), (2)", true},
+		{"", "", false},
+		{"DELETE FROM t", "", false},
+		{"ATTACH DATABASE 'x.db' AS x", "", false},
+		{"PRAGMA table_info(t)", "", false},
+		{"SELECT 1; DROP TABLE t", "", false},
+		{"SELECT 1 /* ; */; ATTACH 'x' AS y", "", false},
+	}
+	for _, tt := range tests {
+		got, err := checkReadOnlySQL(tt.query)
+		if !tt.ok {
+			assert.ErrorIs(t, err, errNotReadOnlySQL, tt.query)
+			continue
+		}
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, got)
+	}
+}
+
+func TestHandleSQLite(t *testing.T) {
+	root := t.TempDir()
+	dbPath := filepath.Join(root, "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, bio TEXT DEFAULT 'n/a', avatar BLOB);
+		CREATE TABLE "odd ""name""" (v INTEGER);
+		CREATE VIEW active AS SELECT id, name FROM users WHERE id % 2 = 0;`)
+	require.NoError(t, err)
+	for i := 0; i < 250; i++ {
+		_, err = db.Exec("INSERT INTO users (name, avatar) VALUES (?, ?)", fmt.Sprintf("user-%d", i), []byte{0x89, 0x50, 0x00, byte(i)})
+		require.NoError(t, err)
+	}
+	_, err = db.Exec(`INSERT INTO users (name, bio) VALUES ('long', ?)`, strings.Repeat("x", maxSQLiteText+10))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain text"), 0644))
+
+	before, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(target string, resp any) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		}
+		return w
+	}
+
+	// 列出表和视图
+	var tables sqliteTablesResponse
+	w := get("/api/sqlite/tables?path=/app.db", &tables)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, tables.Tables, 3)
+	assert.Equal(t, "odd \"name\"", tables.Tables[0].Name)
+	users := tables.Tables[1]
+	assert.Equal(t, "users", users.Name)
+	assert.Equal(t, "table", users.Type)
+	assert.Contains(t, users.SQL, "CREATE TABLE users")
+	require.Len(t, users.Columns, 4)
+	assert.True(t, users.Columns[0].PrimaryKey)
+	assert.True(t, users.Columns[1].NotNull)
+	assert.Equal(t, "'n/a'", *users.Columns[2].Default)
+	assert.Equal(t, int64(251), *users.RowCount)
+	assert.Equal(t, "view", tables.Tables[2].Type)
+	assert.Equal(t, int64(125), *tables.Tables[2].RowCount)
+
+	// 分页浏览
+	var rows sqliteRowsResponse
+	w = get("/api/sqlite/rows?path=/app.db&table=users&offset=200&limit=20", &rows)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"id", "name", "bio", "avatar"}, []string{rows.Columns[0].Name, rows.Columns[1].Name, rows.Columns[2].Name, rows.Columns[3].Name})
+	require.Len(t, rows.Rows, 20)
+	assert.Equal(t, 201.0, rows.Rows[0][0])
+	assert.Equal(t, "user-200", rows.Rows[0][1])
+	assert.Equal(t, map[string]any{"type": "blob", "size": 4.0, "hex": "895000c8"}, rows.Rows[0][3])
+	assert.True(t, rows.HasMore)
+	assert.Equal(t, int64(251), *rows.TotalRows)
+
+	rows = sqliteRowsResponse{}
+	get("/api/sqlite/rows?path=/app.db&table=users&offset=250", &rows)
+	require.Len(t, rows.Rows, 1)
+	assert.False(t, rows.HasMore)
+	assert.Len(t, rows.Rows[0][2], maxSQLiteText+len("…"))
+
+	rows = sqliteRowsResponse{}
+	w = get("/api/sqlite/rows?path=/app.db&table="+url.QueryEscape(`odd "name"`), &rows)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, rows.Rows)
+
+	// 查询
+	rows = sqliteRowsResponse{}
+	w = get("/api/sqlite/query?path=/app.db&limit=5&sql="+url.QueryEscape("SELECT name, id * 2 AS twice FROM users WHERE id > 10 ORDER BY id DESC;"), &rows)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "twice", rows.Columns[1].Name)
+	require.Len(t, rows.Rows, 5)
+	assert.Equal(t, "long", rows.Rows[0][0])
+	assert.Equal(t, 502.0, rows.Rows[0][1])
+	assert.True(t, rows.HasMore)
+
+	w = get("/api/sqlite/query?path=/app.db&sql="+url.QueryEscape("DELETE FROM users"), &rows)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = get("/api/sqlite/query?path=/app.db&sql="+url.QueryEscape("WITH x AS (SELECT 1) DELETE FROM users"), &rows)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = get("/api/sqlite/query?path=/app.db&sql="+url.QueryEscape("SELECT * FROM missing"), &rows)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = get("/api/sqlite/rows?path=/app.db&table=missing", &rows)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = get("/api/sqlite/rows?path=/app.db", &rows)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = get("/api/sqlite/tables?path=/notes.txt", &tables)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w = get("/api/sqlite/tables?path=/../etc/passwd", &tables)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	// 数据库文件未被修改，也没有生成 -journal/-wal 文件
+	after, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,707 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultStructuredLimit = 200              // 默认每层返回的子节点数 / 查询结果数
+	maxStructuredLimit     = 5000             // 每层最多返回的子节点数 / 查询结果数
+	nestedStructuredLimit  = 50               // 展开多层时，非顶层节点最多返回的子节点数
+	defaultStructuredDepth = 1                // 默认展开层数
+	maxStructuredDepth     = 5                // 最多展开层数
+	maxStructuredString    = 1024             // 字符串值最多返回的字节数
+	maxStructuredValue     = 64 * 1024        // 查询结果中单个值最多返回的 JSON 字节数
+	maxStructuredDocSize   = 32 * 1024 * 1024 // YAML/TOML 需完整载入内存，超过该大小拒绝解析
+	maxStructuredTokens    = 10 * 1000 * 1000 // YAML/TOML 展开后的最大 token 数，防止别名膨胀
+	maxYAMLAliasDepth      = 100              // YAML 别名最大嵌套深度
+)
+
+var (
+	// errStructuredParse 文件内容不是合法的 JSON/YAML/TOML
+	errStructuredParse = errors.New("parse failed")
+	// errPointerNotFound JSON Pointer 指向的节点不存在
+	errPointerNotFound = errors.New("pointer not found")
+	// errDocumentTooLarge YAML/TOML 文件超出 maxStructuredDocSize
+	errDocumentTooLarge = errors.New("document too large")
+)
+
+// structuredNode 结构化文档中的一个节点
+type structuredNode struct {
+	Key       string           `json:"key"`                 // 对象键或数组下标，根节点为空
+	Pointer   string           `json:"pointer"`             // 节点的 JSON Pointer
+	Type      string           `json:"type"`                // object、array、string、number、boolean、null
+	Value     any              `json:"value,omitempty"`     // 标量值
+	Truncated bool             `json:"truncated,omitempty"` // 字符串值是否被截断
+	Count     *int             `json:"count,omitempty"`     // 对象/数组的子节点数
+	Children  []structuredNode `json:"children,omitempty"`  // 已展开的子节点
+	HasMore   bool             `json:"hasMore,omitempty"`   // 是否还有未返回的子节点
+}
+
+// structuredMatch JSONPath 查询结果
+type structuredMatch struct {
+	Pointer string          `json:"pointer"`         // 匹配值的 JSON Pointer
+	Value   json.RawMessage `json:"value,omitempty"` // 匹配的值
+	Node    *structuredNode `json:"node,omitempty"`  // 值超过 maxStructuredValue 时只返回摘要
+}
+
+// structuredResponse 结构化预览响应
+type structuredResponse struct {
+	Path     string            `json:"path"`              // 相对路径
+	Name     string            `json:"name"`              // 文件名
+	Size     int64             `json:"size"`              // 文件大小
+	Modified string            `json:"modified"`          // 修改时间
+	Format   string            `json:"format"`            // 格式：json、yaml、toml
+	Node     *structuredNode   `json:"node,omitempty"`    // pointer 指向的节点（未指定 query 时）
+	Query    string            `json:"query,omitempty"`   // JSONPath 查询
+	Matches  []structuredMatch `json:"matches,omitempty"` // 查询结果
+	HasMore  bool              `json:"hasMore,omitempty"` // 查询结果是否超出 limit
+}
+
+// handleStructured 以树形结构预览 JSON/YAML/TOML 文件
+// GET /api/structured?path=/data.json[&pointer=/items/0&depth=1&offset=0&limit=200]
+// GET /api/structured?path=/data.json&query=$.items[*].name
+// 返回 pointer 处节点的折叠树（键、类型、子节点数），depth 控制展开层数，offset/limit 对顶层子节点分页；
+// 指定 query 时返回 JSONPath 匹配结果。JSON 按 token 流式解析，不会整体载入内存
+func (s *Server) handleStructured(c *gin.Context) {
+	src, ok := s.openPreviewSource(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	defer src.close()
+	info := src.info
+
+	head, err := readSniffHead(src.reader)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+		return
+	}
+	format := structuredFormat(c.Query("format"), info.Name(), head)
+	if format == "" {
+		abortWithError(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_FORMAT", "file is not JSON, YAML or TOML")
+		return
+	}
+
+	offset, limit, err := parseOffsetLimit(c, maxStructuredLimit)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultStructuredLimit
+	}
+	depth := defaultStructuredDepth
+	if v := c.Query("depth"); v != "" {
+		depth, err = strconv.Atoi(v)
+		if err != nil || depth < 0 || depth > maxStructuredDepth {
+			abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS",
+				fmt.Sprintf("depth must be between 0 and %d", maxStructuredDepth))
+			return
+		}
+	}
+
+	query := c.Query("query")
+	var segments []pathSegment
+	var pointer []string
+	if query != "" {
+		segments, err = parseJSONPath(query)
+	} else {
+		pointer, err = parseJSONPointer(c.Query("pointer"))
+	}
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+
+	source, err := newStructuredSource(format, io.NewSectionReader(src.reader, 0, info.Size()), info.Size())
+	if err != nil {
+		abortStructured(c, err)
+		return
+	}
+	defer source.close()
+
+	resp := structuredResponse{
+		Path:     src.path,
+		Name:     info.Name(),
+		Size:     info.Size(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+		Format:   format,
+		Query:    query,
+	}
+	w := &structuredWalker{ctx: c.Request.Context(), src: source, limit: int(limit)}
+	root, err := w.next()
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: empty document", errStructuredParse)
+	}
+	if err == nil {
+		if query != "" {
+			resp.Matches = []structuredMatch{}
+			_, err = w.query(root, segments, nil, &resp.Matches)
+			resp.HasMore = w.hasMore
+		} else {
+			var node structuredNode
+			node, err = w.find(root, pointer, int(offset), depth)
+			resp.Node = &node
+		}
+	}
+	if err != nil {
+		abortStructured(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// abortStructured 根据错误类型返回结构化预览的错误响应
+func abortStructured(c *gin.Context, err error) {
+	switch {
+	case c.Request.Context().Err() != nil:
+		c.Abort() // 客户端已断开
+	case errors.Is(err, errPointerNotFound):
+		abortWithError(c, http.StatusNotFound, "POINTER_NOT_FOUND", err.Error())
+	case errors.Is(err, errDocumentTooLarge):
+		abortWithError(c, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", err.Error())
+	case errors.Is(err, errStructuredParse):
+		abortWithError(c, http.StatusUnprocessableEntity, "PARSE_FAILED", err.Error())
+	default:
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", err.Error())
+	}
+}
+
+// structuredFormat 根据参数、扩展名或内容确定格式，无法识别时返回空字符串
+func structuredFormat(param, name string, head []byte) string {
+	switch param {
+	case "json", "yaml", "toml":
+		return param
+	case "":
+	default:
+		return ""
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".geojson", ".webmanifest":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte{0xef, 0xbb, 0xbf}), " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return "json"
+	}
+	return ""
+}
+
+// tokenSource 产生 JSON token 的数据源：JSON 为流式解码器，YAML/TOML 为内存中文档的遍历
+type tokenSource interface {
+	Token() (json.Token, error)
+	close()
+}
+
+// jsonSource 流式解析 JSON
+type jsonSource struct {
+	*json.Decoder
+}
+
+func (jsonSource) close() {}
+
+// seqSource 将内存中文档的遍历序列转换为 tokenSource
+type seqSource struct {
+	next   func() (json.Token, bool)
+	stop   func()
+	tokens int
+}
+
+// tokenError 遍历内存文档时出现的错误，作为特殊 token 传递
+type tokenError struct{ err error }
+
+func (s *seqSource) Token() (json.Token, error) {
+	tok, ok := s.next()
+	if !ok {
+		return nil, io.EOF
+	}
+	if e, ok := tok.(tokenError); ok {
+		return nil, e.err
+	}
+	s.tokens++
+	if s.tokens > maxStructuredTokens {
+		return nil, fmt.Errorf("%w: more than %d tokens", errDocumentTooLarge, maxStructuredTokens)
+	}
+	return tok, nil
+}
+
+func (s *seqSource) close() { s.stop() }
+
+// newStructuredSource 创建对应格式的 token 数据源
+func newStructuredSource(format string, r io.Reader, size int64) (tokenSource, error) {
+	if format == "json" {
+		br := bufio.NewReaderSize(r, 64*1024)
+		// 跳过 UTF-8 BOM
+		if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xef, 0xbb, 0xbf}) {
+			br.Discard(3)
+		}
+		dec := json.NewDecoder(br)
+		dec.UseNumber()
+		return jsonSource{dec}, nil
+	}
+
+	if size > maxStructuredDocSize {
+		return nil, fmt.Errorf("%w: %s files larger than %d bytes are not supported", errDocumentTooLarge, format, maxStructuredDocSize)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var seq iter.Seq[json.Token]
+	switch format {
+	case "yaml":
+		var docs []*yaml.Node
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.Node
+			if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%w: %v", errStructuredParse, err)
+			}
+			docs = append(docs, &doc)
+		}
+		seq = yamlTokens(docs)
+	case "toml":
+		var doc map[string]any
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", errStructuredParse, err)
+		}
+		seq = func(yield func(json.Token) bool) { valueTokens(doc, yield) }
+	}
+	next, stop := iter.Pull(seq)
+	return &seqSource{next: next, stop: stop}, nil
+}
+
+// yamlTokens 遍历 YAML 文档，多个文档时作为数组返回
+func yamlTokens(docs []*yaml.Node) iter.Seq[json.Token] {
+	return func(yield func(json.Token) bool) {
+		switch len(docs) {
+		case 0:
+			return
+		case 1:
+			yamlNodeTokens(docs[0], 0, yield)
+			return
+		}
+		if !yield(json.Delim('[')) {
+			return
+		}
+		for _, doc := range docs {
+			if !yamlNodeTokens(doc, 0, yield) {
+				return
+			}
+		}
+		yield(json.Delim(']'))
+	}
+}
+
+// yamlNodeTokens 按顺序遍历 YAML 节点（保留映射键的原始顺序），返回 false 表示停止遍历
+func yamlNodeTokens(node *yaml.Node, aliasDepth int, yield func(json.Token) bool) bool {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return yield(nil)
+		}
+		return yamlNodeTokens(node.Content[0], aliasDepth, yield)
+	case yaml.MappingNode:
+		if !yield(json.Delim('{')) {
+			return false
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !yield(node.Content[i].Value) || !yamlNodeTokens(node.Content[i+1], aliasDepth, yield) {
+				return false
+			}
+		}
+		return yield(json.Delim('}'))
+	case yaml.SequenceNode:
+		if !yield(json.Delim('[')) {
+			return false
+		}
+		for _, child := range node.Content {
+			if !yamlNodeTokens(child, aliasDepth, yield) {
+				return false
+			}
+		}
+		return yield(json.Delim(']'))
+	case yaml.AliasNode:
+		if aliasDepth >= maxYAMLAliasDepth {
+			yield(tokenError{fmt.Errorf("%w: alias nesting too deep", errStructuredParse)})
+			return false
+		}
+		return yamlNodeTokens(node.Alias, aliasDepth+1, yield)
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		value = node.Value
+	}
+	return yield(scalarToken(value))
+}
+
+// valueTokens 遍历解码后的通用值（TOML），对象键按字母顺序排列
+func valueTokens(v any, yield func(json.Token) bool) bool {
+	switch x := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !yield(json.Delim('{')) {
+			return false
+		}
+		for _, k := range keys {
+			if !yield(k) || !valueTokens(x[k], yield) {
+				return false
+			}
+		}
+		return yield(json.Delim('}'))
+	case []any:
+		if !yield(json.Delim('[')) {
+			return false
+		}
+		for _, item := range x {
+			if !valueTokens(item, yield) {
+				return false
+			}
+		}
+		return yield(json.Delim(']'))
+	}
+	return yield(scalarToken(v))
+}
+
+// scalarToken 将标量转换为 JSON token（string、json.Number、bool 或 nil）
+func scalarToken(v any) json.Token {
+	switch x := v.(type) {
+	case nil, bool, string:
+		return x
+	case int:
+		return json.Number(strconv.Itoa(x))
+	case int64:
+		return json.Number(strconv.FormatInt(x, 10))
+	case uint64:
+		return json.Number(strconv.FormatUint(x, 10))
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return strconv.FormatFloat(x, 'g', -1, 64)
+		}
+		return json.Number(strconv.FormatFloat(x, 'g', -1, 64))
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// structuredWalker 在 token 流上完成节点展开和查询
+type structuredWalker struct {
+	ctx     context.Context
+	src     tokenSource
+	limit   int  // 顶层子节点数 / 查询结果数上限
+	tokens  int  // 已读取的 token 数
+	hasMore bool // 查询结果超出 limit
+}
+
+// next 读取下一个 token，定期检查请求是否已取消
+func (w *structuredWalker) next() (json.Token, error) {
+	w.tokens++
+	if w.tokens%4096 == 0 {
+		if err := w.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	tok, err := w.src.Token()
+	if err == nil || errors.Is(err, errStructuredParse) || errors.Is(err, errDocumentTooLarge) {
+		return tok, err
+	}
+	if errors.Is(err, io.EOF) && w.tokens == 1 {
+		return nil, io.EOF
+	}
+	return nil, fmt.Errorf("%w: %v", errStructuredParse, err)
+}
+
+// isOpen 判断 token 是否为对象或数组的开始
+func isOpen(tok json.Token) (json.Delim, bool) {
+	d, ok := tok.(json.Delim)
+	return d, ok && (d == '{' || d == '[')
+}
+
+// skip 跳过以 tok 开始的值
+func (w *structuredWalker) skip(tok json.Token) error {
+	if _, ok := isOpen(tok); !ok {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		t, err := w.next()
+		if err != nil {
+			return err
+		}
+		if d, ok := t.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// children 依次读取容器的子节点，对每个子节点调用 fn（fn 必须读取或跳过子节点的值）
+func (w *structuredWalker) children(open json.Delim, fn func(step pathStep, value json.Token) (bool, error)) error {
+	for i := 0; ; i++ {
+		tok, err := w.next()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			return nil
+		}
+		step := pathStep{index: i}
+		if open == '{' {
+			key, _ := tok.(string)
+			step = pathStep{key: key, isKey: true}
+			if tok, err = w.next(); err != nil {
+				return err
+			}
+		}
+		stop, err := fn(step, tok)
+		if err != nil || stop {
+			return err
+		}
+	}
+}
+
+// find 定位 JSON Pointer 指向的节点并展开
+func (w *structuredWalker) find(tok json.Token, pointer []string, offset, depth int) (structuredNode, error) {
+	var steps []pathStep
+	for _, token := range pointer {
+		open, ok := isOpen(tok)
+		if !ok {
+			return structuredNode{}, fmt.Errorf("%w: %s", errPointerNotFound, stepsPointer(steps))
+		}
+		found := false
+		err := w.children(open, func(step pathStep, value json.Token) (bool, error) {
+			if (step.isKey && step.key == token) || (!step.isKey && strconv.Itoa(step.index) == token) {
+				steps = append(steps, step)
+				tok, found = value, true
+				return true, nil
+			}
+			return false, w.skip(value)
+		})
+		if err != nil {
+			return structuredNode{}, err
+		}
+		if !found {
+			return structuredNode{}, fmt.Errorf("%w: %s", errPointerNotFound, stepsPointer(append(steps, pathStep{key: token, isKey: true})))
+		}
+	}
+	return w.buildNode(tok, steps, offset, depth, w.limit)
+}
+
+// buildNode 读取以 tok 开始的值并构建节点，depth > 0 时展开子节点（从第 offset 个开始，最多 limit 个）
+func (w *structuredWalker) buildNode(tok json.Token, steps []pathStep, offset, depth, limit int) (structuredNode, error) {
+	node := structuredNode{Key: stepKey(steps), Pointer: stepsPointer(steps)}
+
+	open, ok := isOpen(tok)
+	if !ok {
+		node.Type, node.Value, node.Truncated = scalarInfo(tok)
+		return node, nil
+	}
+	node.Type = "array"
+	if open == '{' {
+		node.Type = "object"
+	}
+
+	count := 0
+	err := w.children(open, func(step pathStep, value json.Token) (bool, error) {
+		count++
+		if depth == 0 || count <= offset || len(node.Children) >= limit {
+			return false, w.skip(value)
+		}
+		child, err := w.buildNode(value, append(steps, step), 0, depth-1, min(limit, nestedStructuredLimit))
+		node.Children = append(node.Children, child)
+		return false, err
+	})
+	if err != nil {
+		return node, err
+	}
+	node.Count = &count
+	node.HasMore = depth > 0 && offset+len(node.Children) < count
+	return node, nil
+}
+
+// stepKey 返回最后一步位置的键（数组下标转换为字符串），根节点返回空字符串
+func stepKey(steps []pathStep) string {
+	if len(steps) == 0 {
+		return ""
+	}
+	last := steps[len(steps)-1]
+	if last.isKey {
+		return last.key
+	}
+	return strconv.Itoa(last.index)
+}
+
+// scalarInfo 返回标量的类型和值，过长的字符串会被截断
+func scalarInfo(tok json.Token) (string, any, bool) {
+	switch x := tok.(type) {
+	case nil:
+		return "null", nil, false
+	case bool:
+		return "boolean", x, false
+	case json.Number, float64:
+		return "number", x, false
+	case string:
+		if len(x) > maxStructuredString {
+			return "string", strings.ToValidUTF8(x[:maxStructuredString], ""), true
+		}
+		return "string", x, false
+	}
+	return "string", fmt.Sprint(tok), false
+}
+
+// query 在以 tok 开始的值中查找匹配 JSONPath 的位置，返回 true 表示结果已满需停止
+// 匹配的值不再向下查找（嵌套在匹配值内部的匹配不会单独返回）
+func (w *structuredWalker) query(tok json.Token, segments []pathSegment, steps []pathStep, matches *[]structuredMatch) (bool, error) {
+	if matchPath(segments, steps) {
+		if len(*matches) >= w.limit {
+			w.hasMore = true
+			return true, nil
+		}
+		match, err := w.capture(tok, steps)
+		*matches = append(*matches, match)
+		return false, err
+	}
+
+	open, ok := isOpen(tok)
+	if !ok {
+		return false, nil
+	}
+	if !prefixMatch(segments, steps) {
+		return false, w.skip(tok)
+	}
+	stopped := false
+	err := w.children(open, func(step pathStep, value json.Token) (bool, error) {
+		stop, err := w.query(value, segments, append(steps, step), matches)
+		stopped = stop
+		return stop, err
+	})
+	return stopped, err
+}
+
+// capture 读取以 tok 开始的完整值并重新编码为 JSON，超过 maxStructuredValue 时改为返回节点摘要
+func (w *structuredWalker) capture(tok json.Token, steps []pathStep) (structuredMatch, error) {
+	match := structuredMatch{Pointer: stepsPointer(steps)}
+	var enc tokenEncoder
+	enc.write(tok)
+
+	open, isContainer := isOpen(tok)
+	direct := 0 // 顶层容器内直接出现的 token 数，用于计算子节点数
+	for depth := 1; isContainer && depth > 0; {
+		t, err := w.next()
+		if err != nil {
+			return match, err
+		}
+		if d, ok := t.(json.Delim); ok && (d == '}' || d == ']') {
+			depth--
+		} else {
+			if depth == 1 {
+				direct++
+			}
+			if _, ok := isOpen(t); ok {
+				depth++
+			}
+		}
+		if enc.buf.Len() <= maxStructuredValue {
+			enc.write(t)
+		}
+	}
+
+	if enc.buf.Len() <= maxStructuredValue {
+		match.Value = enc.buf.Bytes()
+		return match, nil
+	}
+	// 值已被读取，摘要直接根据起始 token 和统计结果构建
+	node := structuredNode{Key: stepKey(steps), Pointer: match.Pointer}
+	switch {
+	case open == '{':
+		direct /= 2
+		node.Type, node.Count = "object", &direct
+	case isContainer:
+		node.Type, node.Count = "array", &direct
+	default:
+		node.Type, node.Value, node.Truncated = scalarInfo(tok)
+	}
+	match.Node = &node
+	return match, nil
+}
+
+// tokenEncoder 将 token 序列重新编码为 JSON 文本
+type tokenEncoder struct {
+	buf   bytes.Buffer
+	stack []tokenFrame
+}
+
+// tokenFrame 正在编码的容器
+type tokenFrame struct {
+	object bool
+	n      int // 已写入的 token 数（对象中键和值各计一个）
+}
+
+func (e *tokenEncoder) write(tok json.Token) {
+	if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+		e.stack = e.stack[:len(e.stack)-1]
+		e.buf.WriteByte(byte(d))
+		return
+	}
+	if n := len(e.stack); n > 0 {
+		top := &e.stack[n-1]
+		switch {
+		case top.object && top.n%2 == 1:
+			e.buf.WriteByte(':')
+		case top.n > 0:
+			e.buf.WriteByte(',')
+		}
+		top.n++
+	}
+
+	switch x := tok.(type) {
+	case json.Delim:
+		e.buf.WriteByte(byte(x))
+		e.stack = append(e.stack, tokenFrame{object: x == '{'})
+	case json.Number:
+		e.buf.WriteString(string(x))
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			data = []byte("null")
+		}
+		e.buf.Write(data)
+	}
+}
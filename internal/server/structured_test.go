@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		query    string
+		steps    []pathStep
+		expected bool
+	}{
+		{"$", nil, true},
+		{"$.a.b", []pathStep{{key: "a", isKey: true}, {key: "b", isKey: true}}, true},
+		{"$['a.b'][2]", []pathStep{{key: "a.b", isKey: true}, {index: 2}}, true},
+		{"$.items[*].name", []pathStep{{key: "items", isKey: true}, {index: 7}, {key: "name", isKey: true}}, true},
+		{"$.items[1:3]", []pathStep{{key: "items", isKey: true}, {index: 3}}, false},
+		{"$..id", []pathStep{{key: "x", isKey: true}, {index: 0}, {key: "id", isKey: true}}, true},
+		{"$..id", []pathStep{{key: "id", isKey: true}, {key: "x", isKey: true}}, false},
+		{"$.*", []pathStep{{index: 0}}, true},
+	}
+	for _, tt := range tests {
+		segments, err := parseJSONPath(tt.query)
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, matchPath(segments, tt.steps), tt.query)
+	}
+
+	for _, query := range []string{"a.b", "$.", "$[-1]", "$['x'", "$[1", "$x"} {
+		_, err := parseJSONPath(query)
+		assert.ErrorIs(t, err, errInvalidQuery, query)
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	tokens, err := parseJSONPointer("/a~1b/m~0n/0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "m~n", "0"}, tokens)
+	assert.Equal(t, "/a~1b/m~0n/0", stepsPointer([]pathStep{{key: "a/b", isKey: true}, {key: "m~n", isKey: true}, {index: 0}}))
+
+	_, err = parseJSONPointer("a")
+	assert.ErrorIs(t, err, errInvalidQuery)
+}
+
+func TestHandleStructured(t *testing.T) {
+	root := t.TempDir()
+	var items []string
+	for i := 0; i < 300; i++ {
+		items = append(items, fmt.Sprintf(`{"id":%d,"name":"item-%d","tags":["a","b"]}`, i, i))
+	}
+	doc := `{"zeta":1,"items":[` + strings.Join(items, ",") + `],"a/b":{"ok":true,"nil":null},"big":"` +
+		strings.Repeat("x", 70*1024) + `"}`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "data.json"), []byte(doc), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "broken.json"), []byte(`{"a": [1, 2`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "config.yaml"), []byte("server:\n  port: 8080\n  host: localhost\nbase: &b\n  x: 1\ncopy: *b\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "multi.yml"), []byte("a: 1\n---\nb: 2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.toml"), []byte("title = \"demo\"\n[db]\nport = 5432\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain text"), 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(url string) (*httptest.ResponseRecorder, structuredResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var resp structuredResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	// 根节点：保留键的原始顺序，子容器只返回子节点数
+	w, resp := get("/api/structured?path=/data.json")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "json", resp.Format)
+	require.NotNil(t, resp.Node)
+	assert.Equal(t, "object", resp.Node.Type)
+	require.Len(t, resp.Node.Children, 4)
+	assert.Equal(t, []string{"zeta", "items", "a/b", "big"}, []string{
+		resp.Node.Children[0].Key, resp.Node.Children[1].Key, resp.Node.Children[2].Key, resp.Node.Children[3].Key,
+	})
+	assert.Equal(t, 1.0, resp.Node.Children[0].Value)
+	assert.Equal(t, 300, *resp.Node.Children[1].Count)
+	assert.Nil(t, resp.Node.Children[1].Children)
+	assert.True(t, resp.Node.Children[3].Truncated)
+
+	// 按 JSON Pointer 展开并分页
+	_, resp = get("/api/structured?path=/data.json&pointer=/items&offset=250&limit=10")
+	assert.Len(t, resp.Node.Children, 10)
+	assert.Equal(t, "/items/250", resp.Node.Children[0].Pointer)
+	assert.True(t, resp.Node.HasMore)
+
+	_, resp = get("/api/structured?path=/data.json&pointer=/a~1b&depth=0")
+	assert.Equal(t, 2, *resp.Node.Count)
+	assert.Empty(t, resp.Node.Children)
+
+	_, resp = get("/api/structured?path=/data.json&pointer=/items/1&depth=2")
+	require.Len(t, resp.Node.Children, 3)
+	assert.Len(t, resp.Node.Children[2].Children, 2)
+
+	// JSONPath 查询
+	_, resp = get("/api/structured?path=/data.json&query=$.items[1:3].name")
+	require.Len(t, resp.Matches, 2)
+	assert.Equal(t, "/items/1/name", resp.Matches[0].Pointer)
+	assert.JSONEq(t, `"item-1"`, string(resp.Matches[0].Value))
+
+	_, resp = get("/api/structured?path=/data.json&query=$..id&limit=5")
+	assert.Len(t, resp.Matches, 5)
+	assert.True(t, resp.HasMore)
+
+	_, resp = get("/api/structured?path=/data.json&query=$['a/b']")
+	require.Len(t, resp.Matches, 1)
+	assert.JSONEq(t, `{"ok":true,"nil":null}`, string(resp.Matches[0].Value))
+
+	// 过大的值只返回摘要
+	_, resp = get("/api/structured?path=/data.json&query=$.big")
+	require.Len(t, resp.Matches, 1)
+	assert.Nil(t, resp.Matches[0].Value)
+	require.NotNil(t, resp.Matches[0].Node)
+	assert.Equal(t, "string", resp.Matches[0].Node.Type)
+
+	// YAML：保留顺序并展开别名
+	_, resp = get("/api/structured?path=/config.yaml&depth=2")
+	assert.Equal(t, "yaml", resp.Format)
+	assert.Equal(t, "port", resp.Node.Children[0].Children[0].Key)
+	assert.Equal(t, 8080.0, resp.Node.Children[0].Children[0].Value)
+	_, resp = get("/api/structured?path=/config.yaml&query=$.copy.x")
+	require.Len(t, resp.Matches, 1)
+	assert.JSONEq(t, `1`, string(resp.Matches[0].Value))
+
+	// 多文档 YAML 作为数组返回
+	_, resp = get("/api/structured?path=/multi.yml")
+	assert.Equal(t, "array", resp.Node.Type)
+	assert.Equal(t, 2, *resp.Node.Count)
+
+	_, resp = get("/api/structured?path=/app.toml&pointer=/db/port")
+	assert.Equal(t, "toml", resp.Format)
+	assert.Equal(t, 5432.0, resp.Node.Value)
+
+	w, _ = get("/api/structured?path=/data.json&pointer=/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = get("/api/structured?path=/data.json&query=items")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/structured?path=/data.json&depth=9")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/structured?path=/broken.json&depth=3")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w, _ = get("/api/structured?path=/notes.txt")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
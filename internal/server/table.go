@@ -0,0 +1,450 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTableLimit   = 100       // 默认每页行数
+	maxTableLimit       = 1000      // 每页最多行数
+	tableIndexStride    = 1024      // 行索引每隔多少行记录一个检查点
+	tableIndexCacheSize = 16        // 最多缓存的表格行索引数量
+	maxTableSortRows    = 2_000_000 // 排序时最多处理的行数
+)
+
+// errTooManyRows 排序的行数超出 maxTableSortRows
+var errTooManyRows = errors.New("too many rows to sort")
+
+// tableColumn 列信息
+type tableColumn struct {
+	Name string `json:"name"` // 列名（无表头时为 A、B、C…）
+	Type string `json:"type"` // 推断的类型：string、integer、number、boolean、date
+}
+
+// tableResponse 表格预览响应
+type tableResponse struct {
+	Path      string        `json:"path"`                // 相对路径
+	Name      string        `json:"name"`                // 文件名
+	Size      int64         `json:"size"`                // 文件大小
+	Modified  string        `json:"modified"`            // 修改时间
+	Encoding  string        `json:"encoding"`            // 字符编码
+	Delimiter string        `json:"delimiter"`           // 字段分隔符
+	Quote     string        `json:"quote"`               // 引号字符，空字符串表示不处理引号
+	HasHeader bool          `json:"hasHeader"`           // 第一行是否为表头
+	Columns   []tableColumn `json:"columns"`             // 列信息
+	Rows      [][]string    `json:"rows"`                // 当前页的数据行
+	Offset    int64         `json:"offset"`              // 当前页起始行号（从 0 开始，不含表头）
+	HasMore   bool          `json:"hasMore"`             // 是否还有更多行
+	TotalRows *int64        `json:"totalRows,omitempty"` // 总行数（筛选时为匹配行数），尚未扫描完整个文件时不返回
+}
+
+// tableQuery 排序和筛选参数
+type tableQuery struct {
+	sortColumn   int // 排序列，-1 表示不排序
+	desc         bool
+	sortType     string
+	filterColumn int // 筛选列，-1 表示不筛选
+	filter       string
+}
+
+// handleTable 以表格形式预览 CSV/TSV 文件
+// GET /api/table?path=/data.csv&offset=0&limit=100
+// GET /api/table?path=/data.csv&sort=price&order=desc&filter=city&q=shanghai
+// 自动检测编码、分隔符、引号和表头（可通过 encoding/delimiter/quote/header 参数指定），
+// 按行号分页并推断列类型；sort/filter 可使用列名或从 0 开始的列序号，筛选为忽略大小写的子串匹配
+func (s *Server) handleTable(c *gin.Context) {
+	src, ok := s.openPreviewSource(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	defer src.close()
+	info := src.info
+
+	offset, limit, err := parseOffsetLimit(c, maxTableLimit)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultTableLimit
+	}
+
+	head := make([]byte, min(info.Size(), tableSniffSize))
+	if _, err := src.reader.ReadAt(head, 0); err != nil && !errors.Is(err, io.EOF) {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+		return
+	}
+	overrides := tableOverrides{
+		Encoding:  c.Query("encoding"),
+		Delimiter: c.Query("delimiter"),
+		Quote:     c.Query("quote"),
+		Header:    c.Query("header"),
+	}
+	dialect, records, err := detectTableDialect(info.Name(), head, int64(len(head)) < info.Size(), overrides)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_DIALECT", err.Error())
+		return
+	}
+	// UTF-16 含有 NUL 字节，其余编码按魔数和控制字符排除二进制文件
+	if dialect.Encoding != tableEncodingUTF16L && dialect.Encoding != tableEncodingUTF16B &&
+		!isTextMediaType(http.DetectContentType(head)) {
+		abortWithError(c, http.StatusUnsupportedMediaType, "NOT_A_TABLE", "file is not a text table")
+		return
+	}
+
+	resp := tableResponse{
+		Path:      src.path,
+		Name:      info.Name(),
+		Size:      info.Size(),
+		Modified:  info.ModTime().UTC().Format(time.RFC3339),
+		Encoding:  dialect.Encoding,
+		Delimiter: string(dialect.Delimiter),
+		HasHeader: dialect.Header,
+		Columns:   tableColumns(records, dialect.Header),
+		Offset:    offset,
+		Rows:      [][]string{},
+	}
+	if dialect.Quote != 0 {
+		resp.Quote = string(dialect.Quote)
+	}
+
+	query, err := parseTableQuery(c, resp.Columns)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "INVALID_COLUMN", err.Error())
+		return
+	}
+
+	// 表头之后的数据起始偏移
+	dataStart := dialect.bom
+	if dialect.Header {
+		reader := newTableReader(src.reader, dialect.bom, info.Size(), dialect)
+		if _, _, err := reader.readRecord(); err == nil {
+			dataStart = reader.pos
+		} else if errors.Is(err, io.EOF) {
+			dataStart = info.Size()
+		}
+	}
+
+	if query.sortColumn >= 0 || query.filterColumn >= 0 {
+		err = queryTable(c, src, dialect, dataStart, query, offset, limit, &resp)
+	} else {
+		err = s.readTablePage(src, dialect, dataStart, offset, limit, &resp)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errRecordTooLarge), errors.Is(err, errTooManyRows):
+			abortWithError(c, http.StatusRequestEntityTooLarge, "TABLE_TOO_LARGE", err.Error())
+		case c.Request.Context().Err() != nil:
+			c.Abort() // 客户端已断开
+		default:
+			abortWithError(c, http.StatusInternalServerError, "READ_FAILED", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// tableColumns 根据样本行生成列信息：列数取样本中的最大字段数，类型由数据行推断
+func tableColumns(records [][]string, header bool) []tableColumn {
+	count := 0
+	for _, record := range records {
+		count = max(count, len(record))
+	}
+	columns := make([]tableColumn, count)
+	for i := range columns {
+		columns[i].Name = columnName(i)
+		if header && i < len(records[0]) && strings.TrimSpace(records[0][i]) != "" {
+			columns[i].Name = strings.TrimSpace(records[0][i])
+		}
+	}
+
+	data := records
+	if header && len(data) > 0 {
+		data = data[1:]
+	}
+	for _, record := range data {
+		for i, value := range record {
+			columns[i].Type = mergeColumnType(columns[i].Type, inferValueType(value))
+		}
+	}
+	for i := range columns {
+		if columns[i].Type == "" {
+			columns[i].Type = columnString
+		}
+	}
+	return columns
+}
+
+// parseTableQuery 解析排序和筛选参数
+func parseTableQuery(c *gin.Context, columns []tableColumn) (tableQuery, error) {
+	query := tableQuery{sortColumn: -1, filterColumn: -1}
+	var err error
+	if v := c.Query("sort"); v != "" {
+		if query.sortColumn, err = findColumn(columns, v); err != nil {
+			return query, err
+		}
+		query.sortType = columns[query.sortColumn].Type
+		switch c.DefaultQuery("order", "asc") {
+		case "asc":
+		case "desc":
+			query.desc = true
+		default:
+			return query, errors.New("order must be asc or desc")
+		}
+	}
+	if v := c.Query("filter"); v != "" && c.Query("q") != "" {
+		if query.filterColumn, err = findColumn(columns, v); err != nil {
+			return query, err
+		}
+		query.filter = strings.ToLower(c.Query("q"))
+	}
+	return query, nil
+}
+
+// findColumn 按列名或从 0 开始的序号查找列，列名优先
+func findColumn(columns []tableColumn, name string) (int, error) {
+	for i, column := range columns {
+		if column.Name == name {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(columns) {
+		return i, nil
+	}
+	return 0, fmt.Errorf("unknown column %q", name)
+}
+
+// readTablePage 借助行索引跳转到第 offset 行并读取 limit 行
+func (s *Server) readTablePage(src *previewSource, dialect *tableDialect, dataStart, offset, limit int64, resp *tableResponse) error {
+	idx := s.tables.get(src.key+"|"+dialect.String(), src.info)
+	defer idx.mu.Unlock()
+
+	start, err := idx.rowOffset(src.reader, src.info.Size(), dialect, dataStart, offset)
+	if err != nil {
+		return err
+	}
+	reader := newTableReader(src.reader, start, src.info.Size(), dialect)
+	for int64(len(resp.Rows)) < limit {
+		record, _, err := reader.readRecord()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		resp.Rows = append(resp.Rows, record)
+	}
+
+	// 读取完本页后顺带检查是否还有下一行
+	if _, _, err := reader.readRecord(); err == nil {
+		resp.HasMore = true
+	} else if !errors.Is(err, io.EOF) {
+		return err
+	}
+	if idx.complete {
+		total := idx.rows
+		resp.TotalRows = &total
+	} else if !resp.HasMore {
+		total := offset + int64(len(resp.Rows))
+		resp.TotalRows = &total
+	}
+	return nil
+}
+
+// sortKey 排序时每行保留的信息
+type sortKey struct {
+	offset int64   // 行起始偏移
+	number float64 // 数值、日期（Unix 纳秒）或布尔（0/1）
+	text   string  // 字符串列的值（小写）
+	valid  bool    // 值非空且可解析，无效值始终排在最后
+}
+
+// queryTable 扫描整个文件完成筛选和排序，返回第 offset 行起的 limit 行
+// 排序时只在内存中保留排序列的值和行偏移，取页面数据时再按偏移读取整行
+func queryTable(c *gin.Context, src *previewSource, dialect *tableDialect, dataStart int64, query tableQuery, offset, limit int64, resp *tableResponse) error {
+	ctx := c.Request.Context()
+	reader := newTableReader(src.reader, dataStart, src.info.Size(), dialect)
+	var keys []sortKey
+	matched := int64(0)
+	for i := 0; ; i++ {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		record, start, err := reader.readRecord()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if query.filterColumn >= 0 && !strings.Contains(strings.ToLower(field(record, query.filterColumn)), query.filter) {
+			continue
+		}
+		matched++
+
+		if query.sortColumn < 0 {
+			// 只筛选：直接收集当前页的行
+			if matched > offset && int64(len(resp.Rows)) < limit {
+				resp.Rows = append(resp.Rows, record)
+			}
+			continue
+		}
+		if len(keys) >= maxTableSortRows {
+			return fmt.Errorf("%w: more than %d rows", errTooManyRows, maxTableSortRows)
+		}
+		keys = append(keys, newSortKey(field(record, query.sortColumn), query.sortType, start))
+	}
+
+	resp.TotalRows = &matched
+	resp.HasMore = offset+limit < matched
+	if query.sortColumn < 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(keys, func(a, b sortKey) int {
+		if a.valid != b.valid {
+			if a.valid {
+				return -1
+			}
+			return 1
+		}
+		result := cmp.Compare(a.number, b.number)
+		if result == 0 {
+			result = strings.Compare(a.text, b.text)
+		}
+		if query.desc {
+			return -result
+		}
+		return result
+	})
+
+	for _, key := range keys[min(offset, int64(len(keys))):min(offset+limit, int64(len(keys)))] {
+		record, _, err := newTableReader(src.reader, key.offset, src.info.Size(), dialect).readRecord()
+		if err != nil {
+			return err
+		}
+		resp.Rows = append(resp.Rows, record)
+	}
+	return nil
+}
+
+// newSortKey 按列类型构建排序键
+func newSortKey(value, columnType string, offset int64) sortKey {
+	key := sortKey{offset: offset}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return key
+	}
+	switch columnType {
+	case columnInteger, columnNumber:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			key.number, key.valid = n, true
+		}
+	case columnDate:
+		if t, ok := parseTableDate(value); ok {
+			key.number, key.valid = float64(t.UnixNano()), true
+		}
+	case columnBoolean:
+		key.valid = true
+		if strings.EqualFold(value, "true") {
+			key.number = 1
+		}
+	default:
+		key.text, key.valid = strings.ToLower(value), true
+	}
+	return key
+}
+
+// field 返回第 i 个字段，不存在时返回空字符串
+func field(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+// tableIndex 表格的行偏移索引，与 lineIndex 类似但按 CSV 记录（可跨多行）计数
+// 按需扫描：只扫描到请求的行为止，之后的请求从上次位置继续
+type tableIndex struct {
+	mu          sync.Mutex
+	size        int64     // 建立索引时的文件大小
+	modTime     time.Time // 建立索引时的修改时间
+	checkpoints []int64   // checkpoints[i] 为第 i*tableIndexStride 个数据行的起始偏移
+	rows        int64     // 已扫描的数据行数
+	pos         int64     // 已扫描到的偏移
+	complete    bool      // 是否已扫描到文件末尾
+}
+
+// rowOffset 返回第 row 个数据行（从 0 开始）的起始偏移，超出总行数时返回文件大小
+func (idx *tableIndex) rowOffset(r io.ReaderAt, size int64, dialect *tableDialect, dataStart, row int64) (int64, error) {
+	if idx.pos < dataStart {
+		idx.pos = dataStart
+	}
+	if !idx.complete && idx.rows <= row {
+		reader := newTableReader(r, idx.pos, size, dialect)
+		for idx.rows <= row {
+			_, start, err := reader.readRecord()
+			if errors.Is(err, io.EOF) {
+				idx.complete = true
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			if idx.rows%tableIndexStride == 0 {
+				idx.checkpoints = append(idx.checkpoints, start)
+			}
+			idx.rows++
+			idx.pos = reader.pos
+		}
+	}
+	if row >= idx.rows {
+		return size, nil
+	}
+
+	// 从最近的检查点开始跳过剩余的行
+	start := idx.checkpoints[row/tableIndexStride]
+	reader := newTableReader(r, start, size, dialect)
+	for skip := row % tableIndexStride; skip > 0; skip-- {
+		if _, _, err := reader.readRecord(); err != nil {
+			return 0, err
+		}
+	}
+	return reader.pos, nil
+}
+
+// tableIndexCache 按文件和格式缓存表格行索引，超出容量时淘汰最久未使用的条目
+type tableIndexCache struct {
+	lru *lruCache[string, *tableIndex]
+}
+
+// newTableIndexCache 创建表格行索引缓存
+func newTableIndexCache(max int) *tableIndexCache {
+	return &tableIndexCache{lru: newLRUCache[string, *tableIndex](int64(max), nil)}
+}
+
+// get 返回 key 对应的行索引，文件大小或修改时间变化时重建
+// 返回的索引已加锁，调用方使用完毕后需调用 index.mu.Unlock()
+func (c *tableIndexCache) get(key string, info os.FileInfo) *tableIndex {
+	idx := c.lru.getOrAdd(key, func() *tableIndex { return &tableIndex{} })
+
+	idx.mu.Lock()
+	if idx.size != info.Size() || !idx.modTime.Equal(info.ModTime()) {
+		idx.size, idx.modTime = info.Size(), info.ModTime()
+		idx.checkpoints, idx.rows, idx.pos, idx.complete = nil, 0, 0, false
+	}
+	return idx
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestTableReader_Quoting(t *testing.T) {
+	data := "name,note\r\n\"Smith, J\",\"said \"\"hi\"\"\"\r\n\n\"multi\nline\",plain\r\nlast,\"unterminated"
+	dialect := &tableDialect{Encoding: tableEncodingUTF8, Delimiter: ',', Quote: '"'}
+	reader := newTableReader(strings.NewReader(data), 0, int64(len(data)), dialect)
+
+	var records [][]string
+	var offsets []int64
+	for {
+		record, start, err := reader.readRecord()
+		if err != nil {
+			break
+		}
+		records = append(records, record)
+		offsets = append(offsets, start)
+	}
+
+	assert.Equal(t, [][]string{
+		{"name", "note"},
+		{"Smith, J", `said "hi"`},
+		{"multi\nline", "plain"},
+		{"last", "unterminated"},
+	}, records)
+	// 偏移指向每行的起始位置（空行被跳过）
+	assert.Equal(t, []int64{0, 11, 38}, offsets[:3])
+}
+
+func TestDetectTableDialect(t *testing.T) {
+	gbk, err := simplifiedchinese.GB18030.NewEncoder().String("城市|人口\n上海|2487\n北京|2184\n")
+	require.NoError(t, err)
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("a\tb\n1\t2\n")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		file      string
+		data      string
+		overrides tableOverrides
+		encoding  string
+		delimiter byte
+		quote     byte
+		header    bool
+		first     []string
+	}{
+		{"comma with header", "a.csv", "id,name\n1,foo\n2,bar\n", tableOverrides{}, tableEncodingUTF8, ',', '"', true, []string{"id", "name"}},
+		{"semicolon", "a.csv", "1;2;3\n4;5;6\n", tableOverrides{}, tableEncodingUTF8, ';', '"', false, []string{"1", "2", "3"}},
+		{"tsv extension", "a.tsv", "a b\tc\nd e\tf\n", tableOverrides{}, tableEncodingUTF8, '\t', '"', true, []string{"a b", "c"}},
+		{"single quotes", "a.csv", "'x,y',z\n'p,q',r\n", tableOverrides{}, tableEncodingUTF8, ',', '\'', true, []string{"x,y", "z"}},
+		{"utf-8 bom", "a.csv", "\xef\xbb\xbfk,v\n1,2\n", tableOverrides{}, tableEncodingUTF8, ',', '"', true, []string{"k", "v"}},
+		{"gb18030", "a.csv", gbk, tableOverrides{}, tableEncodingGB, '|', '"', true, []string{"城市", "人口"}},
+		{"utf-16 bom", "a.tsv", utf16, tableOverrides{}, tableEncodingUTF16L, '\t', '"', true, []string{"a", "b"}},
+		{"overrides", "a.txt", "a:b\nc:d\n", tableOverrides{Delimiter: ":", Quote: "none", Header: "false"}, tableEncodingUTF8, ':', 0, false, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect, records, err := detectTableDialect(tt.file, []byte(tt.data), false, tt.overrides)
+			require.NoError(t, err)
+			assert.Equal(t, tt.encoding, dialect.Encoding)
+			assert.Equal(t, string(tt.delimiter), string(dialect.Delimiter))
+			assert.Equal(t, tt.quote, dialect.Quote)
+			assert.Equal(t, tt.header, dialect.Header)
+			require.NotEmpty(t, records)
+			assert.Equal(t, tt.first, records[0])
+		})
+	}
+
+	_, _, err = detectTableDialect("a.csv", []byte("a,b"), false, tableOverrides{Delimiter: "::"})
+	assert.ErrorIs(t, err, errInvalidDialect)
+	_, _, err = detectTableDialect("a.csv", []byte("a,b"), false, tableOverrides{Encoding: "ebcdic"})
+	assert.ErrorIs(t, err, errInvalidDialect)
+}
+
+func TestTableColumns_InferTypes(t *testing.T) {
+	records := [][]string{
+		{"id", "price", "active", "day", "note"},
+		{"1", "9.5", "true", "2024-01-02", "a"},
+		{"2", "10", "FALSE", "2024-01-03", ""},
+		{"3", "", "true", "", "7"},
+	}
+	columns := tableColumns(records, true)
+	types := make([]string, len(columns))
+	for i, c := range columns {
+		types[i] = c.Type
+	}
+	assert.Equal(t, []string{columnInteger, columnNumber, columnBoolean, columnDate, columnString}, types)
+	assert.Equal(t, "price", columns[1].Name)
+
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+}
+
+func TestHandleTable(t *testing.T) {
+	root := t.TempDir()
+	var buf bytes.Buffer
+	buf.WriteString("id,city,score\n")
+	cities := []string{"Shanghai", "Beijing", "Shenzhen"}
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&buf, "%d,%s,%d\n", i, cities[i%3], (i*7919)%1000)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "data.csv"), buf.Bytes(), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "blob.bin"), []byte{0x00, 0x01, 0x02, 0xff}, 0644))
+
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(url string) (*httptest.ResponseRecorder, tableResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		var resp tableResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	// 第一页：总行数尚未知
+	w, resp := get("/api/table?path=/data.csv&limit=10")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, resp.HasHeader)
+	assert.Equal(t, []tableColumn{{"id", columnInteger}, {"city", columnString}, {"score", columnInteger}}, resp.Columns)
+	assert.Len(t, resp.Rows, 10)
+	assert.Equal(t, []string{"0", "Shanghai", "0"}, resp.Rows[0])
+	assert.True(t, resp.HasMore)
+	assert.Nil(t, resp.TotalRows)
+
+	// 跳转到跨越检查点的位置
+	_, resp = get("/api/table?path=/data.csv&offset=2500&limit=3")
+	assert.Equal(t, "2500", resp.Rows[0][0])
+	assert.Equal(t, "2502", resp.Rows[2][0])
+
+	// 最后一页
+	_, resp = get("/api/table?path=/data.csv&offset=2995&limit=10")
+	assert.Len(t, resp.Rows, 5)
+	assert.False(t, resp.HasMore)
+	require.NotNil(t, resp.TotalRows)
+	assert.Equal(t, int64(3000), *resp.TotalRows)
+
+	// 按数值列降序排序
+	_, resp = get("/api/table?path=/data.csv&sort=score&order=desc&limit=2")
+	assert.Equal(t, "999", resp.Rows[0][2])
+	assert.Equal(t, "999", resp.Rows[1][2])
+	assert.Equal(t, int64(3000), *resp.TotalRows)
+
+	// 筛选（忽略大小写）
+	_, resp = get("/api/table?path=/data.csv&filter=city&q=bei&offset=1&limit=2")
+	assert.Equal(t, [][]string{{"4", "Beijing", "676"}, {"7", "Beijing", "433"}}, resp.Rows)
+	assert.Equal(t, int64(1000), *resp.TotalRows)
+	assert.True(t, resp.HasMore)
+
+	// 筛选 + 排序，列可用序号指定
+	_, resp = get("/api/table?path=/data.csv&filter=1&q=shenzhen&sort=0&order=desc&limit=1")
+	assert.Equal(t, [][]string{{"2999", "Shenzhen", fmt.Sprint(2999 * 7919 % 1000)}}, resp.Rows)
+
+	w, _ = get("/api/table?path=/data.csv&sort=missing")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/table?path=/data.csv&sort=id&order=up")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/table?path=/data.csv&delimiter=ab")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("/api/table?path=/blob.bin")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w, _ = get("/api/table?path=/missing.csv")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
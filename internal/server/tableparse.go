@@ -0,0 +1,520 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	tableSniffSize      = 64 * 1024   // 检测分隔符、表头和列类型时读取的字节数
+	tableSampleRows     = 1000        // 检测时最多解析的行数
+	maxTableRecordSize  = 1024 * 1024 // 单行最大字节数，防止缺失引号时整个文件被当作一行
+	maxTableColumns     = 10000       // 单行最多字段数
+	tableEncodingUTF8   = "utf-8"
+	tableEncodingUTF16L = "utf-16le"
+	tableEncodingUTF16B = "utf-16be"
+	tableEncodingGB     = "gb18030"
+	tableEncodingLatin1 = "latin1"
+)
+
+// 列类型
+const (
+	columnString  = "string"
+	columnInteger = "integer"
+	columnNumber  = "number"
+	columnBoolean = "boolean"
+	columnDate    = "date"
+)
+
+var (
+	// errRecordTooLarge 单行超出 maxTableRecordSize 或 maxTableColumns
+	errRecordTooLarge = errors.New("record too large")
+	// errInvalidDialect 分隔符、引号或编码参数无效
+	errInvalidDialect = errors.New("invalid dialect")
+)
+
+// tableDelimiters 自动检测时尝试的分隔符
+var tableDelimiters = []byte{',', '\t', ';', '|'}
+
+// tableDateLayouts 识别为日期的格式
+var tableDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// tableEncodingAliases 编码参数的别名
+var tableEncodingAliases = map[string]string{
+	"utf-8": tableEncodingUTF8, "utf8": tableEncodingUTF8,
+	"utf-16le": tableEncodingUTF16L, "utf-16be": tableEncodingUTF16B,
+	"gb18030": tableEncodingGB, "gbk": tableEncodingGB, "gb2312": tableEncodingGB,
+	"latin1": tableEncodingLatin1, "iso-8859-1": tableEncodingLatin1,
+}
+
+// tableDialect CSV/TSV 文件的格式
+type tableDialect struct {
+	Encoding  string // 字符编码
+	Delimiter byte   // 字段分隔符
+	Quote     byte   // 引号字符，0 表示不处理引号
+	Header    bool   // 第一行是否为表头
+	bom       int64  // BOM 长度，数据从该偏移开始
+}
+
+// String 返回用于缓存键的格式描述
+func (d *tableDialect) String() string {
+	return fmt.Sprintf("%s|%q|%q|%t", d.Encoding, d.Delimiter, d.Quote, d.Header)
+}
+
+// decoder 返回非 UTF-8 编码的解码器
+func (d *tableDialect) decoder() *encoding.Decoder {
+	return textDecoder(d.Encoding)
+}
+
+// detectTextEncoding 根据文件开头的内容检测文本编码，返回编码和 BOM 长度
+// BOM 优先，其次为合法 UTF-8，否则按 GB18030 处理
+func detectTextEncoding(head []byte) (string, int64) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		return tableEncodingUTF8, 3
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return tableEncodingUTF16L, 2
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return tableEncodingUTF16B, 2
+	case utf8.Valid(trimIncompleteRune(head)):
+		return tableEncodingUTF8, 0
+	default:
+		return tableEncodingGB, 0
+	}
+}
+
+// textDecoder 返回非 UTF-8 编码的解码器，UTF-8 返回 nil
+func textDecoder(enc string) *encoding.Decoder {
+	switch enc {
+	case tableEncodingUTF16L:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+	case tableEncodingUTF16B:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	case tableEncodingGB:
+		return simplifiedchinese.GB18030.NewDecoder()
+	case tableEncodingLatin1:
+		return charmap.ISO8859_1.NewDecoder()
+	}
+	return nil
+}
+
+// tableOverrides 请求中指定的格式参数，空值表示自动检测
+type tableOverrides struct {
+	Encoding  string
+	Delimiter string
+	Quote     string
+	Header    string
+}
+
+// tableReader 按格式逐行解析 CSV/TSV
+// 在原始字节上解析（分隔符、引号、换行均为 ASCII），字段结束时再按编码解码，
+// 因此可以记录每行在文件中的原始偏移，用于分页跳转
+type tableReader struct {
+	r       *bufio.Reader
+	dialect *tableDialect
+	decoder *encoding.Decoder
+	pos     int64   // 已读取到的原始字节偏移
+	char    [4]byte // 当前字符的原始字节
+	charLen int
+	field   []byte // 当前字段的原始字节
+}
+
+// newTableReader 从 offset 开始解析 r 中 size 字节以内的数据
+func newTableReader(r io.ReaderAt, offset, size int64, dialect *tableDialect) *tableReader {
+	return &tableReader{
+		r:       bufio.NewReaderSize(io.NewSectionReader(r, offset, size-offset), 64*1024),
+		dialect: dialect,
+		decoder: dialect.decoder(),
+		pos:     offset,
+	}
+}
+
+// next 读取一个字符，返回其 ASCII 值，非 ASCII 字符返回 -1
+func (t *tableReader) next() (int, error) {
+	switch t.dialect.Encoding {
+	case tableEncodingUTF16L, tableEncodingUTF16B:
+		if _, err := io.ReadFull(t.r, t.char[:2]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		t.charLen = 2
+		t.pos += 2
+		unit := uint16(t.char[0]) | uint16(t.char[1])<<8
+		if t.dialect.Encoding == tableEncodingUTF16B {
+			unit = uint16(t.char[0])<<8 | uint16(t.char[1])
+		}
+		if unit < utf8.RuneSelf {
+			return int(unit), nil
+		}
+		return -1, nil
+	}
+
+	b, err := t.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	t.char[0] = b
+	t.charLen = 1
+	t.pos++
+	if b < utf8.RuneSelf {
+		return int(b), nil
+	}
+	// GB18030 的尾字节可能落在 ASCII 范围内（例如 |），需要整体跳过
+	if t.dialect.Encoding == tableEncodingGB && b >= 0x81 && b <= 0xfe {
+		t.readTrail(1)
+		if t.charLen == 2 && t.char[1] >= 0x30 && t.char[1] <= 0x39 {
+			t.readTrail(2) // 四字节编码
+		}
+	}
+	return -1, nil
+}
+
+// readTrail 读取当前字符的 n 个后续字节，文件结束时忽略
+func (t *tableReader) readTrail(n int) {
+	for range n {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return
+		}
+		t.char[t.charLen] = b
+		t.charLen++
+		t.pos++
+	}
+}
+
+// decodeField 解码当前字段并清空缓冲区
+func (t *tableReader) decodeField() string {
+	raw := t.field
+	t.field = t.field[:0]
+	if t.decoder != nil {
+		if decoded, err := t.decoder.Bytes(raw); err == nil {
+			return string(decoded)
+		}
+	}
+	return strings.ToValidUTF8(string(raw), "\uFFFD")
+}
+
+// trimCR 去掉字段末尾的 \r（\r\n 换行）
+func (t *tableReader) trimCR() {
+	var cr []byte
+	switch t.dialect.Encoding {
+	case tableEncodingUTF16L:
+		cr = []byte{'\r', 0}
+	case tableEncodingUTF16B:
+		cr = []byte{0, '\r'}
+	default:
+		cr = []byte{'\r'}
+	}
+	t.field = bytes.TrimSuffix(t.field, cr)
+}
+
+// readRecord 读取下一行，跳过空行，返回字段和该行的起始偏移
+// 文件结束时返回 io.EOF
+func (t *tableReader) readRecord() ([]string, int64, error) {
+	for {
+		start := t.pos
+		fields, err := t.parseRecord(start)
+		if err != nil {
+			return nil, start, err
+		}
+		if fields != nil {
+			return fields, start, nil
+		}
+	}
+}
+
+// parseRecord 解析一行，空行返回 nil
+// 引号规则：字段以引号开头时进入引号模式，两个连续引号表示一个引号字符；
+// 结束引号之后的内容按普通字符处理（宽松模式）
+func (t *tableReader) parseRecord(start int64) ([]string, error) {
+	var fields []string
+	quote := int(t.dialect.Quote)
+	delimiter := int(t.dialect.Delimiter)
+	quoted, fieldStart, sawQuote, sawAny := false, true, false, false
+	t.field = t.field[:0]
+
+	for {
+		c, err := t.next()
+		if errors.Is(err, io.EOF) {
+			if !sawAny {
+				return nil, io.EOF
+			}
+			t.trimCR()
+			return append(fields, t.decodeField()), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sawAny = true
+		if t.pos-start > maxTableRecordSize || len(fields) >= maxTableColumns {
+			return nil, errRecordTooLarge
+		}
+
+		if quoted {
+			if c != quote {
+				t.field = append(t.field, t.char[:t.charLen]...)
+				continue
+			}
+			c, err = t.next()
+			if errors.Is(err, io.EOF) {
+				return append(fields, t.decodeField()), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if c == quote {
+				t.field = append(t.field, t.char[:t.charLen]...)
+				continue
+			}
+			quoted = false
+		}
+
+		switch {
+		case c == delimiter:
+			fields = append(fields, t.decodeField())
+			fieldStart = true
+		case c == '\n':
+			t.trimCR()
+			if len(fields) == 0 && len(t.field) == 0 && !sawQuote {
+				return nil, nil
+			}
+			return append(fields, t.decodeField()), nil
+		case quote != 0 && c == quote && fieldStart:
+			quoted, sawQuote, fieldStart = true, true, false
+		default:
+			t.field = append(t.field, t.char[:t.charLen]...)
+			fieldStart = false
+		}
+	}
+}
+
+// detectTableDialect 根据文件开头的内容检测表格格式，返回格式和解析出的样本行（含表头）
+// truncated 表示 head 不是完整文件，最后一行可能不完整，不参与检测
+func detectTableDialect(name string, head []byte, truncated bool, o tableOverrides) (*tableDialect, [][]string, error) {
+	dialect := &tableDialect{Quote: '"'}
+
+	dialect.Encoding, dialect.bom = detectTextEncoding(head)
+	if o.Encoding != "" {
+		enc, ok := tableEncodingAliases[strings.ToLower(o.Encoding)]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unsupported encoding %q", errInvalidDialect, o.Encoding)
+		}
+		dialect.Encoding = enc
+	}
+
+	if o.Quote != "" {
+		switch {
+		case o.Quote == "none":
+			dialect.Quote = 0
+		case len(o.Quote) == 1 && o.Quote[0] < utf8.RuneSelf:
+			dialect.Quote = o.Quote[0]
+		default:
+			return nil, nil, fmt.Errorf("%w: quote must be a single ASCII character or none", errInvalidDialect)
+		}
+	}
+
+	sample := func(d *tableDialect) [][]string {
+		reader := newTableReader(bytes.NewReader(head), d.bom, int64(len(head)), d)
+		var records [][]string
+		for len(records) < tableSampleRows {
+			record, _, err := reader.readRecord()
+			if err != nil {
+				break
+			}
+			records = append(records, record)
+		}
+		// 截断的最后一行不完整
+		if truncated && len(records) > 1 {
+			records = records[:len(records)-1]
+		}
+		return records
+	}
+
+	switch o.Delimiter {
+	case "":
+		dialect.Delimiter = detectDelimiter(name, dialect, sample)
+	case "tab", `\t`:
+		dialect.Delimiter = '\t'
+	default:
+		if len(o.Delimiter) != 1 || o.Delimiter[0] >= utf8.RuneSelf || o.Delimiter[0] == '\n' || o.Delimiter[0] == dialect.Quote {
+			return nil, nil, fmt.Errorf("%w: delimiter must be a single ASCII character", errInvalidDialect)
+		}
+		dialect.Delimiter = o.Delimiter[0]
+	}
+	if o.Quote == "" {
+		dialect.Quote = detectQuote(dialect, sample)
+	}
+
+	records := sample(dialect)
+	switch o.Header {
+	case "":
+		dialect.Header = detectHeader(records)
+	case "true", "1":
+		dialect.Header = true
+	case "false", "0":
+		dialect.Header = false
+	default:
+		return nil, nil, fmt.Errorf("%w: header must be true or false", errInvalidDialect)
+	}
+	return dialect, records, nil
+}
+
+// detectDelimiter 选择使各行字段数最一致的分隔符
+// 得分为字段数众数（>1）所占的行比例，相同时取字段数较多者；.tsv 文件优先使用制表符
+func detectDelimiter(name string, dialect *tableDialect, sample func(*tableDialect) [][]string) byte {
+	ext := strings.ToLower(filepath.Ext(name))
+	best, bestScore, bestFields := byte(','), 0.0, 0
+	if ext == ".tsv" || ext == ".tab" {
+		best = '\t'
+	}
+
+	for _, delimiter := range tableDelimiters {
+		candidate := *dialect
+		candidate.Delimiter = delimiter
+		records := sample(&candidate)
+		if len(records) == 0 {
+			continue
+		}
+		counts := make(map[int]int)
+		for _, record := range records {
+			counts[len(record)]++
+		}
+		fields, freq := 0, 0
+		for n, f := range counts {
+			if f > freq || (f == freq && n > fields) {
+				fields, freq = n, f
+			}
+		}
+		if fields < 2 {
+			continue
+		}
+		if delimiter == '\t' && (ext == ".tsv" || ext == ".tab") {
+			return delimiter
+		}
+		score := float64(freq) / float64(len(records))
+		if score > bestScore || (score == bestScore && fields > bestFields) {
+			best, bestScore, bestFields = delimiter, score, fields
+		}
+	}
+	return best
+}
+
+// detectQuote 统计以 " 或 ' 开头或结尾的字段数，选择出现较多的引号字符，默认为 "
+func detectQuote(dialect *tableDialect, sample func(*tableDialect) [][]string) byte {
+	candidate := *dialect
+	candidate.Quote = 0
+	records := sample(&candidate)
+
+	best, bestCount := byte('"'), 0
+	for _, quote := range []byte{'"', '\''} {
+		count := 0
+		for _, record := range records {
+			for _, field := range record {
+				field = strings.TrimSpace(field)
+				if strings.HasPrefix(field, string(quote)) {
+					count++
+				}
+				if strings.HasSuffix(field, string(quote)) {
+					count++
+				}
+			}
+		}
+		if count > bestCount {
+			best, bestCount = quote, count
+		}
+	}
+	return best
+}
+
+// detectHeader 判断第一行是否为表头
+// 第一行的值非空、互不相同且都不是数字/日期/布尔值时视为表头；
+// 只有一行时同样按此规则判断
+func detectHeader(records [][]string) bool {
+	if len(records) == 0 {
+		return false
+	}
+	seen := make(map[string]bool, len(records[0]))
+	for _, value := range records[0] {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] || inferValueType(value) != columnString {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
+
+// inferValueType 推断单个值的类型，空值返回空字符串
+func inferValueType(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return columnInteger
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return columnNumber
+	}
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return columnBoolean
+	}
+	if _, ok := parseTableDate(value); ok {
+		return columnDate
+	}
+	return columnString
+}
+
+// mergeColumnType 合并同一列两个值的类型：整数与小数合并为 number，其他不一致时为 string
+func mergeColumnType(current, next string) string {
+	switch {
+	case next == "" || current == next:
+		return current
+	case current == "":
+		return next
+	case (current == columnInteger && next == columnNumber) || (current == columnNumber && next == columnInteger):
+		return columnNumber
+	}
+	return columnString
+}
+
+// parseTableDate 按 tableDateLayouts 解析日期
+func parseTableDate(value string) (time.Time, bool) {
+	for _, layout := range tableDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// columnName 返回第 i 列（从 0 开始）的电子表格风格名称：A、B、…、Z、AA、AB…
+func columnName(i int) string {
+	var name []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+	return string(name)
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTailLines  = 200   // 默认回显的末尾行数
+	maxTailLines      = 10000 // 最多回显的末尾行数
+	tailChunkSize     = 64 * 1024
+	tailHeartbeatTick = 15 * time.Second // 心跳间隔，防止代理断开空闲连接
+)
+
+// tailPollInterval 检查文件变化的间隔（测试中可调小）
+var tailPollInterval = 500 * time.Millisecond
+
+// tailEvent 追踪事件中的数据
+type tailEvent struct {
+	Content    string `json:"content"`    // 新增内容
+	Offset     int64  `json:"offset"`     // 内容起始偏移量
+	NextOffset int64  `json:"nextOffset"` // 内容结束偏移量
+	Size       int64  `json:"size"`       // 当前文件大小
+}
+
+// handleTail 持续追踪文件新增内容（Server-Sent Events）
+// GET /api/tail?path=/app.log&lines=200
+// 先发送末尾 lines 行，之后文件增长时推送新增内容；
+// 文件被截断时发送 truncated 事件并从头读取，被轮转（inode 变化）时发送 rotated 事件并切换到新文件
+func (s *Server) handleTail(c *gin.Context) {
+	reqPath := c.Query("path")
+	absPath, _, err := s.resolvePath(reqPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+
+	lines := int64(defaultTailLines)
+	if v := c.Query("lines"); v != "" {
+		lines, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || lines < 0 {
+			abortWithError(c, http.StatusBadRequest, "INVALID_RANGE", "invalid lines")
+			return
+		}
+		lines = min(lines, maxTailLines)
+	}
+
+	file, info, err := openRegularFile(absPath)
+	if errors.Is(err, errNotAFile) {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", err.Error())
+		return
+	}
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "STAT_FAILED", err.Error())
+		return
+	}
+	defer func() { file.Close() }()
+
+	// 单个事件的最大字节数
+	eventMax := s.cfg.PreviewMax
+	if eventMax <= 0 {
+		eventMax = defaultPreviewMax
+	}
+
+	start, err := tailStart(file, info.Size(), lines, eventMax)
+	if err != nil {
+		abortWithError(c, http.StatusInternalServerError, "READ_FAILED", "failed to read file")
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止 Nginx 缓冲
+
+	ctx := c.Request.Context()
+	offset := start
+	lastWrite := time.Now()
+	send := func(event string, data any) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+		lastWrite = time.Now()
+	}
+
+	// 推送 offset 之后的全部内容，每个事件不超过 eventMax
+	flush := func(size int64) error {
+		for offset < size {
+			chunk, next, err := readTailChunk(file, offset, size, eventMax)
+			if err != nil {
+				return err
+			}
+			if next == offset {
+				return nil // 仅剩不完整的字符，等待后续写入
+			}
+			send("data", tailEvent{Content: string(chunk), Offset: offset, NextOffset: next, Size: size})
+			offset = next
+		}
+		return nil
+	}
+
+	if err := flush(info.Size()); err != nil {
+		send("error", errorResponse{Error: "failed to read file", Code: "READ_FAILED"})
+		return
+	}
+	if offset == start {
+		// 空文件或没有可回显的内容，也发送一次初始事件
+		send("data", tailEvent{Offset: offset, NextOffset: offset, Size: info.Size()})
+	}
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := file.Stat()
+		if err != nil {
+			send("error", errorResponse{Error: "failed to stat file", Code: "STAT_FAILED"})
+			return
+		}
+
+		// 截断：文件变小，从头开始读取
+		if current.Size() < offset {
+			offset = 0
+			send("truncated", tailEvent{Size: current.Size()})
+		}
+		if err := flush(current.Size()); err != nil {
+			send("error", errorResponse{Error: "failed to read file", Code: "READ_FAILED"})
+			return
+		}
+
+		// 轮转：路径指向了新的文件，读完旧文件剩余内容后切换
+		if next, nextInfo := s.reopenRotated(reqPath, current); next != nil {
+			file.Close()
+			file, offset = next, 0
+			send("rotated", tailEvent{Size: nextInfo.Size()})
+			if err := flush(nextInfo.Size()); err != nil {
+				send("error", errorResponse{Error: "failed to read file", Code: "READ_FAILED"})
+				return
+			}
+		}
+
+		// 长时间没有输出时发送心跳注释
+		if time.Since(lastWrite) >= tailHeartbeatTick {
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+			lastWrite = time.Now()
+		}
+	}
+}
+
+// reopenRotated 路径被轮转到新的普通文件时打开新文件，没有轮转或新文件不可用时返回 nil
+// 与首次打开一样经过 resolvePath 检查，路径被替换为符号链接时不切换，避免读取根目录外的文件
+func (s *Server) reopenRotated(reqPath string, current os.FileInfo) (*os.File, os.FileInfo) {
+	absPath, _, err := s.resolvePath(reqPath)
+	if err != nil {
+		return nil, nil
+	}
+	rotated, err := os.Lstat(absPath)
+	if err != nil || !rotated.Mode().IsRegular() || os.SameFile(rotated, current) {
+		return nil, nil
+	}
+	file, info, err := openRegularFile(absPath)
+	if err != nil {
+		return nil, nil
+	}
+	// 检查和打开之间路径可能再次被替换，确认打开的就是检查过的文件
+	if !os.SameFile(rotated, info) {
+		file.Close()
+		return nil, nil
+	}
+	return file, info
+}
+
+// openRegularFile 打开普通文件，目录、FIFO、设备文件和符号链接返回 errNotAFile
+// 打开前先用 Lstat 检查：以只读方式打开 FIFO 会一直阻塞到有写入方；
+// 打开后再确认与检查的是同一个文件，防止检查后路径被替换
+func openRegularFile(absPath string) (*os.File, os.FileInfo, error) {
+	checked, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !checked.Mode().IsRegular() {
+		return nil, nil, errNotAFile
+	}
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if !info.Mode().IsRegular() || !os.SameFile(checked, info) {
+		file.Close()
+		return nil, nil, errNotAFile
+	}
+	return file, info, nil
+}
+
+// tailStart 返回末尾 lines 行的起始偏移量，回显内容最多 maxBytes 字节
+func tailStart(r io.ReaderAt, size, lines, maxBytes int64) (int64, error) {
+	if lines == 0 || size == 0 {
+		return size, nil
+	}
+
+	floor := max(size-maxBytes, 0)
+	// 末尾的换行符不计入（它结束的是最后一行）
+	end := size - 1
+	buf := make([]byte, tailChunkSize)
+	found := int64(0)
+	for end > floor {
+		chunkStart := max(end-tailChunkSize, floor)
+		n, err := r.ReadAt(buf[:end-chunkStart], chunkStart)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		chunk := buf[:n]
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' {
+				continue
+			}
+			found++
+			if found == lines {
+				return chunkStart + int64(i) + 1, nil
+			}
+		}
+		end = chunkStart
+	}
+	if floor == 0 {
+		return 0, nil
+	}
+
+	// 超出字节上限：从上限内的第一个完整行开始
+	n, err := r.ReadAt(buf, floor)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 && floor+int64(i)+1 < size {
+		return floor + int64(i) + 1, nil
+	}
+	return alignRuneStart(r, floor), nil
+}
+
+// readTailChunk 读取 [offset, size) 中最多 maxBytes 字节，末尾不完整的字符留到下次读取
+func readTailChunk(r io.ReaderAt, offset, size, maxBytes int64) ([]byte, int64, error) {
+	buf := make([]byte, min(size-offset, maxBytes))
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, offset, err
+	}
+	chunk := trimIncompleteRune(buf[:n])
+	return chunk, offset + int64(len(chunk)), nil
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRegularFile_RejectsFIFO(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "pipe"), 0644))
+
+	_, _, err := openRegularFile(filepath.Join(root, "pipe"))
+	assert.ErrorIs(t, err, errNotAFile)
+
+	// 打开 FIFO 会阻塞到有写入方，各接口应直接拒绝而不是挂起
+	server, err := New(Config{Root: root})
+	require.NoError(t, err)
+	router := server.Handler()
+	for _, target := range []string{
+		"/api/preview?path=/pipe",
+		"/api/grep?path=/pipe&pattern=x",
+		"/api/tail?path=/pipe",
+		"/api/meta?path=/pipe",
+		"/api/sqlite/tables?path=/pipe",
+		"/api/thumb?path=/pipe",
+	} {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			done <- w
+		}()
+		select {
+		case w := <-done:
+			assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s blocked on a FIFO", target)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailStart(t *testing.T) {
+	data := "a\nbb\nccc\n"
+	r := strings.NewReader(data)
+
+	tests := []struct {
+		name     string
+		lines    int64
+		maxBytes int64
+		expected int64
+	}{
+		{"zero lines", 0, 1024, int64(len(data))},
+		{"last line", 1, 1024, 5},
+		{"last two lines", 2, 1024, 2},
+		{"more lines than file", 10, 1024, 0},
+		{"byte limit", 10, 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, err := tailStart(r, int64(len(data)), tt.lines, tt.maxBytes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, start)
+		})
+	}
+
+	// 最后一行没有换行符
+	start, err := tailStart(strings.NewReader("a\nbb"), 4, 1, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), start)
+}
+
+// sseEvent 测试中解析出的 SSE 事件
+type sseEvent struct {
+	name string
+	data tailEvent
+}
+
+// readEvents 从 SSE 流中持续读取事件并发送到通道
+func readEvents(scanner *bufio.Scanner, events chan<- sseEvent) {
+	defer close(events)
+	var current sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			current.name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &current.data)
+		case line == "" && current.name != "":
+			events <- current
+			current = sseEvent{}
+		}
+	}
+}
+
+// tailEvents 请求 /api/tail 并返回依次读取事件的函数，测试结束时断开连接
+func tailEvents(t *testing.T, url string) func() sseEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := make(chan sseEvent, 16)
+	go readEvents(bufio.NewScanner(resp.Body), events)
+	return func() sseEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return sseEvent{}
+		}
+	}
+}
+
+func TestHandleTail_FollowsAppendTruncateAndRotate(t *testing.T) {
+	tailPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("one\ntwo\nthree\n"), 0644))
+
+	server, err := New(Config{Root: dir, PreviewMax: 1024})
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	next := tailEvents(t, ts.URL+"/api/tail?path=/app.log&lines=2")
+
+	// 初始回显末尾两行
+	ev := next()
+	assert.Equal(t, "data", ev.name)
+	assert.Equal(t, "two\nthree\n", ev.data.Content)
+
+	// 追加
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("four\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	ev = next()
+	assert.Equal(t, "four\n", ev.data.Content)
+
+	// 截断
+	require.NoError(t, os.Truncate(logPath, 0))
+	assert.Equal(t, "truncated", next().name)
+
+	// 轮转
+	require.NoError(t, os.Rename(logPath, logPath+".1"))
+	require.NoError(t, os.WriteFile(logPath, []byte("fresh\n"), 0644))
+	assert.Equal(t, "rotated", next().name)
+	ev = next()
+	assert.Equal(t, "fresh\n", ev.data.Content)
+}
+
+func TestHandleTail_RotateIntoSymlink(t *testing.T) {
+	tailPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret\n"), 0644))
+	logPath := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("one\n"), 0644))
+
+	server, err := New(Config{Root: dir, PreviewMax: 1024})
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	next := tailEvents(t, ts.URL+"/api/tail?path=/app.log")
+	assert.Equal(t, "one\n", next().data.Content)
+
+	// 轮转为指向根目录外文件的符号链接：不切换
+	require.NoError(t, os.Rename(logPath, logPath+".1"))
+	require.NoError(t, os.Symlink(outside, logPath))
+	time.Sleep(20 * tailPollInterval)
+
+	// 符号链接被替换为普通文件后才切换，之前没有发送任何事件
+	require.NoError(t, os.Remove(logPath))
+	require.NoError(t, os.WriteFile(logPath, []byte("fresh\n"), 0644))
+	assert.Equal(t, "rotated", next().name)
+	ev := next()
+	assert.Equal(t, "fresh\n", ev.data.Content)
+}
+
+func TestHandleTail_Errors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	server, err := New(Config{Root: dir, PreviewMax: 1024})
+	require.NoError(t, err)
+	router := server.Handler()
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"missing file", "/api/tail?path=/missing.log", http.StatusNotFound},
+		{"directory", "/api/tail?path=/sub", http.StatusBadRequest},
+		{"invalid lines", "/api/tail?path=/sub&lines=-1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
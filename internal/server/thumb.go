@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码器
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+const (
+	defaultThumbSize = 256              // 默认缩略图边长
+	minThumbSize     = 16               // 最小缩略图边长
+	maxThumbSize     = 1024             // 最大缩略图边长
+	thumbMaxPixels   = 80 * 1000 * 1000 // 可解码的最大像素数，防止超大图片耗尽内存
+	thumbQuality     = 80               // JPEG 编码质量
+)
+
+// errUnsupportedImage 无法解码的图片格式或尺寸超出限制
+var errUnsupportedImage = errors.New("unsupported image")
+
+// thumbFormat 缩略图输出格式
+type thumbFormat struct {
+	ext    string // 缓存文件扩展名，同时用于推断响应的 Content-Type
+	encode func(io.Writer, image.Image) error
+}
+
+// thumbFormats format 参数到输出格式的映射，默认为 jpeg
+// WebP 为无损编码（VP8L），适合图标和截图等颜色较少的图片，照片的文件通常比 JPEG 大
+var thumbFormats = map[string]thumbFormat{
+	"jpeg": {ext: ".jpg", encode: func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: thumbQuality})
+	}},
+	"webp": {ext: ".webp", encode: func(w io.Writer, img image.Image) error {
+		return nativewebp.Encode(w, img, nil)
+	}},
+}
+
+// handleThumb 返回图片缩略图
+// GET /api/thumb?path=/photos/a.jpg&size=256&format=webp
+// 支持 JPEG/PNG/GIF/WebP，按 EXIF 方向旋转后等比缩放到 size×size 以内并编码为 JPEG（默认）或 WebP，
+// 结果按 路径+修改时间+文件大小+尺寸+格式 缓存在磁盘上
+func (s *Server) handleThumb(c *gin.Context) {
+	absPath, relPath, err := s.resolvePath(c.Query("path"))
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "INVALID_PATH", err.Error())
+		return
+	}
+
+	size := defaultThumbSize
+	if v := c.Query("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < minThumbSize || size > maxThumbSize {
+			abortWithError(c, http.StatusBadRequest, "INVALID_SIZE",
+				fmt.Sprintf("size must be between %d and %d", minThumbSize, maxThumbSize))
+			return
+		}
+	}
+
+	formatName := c.DefaultQuery("format", "jpeg")
+	format, ok := thumbFormats[formatName]
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, "INVALID_FORMAT", "format must be jpeg or webp")
+		return
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		abortWithError(c, statusFromErr(err), "STAT_FAILED", err.Error())
+		return
+	}
+	if !info.Mode().IsRegular() {
+		abortWithError(c, http.StatusBadRequest, "NOT_A_FILE", errNotAFile.Error())
+		return
+	}
+
+	key := thumbKey(relPath, info, size) + format.ext
+	data, ok := s.thumbs.get(key)
+	if !ok {
+		data, err = s.generateThumb(absPath, size, format)
+		if errors.Is(err, errUnsupportedImage) {
+			abortWithError(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_IMAGE", err.Error())
+			return
+		}
+		if err != nil {
+			abortWithError(c, http.StatusInternalServerError, "THUMB_FAILED", err.Error())
+			return
+		}
+		_ = s.thumbs.put(key, data) // 缓存失败不影响本次响应
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	httpServeContent(c, info.Name()+format.ext, info.ModTime(), bytes.NewReader(data))
+}
+
+// thumbKey 计算缩略图缓存键：路径、修改时间、文件大小或尺寸变化时缓存自然失效
+// 调用方追加输出格式的扩展名作为缓存文件名
+func thumbKey(relPath string, info os.FileInfo, size int) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d|%d", relPath, info.ModTime().UnixNano(), info.Size(), size))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateThumb 解码图片并按 out 的格式编码缩略图
+// 通过 thumbSlots 限制同时解码的数量，避免大量请求同时占用内存
+func (s *Server) generateThumb(absPath string, size int, out thumbFormat) ([]byte, error) {
+	s.thumbSlots <- struct{}{}
+	defer func() { <-s.thumbSlots }()
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 先读取尺寸，拒绝像素数过大的图片
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedImage, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > thumbMaxPixels {
+		return nil, fmt.Errorf("%w: image too large (%dx%d)", errUnsupportedImage, cfg.Width, cfg.Height)
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		orientation = exifOrientation(file)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedImage, err)
+	}
+
+	thumb := orientImage(resizeImage(src, size), orientation)
+	var buf bytes.Buffer
+	if err := out.encode(&buf, thumb); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeImage 等比缩放到 size×size 以内（不放大），透明区域以白色填充
+func resizeImage(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(h*size/w, 1)
+		} else {
+			w, h = max(w*size/h, 1), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+// orientImage 根据 EXIF 方向值（1-8）旋转/翻转图片
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w // 5-8 需要交换宽高
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+// writePNG 写入 w×h 的纯色 PNG 图片
+func writePNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestHandleThumb(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	writePNG(t, filepath.Join(root, "wide.png"), 400, 200)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "note.txt"), []byte("not an image"), 0644))
+
+	server, err := New(Config{Root: root, CacheDir: cacheDir, ThumbCacheMax: 1024 * 1024})
+	require.NoError(t, err)
+	router := server.Handler()
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get("/api/thumb?path=/wide.png&size=100")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.Width)
+	assert.Equal(t, 50, cfg.Height)
+
+	// 结果写入磁盘缓存
+	cached, err := filepath.Glob(filepath.Join(cacheDir, "thumbs", "*.jpg"))
+	require.NoError(t, err)
+	assert.Len(t, cached, 1)
+
+	// 再次请求命中缓存
+	w = get("/api/thumb?path=/wide.png&size=100")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// WebP 输出单独缓存
+	w = get("/api/thumb?path=/wide.png&size=100&format=webp")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+	img, err := webp.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+	r, _, _, _ := img.At(10, 10).RGBA()
+	assert.Equal(t, uint32(200), r>>8)
+	cached, err = filepath.Glob(filepath.Join(cacheDir, "thumbs", "*.webp"))
+	require.NoError(t, err)
+	assert.Len(t, cached, 1)
+	assert.Equal(t, http.StatusBadRequest, get("/api/thumb?path=/wide.png&format=gif").Code)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, get("/api/thumb?path=/note.txt").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/thumb?path=/wide.png&size=5000").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/thumb?path=/missing.png").Code)
+}
+
+func TestResizeImage_NoUpscale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 30, 60))
+
+	assert.Equal(t, image.Rect(0, 0, 30, 60), resizeImage(src, 256).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 10, 20), resizeImage(src, 20).Bounds())
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2×1 图片：左红右蓝
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		bounds      image.Rectangle
+		at          image.Point // red 像素所在位置
+	}{
+		{1, image.Rect(0, 0, 2, 1), image.Pt(0, 0)},
+		{2, image.Rect(0, 0, 2, 1), image.Pt(1, 0)},
+		{3, image.Rect(0, 0, 2, 1), image.Pt(1, 0)},
+		{6, image.Rect(0, 0, 1, 2), image.Pt(0, 0)},
+		{8, image.Rect(0, 0, 1, 2), image.Pt(0, 1)},
+	}
+
+	for _, tt := range tests {
+		dst := orientImage(src, tt.orientation)
+		assert.Equal(t, tt.bounds, dst.Bounds(), "orientation %d", tt.orientation)
+		assert.Equal(t, red, dst.RGBAAt(tt.at.X, tt.at.Y), "orientation %d", tt.orientation)
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	// 最小 JPEG 头 + APP1 Exif 段（大端，IFD0 仅含 Orientation=6）
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // TIFF 头
+		0, 1, // 1 个条目
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6, 0, 0, // Orientation SHORT 6
+		0, 0, 0, 0, // 下一个 IFD
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xff, 0xd8, 0xff, 0xe1, 0, byte(len(segment) + 2)}
+	data = append(data, segment...)
+	data = append(data, 0xff, 0xd9)
+
+	assert.Equal(t, 6, exifOrientation(bytes.NewReader(data)))
+	assert.Equal(t, 1, exifOrientation(bytes.NewReader([]byte("not a jpeg"))))
+}
+
+func TestThumbCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newThumbCache(dir, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.put("a.jpg", []byte("aaaa")))
+	require.NoError(t, cache.put("b.webp", []byte("bbbb")))
+	_, ok := cache.get("a.jpg") // a 变为最近使用
+	require.True(t, ok)
+	require.NoError(t, cache.put("c.jpg", []byte("cccc")))
+
+	_, ok = cache.get("b.webp")
+	assert.False(t, ok)
+	_, err = os.Stat(filepath.Join(dir, "b.webp"))
+	assert.True(t, os.IsNotExist(err))
+	data, ok := cache.get("a.jpg")
+	assert.True(t, ok)
+	assert.Equal(t, "aaaa", string(data))
+
+	// 重新加载时恢复已有的缓存
+	reloaded, err := newThumbCache(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), reloaded.lru.total)
+}
+
+func TestThumbCache_EvictionKeepsRewrittenFile(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newThumbCache(dir, 10)
+	require.NoError(t, err)
+
+	// 淘汰回调在 LRU 解锁后执行：回调执行前同一个键已被并发的 put 重新写入时，不能删除新文件
+	require.NoError(t, cache.put("a.jpg", []byte("aaaa")))
+	evicted := func() []*lruEntry[string, int64] {
+		cache.lru.mu.Lock()
+		defer cache.lru.mu.Unlock()
+		cache.lru.max = 0
+		defer func() { cache.lru.max = 10 }()
+		return cache.lru.evict()
+	}()
+	require.NoError(t, cache.put("a.jpg", []byte("AAAA")))
+	cache.lru.notify(evicted)
+
+	data, ok := cache.get("a.jpg")
+	require.True(t, ok)
+	assert.Equal(t, "AAAA", string(data))
+
+	// 键确实已被淘汰时删除文件
+	cache.lru.remove("a.jpg")
+	cache.lru.notify(evicted)
+	assert.NoFileExists(t, filepath.Join(dir, "a.jpg"))
+}
+
+func TestThumbCache_RemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	// 上次写入中途退出残留的临时文件，甚至带有缓存扩展名
+	for _, name := range []string{"tmp-123", "tmp-456.jpg", "tmp-789.webp"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("partial"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.jpg"), []byte("aaaa"), 0644))
+
+	cache, err := newThumbCache(dir, 100)
+	require.NoError(t, err)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a.jpg", entries[0].Name())
+	assert.Equal(t, int64(4), cache.lru.total)
+	assert.Len(t, cache.lru.entries, 1)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// thumbCacheTmpPre 写入中的临时文件前缀
+const thumbCacheTmpPre = "tmp-"
+
+// thumbCache 缩略图磁盘缓存
+// 总大小超过上限时按最近访问时间淘汰（LRU），访问时间记录在文件的修改时间上，重启后依然有效
+type thumbCache struct {
+	dir string                   // 缓存目录，为空时不缓存
+	max int64                    // 缓存总大小上限
+	lru *lruCache[string, int64] // 缓存键（即文件名）-> 文件大小，按文件大小计算权重
+	// 串行化缓存文件的重命名和淘汰删除：淘汰回调在 LRU 解锁后执行，
+	// 删除前需确认条目没有被并发的 put 重新写入
+	mu sync.Mutex
+}
+
+// newThumbCache 创建缩略图缓存并加载目录中已有的缓存文件
+// 上次运行中途退出时残留的临时文件不会再被使用，直接删除
+// dir 为空时返回不做缓存的实例
+func newThumbCache(dir string, max int64) (*thumbCache, error) {
+	c := &thumbCache{
+		dir: dir,
+		max: max,
+		lru: newLRUCache[string, int64](max, func(size int64) int64 { return size }),
+	}
+	c.lru.onEvict = func(key string, _ int64) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.lru.contains(key) {
+			os.Remove(c.path(key))
+		}
+	}
+	if dir == "" {
+		return c, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type existing struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]existing, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), thumbCacheTmpPre) {
+			os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		if !entry.Type().IsRegular() || !isThumbCacheFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, existing{
+			key:     entry.Name(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	// 按访问时间从旧到新加入缓存，超出上限时淘汰最久未访问的文件
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		c.lru.add(f.key, f.size)
+	}
+	return c, nil
+}
+
+// isThumbCacheFile 判断文件名是否为某种输出格式的缓存文件
+func isThumbCacheFile(name string) bool {
+	for _, f := range thumbFormats {
+		if strings.HasSuffix(name, f.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// path 返回缓存键对应的文件路径
+func (c *thumbCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// get 读取缓存的缩略图，命中时更新访问时间
+func (c *thumbCache) get(key string) ([]byte, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	if _, ok := c.lru.get(key); !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		c.lru.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	return data, true
+}
+
+// put 写入缩略图并在超出上限时淘汰最久未访问的条目
+// 先写临时文件再重命名，避免并发读取到不完整的文件
+func (c *thumbCache) put(key string, data []byte) error {
+	if c.dir == "" || int64(len(data)) > c.max {
+		return nil
+	}
+	tmp, err := os.CreateTemp(c.dir, thumbCacheTmpPre+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// 重命名和加入 LRU 在同一把锁内完成，淘汰回调看到的条目状态与磁盘一致
+	c.mu.Lock()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		c.mu.Unlock()
+		os.Remove(tmp.Name())
+		return err
+	}
+	evicted := c.lru.push(key, int64(len(data)))
+	c.mu.Unlock()
+
+	c.lru.notify(evicted)
+	return nil
+}
@@ -22,6 +22,29 @@ export interface SearchState {
   isSearching: boolean;
   loading: boolean;
   recursive: boolean;
+  truncated: boolean; // 还有更多结果未加载
+  cursor: string;     // 获取下一页的游标
+}
+
+/** /api/search 响应 */
+interface SearchResponse {
+  results: FileEntry[];
+  truncated: boolean;
+  scanned: number;
+  cursor?: string;
+}
+
+/** 补全扩展名，并按目录优先、名称排序 */
+function sortSearchResults(items: FileEntry[]): FileEntry[] {
+  return items
+    .map((entry) => ({
+      ...entry,
+      extension: entry.extension || fileExtensionFromName(entry.name)
+    }))
+    .sort((a, b) => {
+      if (a.type !== b.type) return a.type === 'dir' ? -1 : 1;
+      return a.name.toLowerCase().localeCompare(b.name.toLowerCase());
+    });
 }
 
 export function useFileBrowser() {
@@ -37,7 +60,9 @@ export function useFileBrowser() {
     results: [],
     isSearching: false,
     loading: false,
-    recursive: false
+    recursive: false,
+    truncated: false,
+    cursor: ''
   });
 
   let searchTimeout: ReturnType<typeof setTimeout> | null = null;
@@ -79,34 +104,63 @@ export function useFileBrowser() {
     search.results = [];
     search.isSearching = false;
     search.loading = false;
+    search.truncated = false;
+    search.cursor = '';
     if (searchTimeout) {
       clearTimeout(searchTimeout);
       searchTimeout = null;
     }
   }
 
+  /** 请求一页搜索结果，cursor 为空时从头开始 */
+  async function fetchSearchPage(cursor: string): Promise<SearchResponse | null> {
+    const recursiveParam = search.recursive ? 'true' : 'false';
+    let url = '/api/search?path=' + encodeURIComponent(state.currentPath) + '&q=' + encodeURIComponent(search.query) + '&recursive=' + recursiveParam;
+    if (cursor) {
+      url += '&cursor=' + encodeURIComponent(cursor);
+    }
+    const response = await fetch(apiUrl(url));
+    if (!response.ok) {
+      return null;
+    }
+    return response.json();
+  }
+
   /** 执行搜索 */
   async function performSearch() {
     if (!search.query.trim()) {
       search.isSearching = false;
       search.results = [];
+      search.truncated = false;
+      search.cursor = '';
       return;
     }
 
     search.loading = true;
     search.isSearching = true;
 
-    const recursiveParam = search.recursive ? 'true' : 'false';
-    const response = await fetch(
-      apiUrl('/api/search?path=' + encodeURIComponent(state.currentPath) + '&q=' + encodeURIComponent(search.query) + '&recursive=' + recursiveParam)
-    );
+    const page = await fetchSearchPage('');
+    if (page) {
+      search.results = sortSearchResults(page.results);
+      search.truncated = page.truncated;
+      search.cursor = page.cursor || '';
+    }
+    search.loading = false;
+  }
 
-    if (response.ok) {
-      const items: FileEntry[] = await response.json();
-      search.results = items.map((entry) => ({
-        ...entry,
-        extension: entry.extension || fileExtensionFromName(entry.name)
-      }));
+  /** 加载下一页搜索结果 */
+  async function loadMoreSearch() {
+    if (!search.truncated || !search.cursor || search.loading) {
+      return;
+    }
+
+    const query = search.query;
+    search.loading = true;
+    const page = await fetchSearchPage(search.cursor);
+    if (page && query === search.query) {
+      search.results = sortSearchResults([...search.results, ...page.results]);
+      search.truncated = page.truncated;
+      search.cursor = page.cursor || '';
     }
     search.loading = false;
   }
@@ -206,6 +260,7 @@ export function useFileBrowser() {
     clearSearch,
     performSearch,
     debouncedSearch,
+    loadMoreSearch,
     toggleSearchRecursive,
     selectEntry,
     setSelected,
@@ -136,7 +136,8 @@ export function usePreview() {
 
     // 更新 entry 的大小和时间
     if (!append) {
-      if (payload.size !== undefined) {
+      // 透明解压且尚未解压到末尾时大小未知，保留列表中的大小
+      if (payload.size !== undefined && !payload.sizeUnknown) {
         selectedEntry.size = payload.size;
       }
       if (payload.modified) {